@@ -16,12 +16,28 @@ func main() {
 			commands.InitCommand(),
 			commands.NewCommand(),
 			commands.ReadCommand(),
+			commands.ShowCommand(),
 			commands.UpdateCommand(),
+			commands.EditCommand(),
+			commands.TUICommand(),
 			commands.DeleteCommand(),
+			commands.BatchCommand(),
+			commands.ImportCommand(),
+			commands.ExportCommand(),
 			commands.QueryCommand(),
 			commands.CleanupCommand(),
 			commands.PrimeCommand(),
 			commands.ConfigCommand(),
+			commands.MigrateCommand(),
+			commands.ReprefixCommand(),
+			commands.DedupeCommand(),
+			commands.StatsCommand(),
+			commands.ChangelogCommand(),
+			commands.LinkCommitCommand(),
+			commands.MergeDriverCommand(),
+			commands.MCPCommand(),
+			commands.ServeCommand(),
+			commands.WatchCommand(),
 		},
 	}
 
@@ -0,0 +1,430 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"go.yozora.eu/pebbles/internal/config"
+	"go.yozora.eu/pebbles/internal/peb"
+	"go.yozora.eu/pebbles/internal/store"
+)
+
+type BatchOp struct {
+	Op        string    `json:"op"`
+	Ref       string    `json:"ref,omitempty"`
+	Parent    string    `json:"parent,omitempty"`
+	ID        string    `json:"id,omitempty"`
+	Title     *string   `json:"title,omitempty"`
+	Content   *string   `json:"content,omitempty"`
+	Type      *string   `json:"type,omitempty"`
+	Status    *string   `json:"status,omitempty"`
+	BlockedBy *[]string `json:"blocked-by,omitempty"`
+}
+
+type BatchResult struct {
+	Op  string `json:"op"`
+	Ref string `json:"ref,omitempty"`
+	ID  string `json:"id"`
+}
+
+func BatchCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "batch",
+		Usage: "Apply many operations at once",
+		Description: `Apply a list of operations read from stdin as a JSON array or as JSON lines.
+
+Operations:
+  {"op":"new", "ref":"<name>", "parent":"<id>", "title":..., "content":..., "type":..., "blocked-by":[...]}
+  {"op":"update", "id":"<id>", "title":..., "content":..., "type":..., "status":..., "blocked-by":[...]}
+  {"op":"delete", "id":"<id>"}
+  {"op":"link", "id":"<id>", "blocked-by":[...]}
+
+A new peb can be given a "ref" name. Later operations refer to it as
+"$<name>" wherever a peb ID is expected. "link" adds blockers to a peb
+without replacing its existing ones.
+
+A new peb with a "parent" is a child of that peb, which becomes blocked by
+it. With id_scheme = "hierarchical" in config.toml, children get the ID of
+their parent followed by a number, e.g. peb-ab12.1.
+
+All operations are validated together, including types, statuses,
+references and cycles,
+and are applied all-or-nothing: if any operation fails, nothing is changed.
+A deleted peb must not be left in the blocked-by list of another peb.
+
+Prints a JSON array with the operation, ref and peb ID of every operation.
+
+Examples:
+  peb batch <<'EOF'
+  {"op":"new","ref":"api","title":"Add API","content":"...","type":"task"}
+  {"op":"new","ref":"docs","title":"Document API","content":"...","type":"task","blocked-by":["$api"]}
+  {"op":"new","title":"API epic","content":"...","type":"epic","blocked-by":["$api","$docs"]}
+  EOF`,
+		Action: func(c *cli.Context) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			if err := config.MaybeUpdatePlugin(cfg); err != nil {
+				return fmt.Errorf("failed to update plugin: %w", err)
+			}
+
+			s, err := openStore(cfg)
+			if err != nil {
+				return err
+			}
+
+			ops, err := parseBatch(c.App.Reader)
+			if err != nil {
+				return err
+			}
+
+			results, err := runBatch(s, cfg, ops)
+			if err != nil {
+				return err
+			}
+
+			output, err := json.Marshal(results)
+			if err != nil {
+				return fmt.Errorf("failed to marshal JSON: %w", err)
+			}
+			fmt.Fprintln(c.App.Writer, string(output))
+			return nil
+		},
+	}
+}
+
+func parseBatch(r io.Reader) ([]BatchOp, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("[")) {
+		var ops []BatchOp
+		if err := json.Unmarshal(data, &ops); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON input: %w", err)
+		}
+		return ops, nil
+	}
+
+	var ops []BatchOp
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var op BatchOp
+		if err := decoder.Decode(&op); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse JSON input: %w", err)
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// batch implements peb.Store, so the usual validation sees the changes of
+// the operations applied so far.
+type batch struct {
+	s        *store.Store
+	refs     map[string]string
+	original map[string]*peb.Peb
+	current  map[string]*peb.Peb
+	order    []string
+}
+
+func (b *batch) Get(id string) (*peb.Peb, bool) {
+	if p, ok := b.current[id]; ok {
+		return p, p != nil
+	}
+	return b.s.Get(id)
+}
+
+func (b *batch) edit(id string) (*peb.Peb, error) {
+	if p, ok := b.current[id]; ok {
+		if p == nil {
+			return nil, fmt.Errorf("peb %s is deleted earlier in the batch", id)
+		}
+		return p, nil
+	}
+	p, ok := b.s.Get(id)
+	if !ok {
+		return nil, notFound(b.s, id)
+	}
+	edited := *p
+	edited.BlockedBy = slices.Clone(p.BlockedBy)
+	b.original[id] = p
+	b.current[id] = &edited
+	b.order = append(b.order, id)
+	return &edited, nil
+}
+
+func (b *batch) resolve(id string) (string, error) {
+	name, ok := strings.CutPrefix(id, "$")
+	if !ok {
+		return id, nil
+	}
+	resolved, ok := b.refs[name]
+	if !ok {
+		return "", fmt.Errorf("unknown ref %s", id)
+	}
+	return resolved, nil
+}
+
+func (b *batch) resolveAll(ids []string) ([]string, error) {
+	resolved := make([]string, 0, len(ids))
+	for _, id := range ids {
+		r, err := b.resolve(id)
+		if err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, r)
+	}
+	return resolved, nil
+}
+
+func (b *batch) generateID(cfg *config.Config, parent string) (string, error) {
+	ids := b.s.IDs()
+	for id := range b.current {
+		ids = append(ids, id)
+	}
+	id, err := cfg.IDScheme.NextID(cfg.Prefix, cfg.IDLength, parent, ids, cfg.IDSpread())
+	if err != nil {
+		return "", fmt.Errorf("failed to generate ID: %w", err)
+	}
+	return id, nil
+}
+
+func (b *batch) validateBlockedBy(p *peb.Peb) error {
+	if err := peb.ValidateBlockedBy(b, p, p.BlockedBy); err != nil {
+		if peb.HasInvalidReference(err) {
+			return fmt.Errorf("Referenced peb(s) not found: %s", extractInvalidID(err))
+		}
+		return err
+	}
+	return peb.CheckCycle(b, p.ID, p.BlockedBy)
+}
+
+func (b *batch) apply(cfg *config.Config, op BatchOp) (BatchResult, error) {
+	result := BatchResult{Op: op.Op, Ref: op.Ref}
+
+	if op.Op == "new" {
+		if op.Title == nil || *op.Title == "" {
+			return result, fmt.Errorf("title is required")
+		}
+		if op.Content == nil || *op.Content == "" {
+			return result, fmt.Errorf("content is required")
+		}
+		if op.Ref != "" {
+			if _, ok := b.refs[op.Ref]; ok {
+				return result, fmt.Errorf("ref %s is already defined", op.Ref)
+			}
+		}
+
+		pebType := peb.TypeBug
+		if op.Type != nil && *op.Type != "" {
+			pebType = peb.Type(*op.Type)
+			if err := peb.ValidateType(pebType); err != nil {
+				return result, err
+			}
+		}
+
+		// The parent is blocked by its children, like an epic by its tasks.
+		var parent *peb.Peb
+		var parentID string
+		if op.Parent != "" {
+			var err error
+			if parentID, err = b.resolve(op.Parent); err != nil {
+				return result, err
+			}
+			if parent, err = b.edit(parentID); err != nil {
+				return result, err
+			}
+		}
+
+		id, err := b.generateID(cfg, parentID)
+		if err != nil {
+			return result, err
+		}
+		p := peb.New(id, *op.Title, pebType, peb.StatusNew, *op.Content)
+		if op.BlockedBy != nil {
+			if p.BlockedBy, err = b.resolveAll(*op.BlockedBy); err != nil {
+				return result, err
+			}
+		}
+		if err := b.validateBlockedBy(p); err != nil {
+			return result, err
+		}
+		b.current[id] = p
+		b.order = append(b.order, id)
+		if parent != nil {
+			parent.BlockedBy = append(parent.BlockedBy, id)
+			if err := b.validateBlockedBy(parent); err != nil {
+				return result, err
+			}
+			parent.UpdateTimestamp()
+		}
+		if op.Ref != "" {
+			b.refs[op.Ref] = id
+		}
+		result.ID = id
+		return result, nil
+	}
+
+	if op.Ref != "" {
+		return result, fmt.Errorf("ref is only allowed for new pebs")
+	}
+	if op.ID == "" {
+		return result, fmt.Errorf("id is required")
+	}
+	id, err := b.resolve(op.ID)
+	if err != nil {
+		return result, err
+	}
+	result.ID = id
+
+	p, err := b.edit(id)
+	if err != nil {
+		return result, err
+	}
+
+	switch op.Op {
+	case "update":
+		if op.Title != nil {
+			p.Title = *op.Title
+		}
+		if op.Content != nil {
+			p.Content = *op.Content
+		}
+		if op.Type != nil {
+			p.Type = peb.Type(*op.Type)
+			if err := peb.ValidateType(p.Type); err != nil {
+				return result, err
+			}
+		}
+		if op.Status != nil {
+			p.Status = peb.Status(*op.Status)
+			if err := peb.ValidateStatus(p.Status); err != nil {
+				return result, err
+			}
+		}
+		if op.BlockedBy != nil {
+			if p.BlockedBy, err = b.resolveAll(*op.BlockedBy); err != nil {
+				return result, err
+			}
+		}
+	case "link":
+		if op.BlockedBy == nil || len(*op.BlockedBy) == 0 {
+			return result, fmt.Errorf("blocked-by is required")
+		}
+		blockers, err := b.resolveAll(*op.BlockedBy)
+		if err != nil {
+			return result, err
+		}
+		for _, blocker := range blockers {
+			if !slices.Contains(p.BlockedBy, blocker) {
+				p.BlockedBy = append(p.BlockedBy, blocker)
+			}
+		}
+	case "delete":
+		b.current[id] = nil
+		return result, nil
+	default:
+		return result, fmt.Errorf("unknown operation %q", op.Op)
+	}
+
+	if err := b.validateBlockedBy(p); err != nil {
+		return result, err
+	}
+	p.UpdateTimestamp()
+	return result, nil
+}
+
+// checkDeletes runs once all operations are applied, since a peb may be
+// unlinked after its blocker is deleted, or deleted as well. deletes maps
+// the deleted IDs to the index of their operation.
+func (b *batch) checkDeletes(deletes map[string]int) error {
+	if len(deletes) == 0 {
+		return nil
+	}
+	ids := b.s.IDs()
+	for _, id := range b.order {
+		if _, ok := b.original[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	for _, id := range ids {
+		p, ok := b.Get(id)
+		if !ok {
+			continue
+		}
+		for _, blocker := range p.BlockedBy {
+			if i, ok := deletes[blocker]; ok {
+				return &batchError{Index: i, Op: "delete", Err: fmt.Errorf("peb %s still blocks %s; remove it from the blocked-by list first", blocker, p.ID)}
+			}
+		}
+	}
+	return nil
+}
+
+type batchError struct {
+	Index int
+	Op    string
+	Err   error
+}
+
+func (e *batchError) Error() string {
+	return fmt.Sprintf("operation %d (%s): %v", e.Index+1, e.Op, e.Err)
+}
+
+func (e *batchError) Unwrap() error {
+	return e.Err
+}
+
+func runBatch(s *store.Store, cfg *config.Config, ops []BatchOp) ([]BatchResult, error) {
+	b := &batch{
+		s:        s,
+		refs:     make(map[string]string),
+		original: make(map[string]*peb.Peb),
+		current:  make(map[string]*peb.Peb),
+	}
+
+	results := make([]BatchResult, 0, len(ops))
+	deletes := make(map[string]int)
+	for i, op := range ops {
+		result, err := b.apply(cfg, op)
+		if err != nil {
+			return nil, &batchError{Index: i, Op: op.Op, Err: err}
+		}
+		if op.Op == "delete" {
+			deletes[result.ID] = i
+		}
+		results = append(results, result)
+	}
+	if err := b.checkDeletes(deletes); err != nil {
+		return nil, err
+	}
+
+	var changes []store.Change
+	for _, id := range b.order {
+		old, cur := b.original[id], b.current[id]
+		if old == nil && cur == nil {
+			// Created and deleted within the batch.
+			continue
+		}
+		changes = append(changes, store.Change{Old: old, New: cur})
+	}
+
+	if err := s.Apply(changes); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,238 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.yozora.eu/pebbles/internal/config"
+	"go.yozora.eu/pebbles/internal/peb"
+	"go.yozora.eu/pebbles/internal/store"
+)
+
+func loadTestBatch(t *testing.T, pebblesDir, input string) ([]BatchResult, error) {
+	t.Helper()
+	t.Chdir(pebblesDir)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := openStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops, err := parseBatch(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return runBatch(s, cfg, ops)
+}
+
+func TestBatch(t *testing.T) {
+	pebblesDir, s, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	existing := peb.New("peb-aaaa", "Existing", peb.TypeTask, peb.StatusNew, "Content")
+	obsolete := peb.New("peb-bbbb", "Obsolete", peb.TypeTask, peb.StatusNew, "Content")
+	for _, p := range []*peb.Peb{existing, obsolete} {
+		if err := s.Save(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := loadTestBatch(t, pebblesDir, `
+{"op":"new","ref":"one","title":"One","content":"First","type":"task"}
+{"op":"new","ref":"two","title":"Two","content":"Second","type":"task","blocked-by":["$one"]}
+{"op":"new","ref":"epic","title":"Epic","content":"All","type":"epic","blocked-by":["$two"]}
+{"op":"link","id":"$epic","blocked-by":["$one","peb-aaaa"]}
+{"op":"update","id":"peb-aaaa","status":"in-progress","title":"Renamed"}
+{"op":"delete","id":"peb-bbbb"}
+`)
+	if err != nil {
+		t.Fatalf("runBatch() failed: %v", err)
+	}
+	if len(results) != 6 {
+		t.Fatalf("expected 6 results, got %+v", results)
+	}
+	if results[0].Ref != "one" || results[0].ID == "" || results[3].ID != results[2].ID {
+		t.Errorf("unexpected results %+v", results)
+	}
+
+	s = store.New(pebblesDir, "peb")
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	epic, ok := s.Get(results[2].ID)
+	if !ok {
+		t.Fatal("expected epic to be created")
+	}
+	want := []string{results[1].ID, results[0].ID, "peb-aaaa"}
+	if !slices.Equal(epic.BlockedBy, want) {
+		t.Errorf("expected epic blocked by %v, got %v", want, epic.BlockedBy)
+	}
+
+	if p, _ := s.Get("peb-aaaa"); p.Status != peb.StatusInProgress || p.Title != "Renamed" {
+		t.Errorf("expected peb-aaaa to be updated, got %+v", p)
+	}
+	if s.Exists("peb-bbbb") {
+		t.Error("expected peb-bbbb to be deleted")
+	}
+}
+
+func TestBatchAllOrNothing(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{
+			name:  "cycle",
+			input: `[{"op":"new","ref":"a","title":"A","content":"A"},{"op":"update","id":"peb-aaaa","blocked-by":["$a"]},{"op":"link","id":"$a","blocked-by":["peb-aaaa"]}]`,
+			err:   "operation 3 (link): " + peb.ErrCycle.Error(),
+		},
+		{
+			name:  "unknown ref",
+			input: `[{"op":"new","title":"A","content":"A","blocked-by":["$missing"]}]`,
+			err:   "operation 1 (new): unknown ref $missing",
+		},
+		{
+			name:  "unknown peb",
+			input: `[{"op":"new","title":"A","content":"A"},{"op":"update","id":"peb-nope","status":"fixed"}]`,
+			err:   "operation 2 (update): peb peb-nope not found",
+		},
+		{
+			name:  "reference to deleted peb",
+			input: `[{"op":"delete","id":"peb-aaaa"},{"op":"new","title":"A","content":"A","blocked-by":["peb-aaaa"]}]`,
+			err:   "operation 2 (new): Referenced peb(s) not found: peb-aaaa",
+		},
+		{
+			name:  "invalid type",
+			input: `[{"op":"new","title":"A","content":"A"},{"op":"new","title":"B","content":"B","type":"chore"}]`,
+			err:   `operation 2 (new): invalid type "chore": must be one of bug, feature, epic, task`,
+		},
+		{
+			name:  "invalid status",
+			input: `[{"op":"new","title":"A","content":"A"},{"op":"update","id":"peb-aaaa","status":"done"}]`,
+			err:   `operation 2 (update): invalid status "done": must be one of new, in-progress, fixed, wont-fix`,
+		},
+		{
+			name:  "unknown operation",
+			input: `[{"op":"frobnicate","id":"peb-aaaa"}]`,
+			err:   `operation 1 (frobnicate): unknown operation "frobnicate"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pebblesDir, s, cleanup := setupTestStore(t)
+			defer cleanup()
+
+			if err := s.Save(peb.New("peb-aaaa", "Existing", peb.TypeTask, peb.StatusNew, "Content")); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := loadTestBatch(t, pebblesDir, tt.input)
+			if err == nil || err.Error() != tt.err {
+				t.Fatalf("expected error %q, got %v", tt.err, err)
+			}
+
+			entries, err := os.ReadDir(pebblesDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// config.toml and peb-aaaa.
+			if len(entries) != 2 {
+				t.Errorf("expected no changes on disk, got %d entries", len(entries))
+			}
+		})
+	}
+}
+
+func TestBatchPreHookVetoesEverything(t *testing.T) {
+	pebblesDir, _, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	config := `prefix = "peb"
+id_length = 4
+
+[hooks]
+pre_create = 'grep -q "\"title\":\"Vetoed\"" && exit 1 || exit 0'
+`
+	if err := os.WriteFile(pebblesDir+"/config.toml", []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := loadTestBatch(t, pebblesDir, `[{"op":"new","title":"Fine","content":"A"},{"op":"new","title":"Vetoed","content":"B"}]`)
+	if err == nil {
+		t.Fatal("expected veto")
+	}
+
+	entries, err := os.ReadDir(pebblesDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected no pebs to be written, got %d entries", len(entries))
+	}
+}
+
+func TestBatchDeleteBlocker(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{
+			name:  "blocker still referenced",
+			input: `[{"op":"link","id":"peb-bbbb","blocked-by":["peb-aaaa"]},{"op":"delete","id":"peb-aaaa"}]`,
+			err:   "operation 2 (delete): peb peb-aaaa still blocks peb-bbbb; remove it from the blocked-by list first",
+		},
+		{
+			name:  "blocker of an unchanged peb",
+			input: `[{"op":"update","id":"peb-aaaa","title":"Renamed"},{"op":"delete","id":"peb-cccc"}]`,
+			err:   "operation 2 (delete): peb peb-cccc still blocks peb-bbbb; remove it from the blocked-by list first",
+		},
+		{
+			name:  "unlinked after the delete",
+			input: `[{"op":"link","id":"peb-bbbb","blocked-by":["peb-aaaa"]},{"op":"delete","id":"peb-aaaa"},{"op":"update","id":"peb-bbbb","blocked-by":[]}]`,
+		},
+		{
+			name:  "blocked peb deleted as well",
+			input: `[{"op":"link","id":"peb-bbbb","blocked-by":["peb-aaaa"]},{"op":"delete","id":"peb-aaaa"},{"op":"delete","id":"peb-bbbb"}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pebblesDir, s, cleanup := setupTestStore(t)
+			defer cleanup()
+
+			blocker := peb.New("peb-cccc", "Blocker", peb.TypeTask, peb.StatusNew, "Content")
+			blocked := peb.New("peb-bbbb", "Blocked", peb.TypeTask, peb.StatusNew, "Content")
+			blocked.BlockedBy = []string{"peb-cccc"}
+			for _, p := range []*peb.Peb{peb.New("peb-aaaa", "Existing", peb.TypeTask, peb.StatusNew, "Content"), blocker, blocked} {
+				if err := s.Save(p); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err := loadTestBatch(t, pebblesDir, tt.input)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("runBatch() failed: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.err {
+				t.Fatalf("expected error %q, got %v", tt.err, err)
+			}
+			if _, err := os.Stat(filepath.Join(pebblesDir, peb.Filename(blocker))); err != nil {
+				t.Errorf("expected no changes on disk: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package commands
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"go.yozora.eu/pebbles/internal/config"
+	"go.yozora.eu/pebbles/internal/peb"
+	"go.yozora.eu/pebbles/internal/store"
+)
+
+//go:embed data/changelog.md
+var changelogTemplate string
+
+// Changelog is the data passed to the changelog template.
+type Changelog struct {
+	Since    string
+	Sections []ChangelogSection
+}
+
+type ChangelogSection struct {
+	Type    peb.Type
+	Title   string
+	Entries []*ChangelogEntry
+}
+
+// With --epics, a ChangelogEntry may be an open epic with closed Children.
+type ChangelogEntry struct {
+	ID       string
+	Title    string
+	Type     peb.Type
+	Status   peb.Status
+	Closed   string
+	Depth    int
+	Children []*ChangelogEntry
+}
+
+var changelogSections = []struct {
+	typ   peb.Type
+	title string
+}{
+	{peb.TypeFeature, "Features"},
+	{peb.TypeBug, "Bug Fixes"},
+	{peb.TypeTask, "Tasks"},
+	{peb.TypeEpic, "Epics"},
+}
+
+var changelogFuncs = template.FuncMap{
+	"indent": func(depth int) string {
+		return strings.Repeat("  ", depth)
+	},
+}
+
+type changelogOptions struct {
+	// since is zero for all closed pebs.
+	since   time.Time
+	wontFix bool
+	epics   bool
+}
+
+func ChangelogCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "changelog",
+		Usage:     "Generate release notes from closed pebs",
+		ArgsUsage: "[filters]",
+		Description: `Print the pebs closed since a date or git revision, grouped by type, as
+markdown release notes.
+
+A peb counts as closed at its changed timestamp. wont-fix pebs are left out
+unless --wont-fix is given. Accepts the same filters as "peb query".
+
+With --epics, pebs that block an epic are listed under it, and epics with
+pebs closed in the window are included even if they are still open.
+
+--template renders the changelog with a Go text/template instead of the
+default markdown. The template gets .Since and .Sections; each section has
+.Type, .Title and .Entries, and each entry has .ID, .Title, .Type, .Status,
+.Closed (YYYY-MM-DD), .Depth and .Children. The function indent returns two
+spaces per depth level.
+
+Examples:
+  peb changelog --since 2024-06-01
+  peb changelog --since v1.2.0 --epics
+  peb changelog --since v1.2.0 --template .pebbles/release.tmpl`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "since",
+				Usage: "Only include pebs closed since a date (YYYY-MM-DD or RFC 3339) or git revision",
+			},
+			&cli.BoolFlag{
+				Name:  "wont-fix",
+				Usage: "Include wont-fix pebs",
+			},
+			&cli.BoolFlag{
+				Name:  "epics",
+				Usage: "List pebs under the epics they belong to",
+			},
+			&cli.StringFlag{
+				Name:  "template",
+				Usage: "Render the changelog with the text/template in this file",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			if err := config.MaybeUpdatePlugin(cfg); err != nil {
+				return fmt.Errorf("failed to update plugin: %w", err)
+			}
+
+			text := changelogTemplate
+			if path := c.String("template"); path != "" {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					return fmt.Errorf("failed to read template: %w", err)
+				}
+				text = string(data)
+			}
+			tmpl, err := template.New("changelog").Funcs(changelogFuncs).Parse(text)
+			if err != nil {
+				return fmt.Errorf("failed to parse template: %w", err)
+			}
+
+			opts := changelogOptions{
+				wontFix: c.Bool("wont-fix"),
+				epics:   c.Bool("epics"),
+			}
+			if since := c.String("since"); since != "" {
+				opts.since, err = parseSince(since, cfg.ProjectDir())
+				if err != nil {
+					return err
+				}
+			}
+
+			s := store.New(cfg.PebblesDir(), cfg.Prefix)
+			if err := s.Load(); err != nil {
+				return err
+			}
+			warnInvalid(c.App.ErrWriter, s, cfg.Prefix)
+
+			args, err := expandSavedQueries(c.Args().Slice(), cfg.Queries)
+			if err != nil {
+				return err
+			}
+
+			filters, err := parseFilters(args, s.Resolve)
+			if err != nil {
+				return err
+			}
+
+			var pebs []*peb.Peb
+			for _, p := range s.All() {
+				if applyFilters(p, filters) {
+					pebs = append(pebs, p)
+				}
+			}
+
+			data := Changelog{
+				Since:    c.String("since"),
+				Sections: buildChangelog(s, pebs, opts),
+			}
+			return tmpl.Execute(c.App.Writer, data)
+		},
+	}
+}
+
+// parseSince accepts a date, an RFC 3339 timestamp or a git revision.
+func parseSince(since string, dir string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", since, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, since); err == nil {
+		return t, nil
+	}
+
+	cmd := exec.Command("git", "log", "-1", "--format=%cI", since, "--")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --since %q: not a date (YYYY-MM-DD) or a git revision", since)
+	}
+	return time.Parse(time.RFC3339, strings.TrimSpace(string(out)))
+}
+
+func closedInWindow(p *peb.Peb, opts changelogOptions) bool {
+	if !peb.IsClosed(p.Status) || (p.Status == peb.StatusWontFix && !opts.wontFix) {
+		return false
+	}
+	if opts.since.IsZero() {
+		return true
+	}
+	changed, err := peb.ParseTimestamp(p.Changed)
+	return err == nil && !changed.Before(opts.since)
+}
+
+func buildChangelog(s *store.Store, pebs []*peb.Peb, opts changelogOptions) []ChangelogSection {
+	var closed []*peb.Peb
+	for _, p := range pebs {
+		if closedInWindow(p, opts) {
+			closed = append(closed, p)
+		}
+	}
+	sort.Slice(closed, func(i, j int) bool {
+		ti, _ := peb.ParseTimestamp(closed[i].Changed)
+		tj, _ := peb.ParseTimestamp(closed[j].Changed)
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return closed[i].ID < closed[j].ID
+	})
+
+	entries := make(map[string]*ChangelogEntry)
+	var top []*ChangelogEntry
+	var add func(p *peb.Peb) *ChangelogEntry
+	add = func(p *peb.Peb) *ChangelogEntry {
+		if e, ok := entries[p.ID]; ok {
+			return e
+		}
+		e := &ChangelogEntry{ID: p.ID, Title: p.Title, Type: p.Type, Status: p.Status}
+		if closedInWindow(p, opts) {
+			if t, err := peb.ParseTimestamp(p.Changed); err == nil {
+				e.Closed = t.Format("2006-01-02")
+			}
+		}
+		entries[p.ID] = e
+
+		if opts.epics {
+			if epic := parentEpic(s, p); epic != nil {
+				parent := add(epic)
+				parent.Children = append(parent.Children, e)
+				return e
+			}
+		}
+		top = append(top, e)
+		return e
+	}
+	for _, p := range closed {
+		add(p)
+	}
+
+	var setDepth func(e *ChangelogEntry, depth int)
+	setDepth = func(e *ChangelogEntry, depth int) {
+		e.Depth = depth
+		for _, child := range e.Children {
+			setDepth(child, depth+1)
+		}
+	}
+
+	var sections []ChangelogSection
+	for _, section := range changelogSections {
+		var sectionEntries []*ChangelogEntry
+		for _, e := range top {
+			if e.Type == section.typ {
+				setDepth(e, 0)
+				sectionEntries = append(sectionEntries, e)
+			}
+		}
+		if len(sectionEntries) > 0 {
+			sections = append(sections, ChangelogSection{
+				Type:    section.typ,
+				Title:   section.title,
+				Entries: sectionEntries,
+			})
+		}
+	}
+	return sections
+}
+
+func parentEpic(s *store.Store, p *peb.Peb) *peb.Peb {
+	for _, id := range s.Blocks(p.ID) {
+		if blocked, ok := s.Get(id); ok && blocked.Type == peb.TypeEpic {
+			return blocked
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"go.yozora.eu/pebbles/internal/peb"
+)
+
+func runChangelog(args ...string) (string, error) {
+	var out bytes.Buffer
+	app := &cli.App{
+		Name:     "peb",
+		Writer:   &out,
+		Commands: []*cli.Command{ChangelogCommand()},
+	}
+	err := app.Run(append([]string{"peb", "changelog"}, args...))
+	return out.String(), err
+}
+
+func TestChangelog(t *testing.T) {
+	pebblesDir, s, cleanup := setupTestStore(t)
+	defer cleanup()
+	t.Chdir(pebblesDir)
+
+	old := peb.FormatTimestamp(time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local))
+	recent := peb.FormatTimestamp(time.Date(2024, 6, 2, 12, 0, 0, 0, time.Local))
+	later := peb.FormatTimestamp(time.Date(2024, 6, 3, 12, 0, 0, 0, time.Local))
+
+	pebs := []*peb.Peb{
+		{ID: "peb-form", Title: "Login form", Type: peb.TypeTask, Status: peb.StatusFixed, Changed: later},
+		{ID: "peb-back", Title: "Login backend", Type: peb.TypeFeature, Status: peb.StatusFixed, Changed: recent},
+		{ID: "peb-bug1", Title: "Crash", Type: peb.TypeBug, Status: peb.StatusFixed, Changed: recent},
+		{ID: "peb-bug2", Title: "Old crash", Type: peb.TypeBug, Status: peb.StatusFixed, Changed: old},
+		{ID: "peb-bug3", Title: "Not a bug", Type: peb.TypeBug, Status: peb.StatusWontFix, Changed: recent},
+		{ID: "peb-open", Title: "Open", Type: peb.TypeBug, Status: peb.StatusNew, Changed: recent},
+		{ID: "peb-epic", Title: "Login", Type: peb.TypeEpic, Status: peb.StatusInProgress, Changed: recent, BlockedBy: []string{"peb-form", "peb-back"}},
+	}
+	for _, p := range pebs {
+		if err := s.Save(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, err := runChangelog("--since", "2024-06-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# Changelog since 2024-06-01
+
+## Features
+
+- Login backend (peb-back)
+
+## Bug Fixes
+
+- Crash (peb-bug1)
+
+## Tasks
+
+- Login form (peb-form)
+`
+	if out != want {
+		t.Errorf("unexpected changelog:\n%s\nwant:\n%s", out, want)
+	}
+
+	out, err = runChangelog("--since", "2024-06-01", "--epics", "--wont-fix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `# Changelog since 2024-06-01
+
+## Bug Fixes
+
+- Crash (peb-bug1)
+- Not a bug (peb-bug3) (won't fix)
+
+## Epics
+
+- Login (peb-epic) (open)
+  - Login backend (peb-back)
+  - Login form (peb-form)
+`
+	if out != want {
+		t.Errorf("unexpected changelog:\n%s\nwant:\n%s", out, want)
+	}
+
+	tmpl := filepath.Join(t.TempDir(), "release.tmpl")
+	if err := os.WriteFile(tmpl, []byte(`{{range .Sections}}{{range .Entries}}{{.ID}} {{.Closed}};{{end}}{{end}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err = runChangelog("--template", tmpl, "type:bug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "peb-bug2 2024-01-01;peb-bug1 2024-06-02;" {
+		t.Errorf("unexpected custom changelog %q", out)
+	}
+
+	if _, err := runChangelog("--since", "not-a-revision"); err == nil {
+		t.Error("expected an error for an invalid --since")
+	}
+}
+
+func TestChangelogSinceGitRevision(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	pebblesDir, s, cleanup := setupTestStore(t)
+	defer cleanup()
+	t.Chdir(pebblesDir)
+
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = filepath.Dir(pebblesDir)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+			"GIT_COMMITTER_DATE=2024-06-01T00:00:00Z")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	git("commit", "-q", "--allow-empty", "-m", "release")
+	git("tag", "v1.0.0")
+
+	for _, p := range []*peb.Peb{
+		{ID: "peb-old1", Title: "Before", Type: peb.TypeBug, Status: peb.StatusFixed, Changed: "2024-05-31T12:00:00+00:00"},
+		{ID: "peb-new1", Title: "After", Type: peb.TypeBug, Status: peb.StatusFixed, Changed: "2024-06-01T12:00:00+00:00"},
+	} {
+		if err := s.Save(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, err := runChangelog("--since", "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "peb-new1") || strings.Contains(out, "peb-old1") {
+		t.Errorf("unexpected changelog:\n%s", out)
+	}
+}
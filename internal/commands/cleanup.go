@@ -6,7 +6,6 @@ import (
 	"github.com/urfave/cli/v2"
 	"go.yozora.eu/pebbles/internal/config"
 	"go.yozora.eu/pebbles/internal/peb"
-	"go.yozora.eu/pebbles/internal/store"
 )
 
 func CleanupCommand() *cli.Command {
@@ -28,8 +27,8 @@ Examples:
 				return fmt.Errorf("failed to update plugin: %w", err)
 			}
 
-			s := store.New(cfg.PebblesDir(), cfg.Prefix)
-			if err := s.Load(); err != nil {
+			s, err := openStore(cfg)
+			if err != nil {
 				return err
 			}
 
@@ -46,9 +45,9 @@ Examples:
 			}
 
 			if deletedCount > 0 {
-				fmt.Printf("Deleted %d closed peb(s).\n", deletedCount)
+				fmt.Fprintf(c.App.Writer, "Deleted %d closed peb(s).\n", deletedCount)
 			} else {
-				fmt.Println("No closed pebs found to delete.")
+				fmt.Fprintln(c.App.Writer, "No closed pebs found to delete.")
 			}
 
 			return nil
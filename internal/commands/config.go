@@ -3,10 +3,10 @@ package commands
 import (
 	"encoding/json"
 	"fmt"
-	"os"
 
 	"github.com/urfave/cli/v2"
 	"go.yozora.eu/pebbles/internal/config"
+	"go.yozora.eu/pebbles/internal/peb"
 )
 
 func ConfigCommand() *cli.Command {
@@ -15,7 +15,9 @@ func ConfigCommand() *cli.Command {
 		Usage: "Display configuration as JSON",
 		Description: `Display the current pebbles configuration as formatted JSON.
 
-This command shows all configuration fields including prefix, id_length, etc.
+This command shows all configuration fields including prefix, id_length,
+id_scheme and saved queries, and id_examples: three example IDs in the form
+of the ID scheme.
 
 Example:
   peb config`,
@@ -30,16 +32,22 @@ Example:
 			}
 
 			type OutputConfig struct {
-				Prefix   string `json:"prefix"`
-				IDLength int    `json:"id_length"`
+				Prefix     string            `json:"prefix"`
+				IDLength   int               `json:"id_length"`
+				IDScheme   peb.IDScheme      `json:"id_scheme"`
+				IDExamples [3]string         `json:"id_examples"`
+				Queries    map[string]string `json:"queries,omitempty"`
 			}
 
 			outputCfg := OutputConfig{
-				Prefix:   cfg.Prefix,
-				IDLength: cfg.IDLength,
+				Prefix:     cfg.Prefix,
+				IDLength:   cfg.IDLength,
+				IDScheme:   cfg.IDScheme,
+				IDExamples: cfg.IDScheme.Examples(cfg.Prefix, cfg.IDLength),
+				Queries:    cfg.Queries,
 			}
 
-			encoder := json.NewEncoder(os.Stdout)
+			encoder := json.NewEncoder(c.App.Writer)
 			encoder.SetIndent("", "  ")
 
 			if err := encoder.Encode(outputCfg); err != nil {
@@ -174,3 +174,45 @@ func TestConfigCommandJSONFormatting(t *testing.T) {
 		t.Errorf("expected prefix 'peb', got '%s'", config.Prefix)
 	}
 }
+
+func TestConfigCommandSavedQueries(t *testing.T) {
+	pebblesDir, _, cleanup := setupTestStore(t)
+	defer cleanup()
+	t.Chdir(pebblesDir)
+
+	configContent := `prefix = "peb"
+id_length = 4
+
+[queries]
+triage = "status:open type:(bug|feature)"
+`
+	if err := os.WriteFile(pebblesDir+"/config.toml", []byte(configContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	app := &cli.App{
+		Commands: []*cli.Command{ConfigCommand()},
+	}
+
+	r, w, _ := os.Pipe()
+	oldStdout := os.Stdout
+	os.Stdout = w
+
+	err := app.Run([]string{"peb", "config"})
+	w.Close()
+	os.Stdout = oldStdout
+	if err != nil {
+		t.Fatalf("command failed: %v", err)
+	}
+
+	var config struct {
+		Queries map[string]string `json:"queries"`
+	}
+	if err := json.NewDecoder(r).Decode(&config); err != nil {
+		t.Fatalf("failed to parse JSON output: %v", err)
+	}
+
+	if config.Queries["triage"] != "status:open type:(bug|feature)" {
+		t.Errorf("expected saved query triage in output, got %v", config.Queries)
+	}
+}
@@ -0,0 +1,181 @@
+package commands
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"go.yozora.eu/pebbles/internal/config"
+	"go.yozora.eu/pebbles/internal/peb"
+	"go.yozora.eu/pebbles/internal/store"
+)
+
+func DedupeCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "dedupe",
+		Usage: "Give pebs that share an ID unique IDs",
+		Description: `When branches that both created a peb with the same ID are merged, .pebbles/
+holds several files with that ID, and commands refuse to read or change the
+peb since they cannot tell which one is meant. This command keeps the ID for
+the peb created first and gives the others new IDs, following id_scheme in
+config.toml. Either all pebs are changed or none.
+
+Blocked-by lists that contain the ID are rewritten where it is clear which
+peb they mean: a peb last changed before the renamed peb was created can only
+mean the peb that keeps the ID. Otherwise the peb becomes blocked by both, and
+a warning asks to remove the wrong one with "peb update". Mentions of the ID
+in the content of pebs are left as they are.
+
+Example:
+  peb dedupe`,
+		Action: func(c *cli.Context) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			if err := config.MaybeUpdatePlugin(cfg); err != nil {
+				return fmt.Errorf("failed to update plugin: %w", err)
+			}
+
+			// Like reprefix, this only renames pebs, so it doesn't run hooks.
+			s := store.New(cfg.PebblesDir(), cfg.Prefix)
+			if err := s.Load(); err != nil {
+				return err
+			}
+
+			moves, ambiguous, err := dedupe(s, cfg)
+			if err != nil {
+				return err
+			}
+			if len(moves) == 0 {
+				fmt.Fprintln(c.App.Writer, "No duplicate IDs found")
+				return nil
+			}
+			for _, m := range moves {
+				fmt.Fprintf(c.App.Writer, "Renamed %s in %s to %s\n", m.oldID, m.filename, m.newID)
+			}
+			for _, a := range ambiguous {
+				fmt.Fprintf(c.App.ErrWriter, "Warning: %s is now blocked by both %s and %s; remove the wrong one with \"peb update\"\n", a.id, a.oldID, a.newID)
+			}
+			return nil
+		},
+	}
+}
+
+type dedupeMove struct {
+	oldID    string
+	newID    string
+	filename string
+	created  time.Time
+}
+
+// dedupeAmbiguity is a peb that is now blocked by both pebs of a duplicate
+// ID.
+type dedupeAmbiguity struct {
+	id    string
+	oldID string
+	newID string
+}
+
+// dedupe gives all but the oldest of the pebs that share an ID new IDs.
+func dedupe(s *store.Store, cfg *config.Config) ([]dedupeMove, []dedupeAmbiguity, error) {
+	taken := s.IDs()
+	var moves []dedupeMove
+	var moved []*peb.Peb
+	var kept []store.Duplicate
+	var changes []store.Change
+	for _, id := range s.DuplicateIDs() {
+		duplicates := s.Duplicates(id)
+		for _, d := range duplicates {
+			if d.Err != nil {
+				return nil, nil, fmt.Errorf("cannot dedupe %s: %w", id, d.Err)
+			}
+		}
+		// Duplicates are sorted by file name, which breaks ties.
+		slices.SortStableFunc(duplicates, func(a, b store.Duplicate) int {
+			return parseTimestamp(a.Peb.Created).Compare(parseTimestamp(b.Peb.Created))
+		})
+
+		kept = append(kept, duplicates[0])
+		for _, d := range duplicates[1:] {
+			var parent string
+			if cfg.IDScheme == peb.IDSchemeHierarchical {
+				parent = peb.ParentID(id)
+			}
+			newID, err := cfg.IDScheme.NextID(cfg.Prefix, cfg.IDLength, parent, taken, cfg.IDSpread())
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to generate ID: %w", err)
+			}
+			taken = append(taken, newID)
+
+			p := *d.Peb
+			p.ID = newID
+			p.BlockedBy = slices.Clone(d.Peb.BlockedBy)
+			moves = append(moves, dedupeMove{oldID: id, newID: newID, filename: d.Filename, created: parseTimestamp(d.Peb.Created)})
+			moved = append(moved, &p)
+			changes = append(changes, s.MoveDuplicate(d, &p)...)
+		}
+	}
+	if len(moves) == 0 {
+		return nil, nil, nil
+	}
+
+	pebs := s.All()
+	slices.SortFunc(pebs, func(a, b *peb.Peb) int { return strings.Compare(a.ID, b.ID) })
+	var ambiguous []dedupeAmbiguity
+	for _, p := range append(pebs, moved...) {
+		blockedBy := rewriteBlockers(p, moves, &ambiguous)
+		if slices.Equal(blockedBy, p.BlockedBy) {
+			continue
+		}
+		if slices.Contains(moved, p) {
+			p.BlockedBy = blockedBy
+			continue
+		}
+		updated := *p
+		updated.BlockedBy = blockedBy
+		changes = append(changes, store.Change{Old: p, New: &updated})
+	}
+	// The pebs that keep a duplicate ID aren't in s.All(), and can only be
+	// written once the others have moved out of the way.
+	for _, d := range kept {
+		blockedBy := rewriteBlockers(d.Peb, moves, &ambiguous)
+		if slices.Equal(blockedBy, d.Peb.BlockedBy) {
+			continue
+		}
+		updated := *d.Peb
+		updated.BlockedBy = blockedBy
+		changes = append(changes, s.UpdateDuplicate(d, &updated))
+	}
+
+	if err := s.Apply(changes); err != nil {
+		return nil, nil, err
+	}
+	return moves, ambiguous, nil
+}
+
+// rewriteBlockers adds the new IDs of moved pebs after their old ID unless p
+// was last changed before the moved peb was created.
+func rewriteBlockers(p *peb.Peb, moves []dedupeMove, ambiguous *[]dedupeAmbiguity) []string {
+	changed, err := peb.ParseTimestamp(p.Changed)
+	var blockedBy []string
+	for _, id := range p.BlockedBy {
+		blockedBy = append(blockedBy, id)
+		for _, m := range moves {
+			if m.oldID != id || m.newID == p.ID || (err == nil && changed.Before(m.created)) {
+				continue
+			}
+			blockedBy = append(blockedBy, m.newID)
+			*ambiguous = append(*ambiguous, dedupeAmbiguity{id: p.ID, oldID: id, newID: m.newID})
+		}
+	}
+	return blockedBy
+}
+
+func parseTimestamp(s string) time.Time {
+	t, _ := peb.ParseTimestamp(s)
+	return t
+}
@@ -0,0 +1,212 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+	"go.yozora.eu/pebbles/internal/peb"
+	"go.yozora.eu/pebbles/internal/store"
+)
+
+func runDedupe() (string, string, error) {
+	var out, errOut bytes.Buffer
+	app := &cli.App{
+		Name:      "peb",
+		Writer:    &out,
+		ErrWriter: &errOut,
+		Commands:  []*cli.Command{DedupeCommand()},
+	}
+	err := app.Run([]string{"peb", "dedupe"})
+	return strings.TrimSpace(out.String()), strings.TrimSpace(errOut.String()), err
+}
+
+func TestDedupe(t *testing.T) {
+	pebblesDir, _, cleanup := setupTestStore(t)
+	defer cleanup()
+	t.Chdir(pebblesDir)
+
+	write := func(id, title, created, changed string, blockedBy ...string) {
+		t.Helper()
+		p := peb.New(id, title, peb.TypeTask, peb.StatusNew, "")
+		p.Created, p.Changed, p.BlockedBy = created, changed, blockedBy
+		if err := peb.WriteFile(pebblesDir, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Both branches created peb-aaaa; theirs is newer. peb-bbbb was last
+	// changed before theirs existed, peb-cccc after.
+	write("peb-aaaa", "Ours", "2026-01-01T10:00:00+00:00", "2026-01-01T10:00:00+00:00")
+	write("peb-aaaa", "Theirs", "2026-01-02T10:00:00+00:00", "2026-01-02T10:00:00+00:00")
+	write("peb-bbbb", "Old", "2026-01-01T11:00:00+00:00", "2026-01-01T12:00:00+00:00", "peb-aaaa")
+	write("peb-cccc", "New", "2026-01-01T11:00:00+00:00", "2026-01-03T12:00:00+00:00", "peb-aaaa")
+
+	_, err := runInProcess("", "read", "peb-aaaa")
+	if err == nil || !strings.Contains(err.Error(), "duplicate peb ID peb-aaaa in peb-aaaa--ours.md, peb-aaaa--theirs.md") {
+		t.Fatalf("expected read to refuse the duplicate ID, got %v", err)
+	}
+
+	output, warnings, err := runDedupe()
+	if err != nil {
+		t.Fatalf("dedupe failed: %v", err)
+	}
+	s := store.New(pebblesDir, "peb")
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if ids := s.DuplicateIDs(); len(ids) != 0 {
+		t.Fatalf("expected no duplicates, got %v", ids)
+	}
+	if p, ok := s.Get("peb-aaaa"); !ok || p.Title != "Ours" {
+		t.Fatalf("expected the older peb to keep the ID, got %v", p)
+	}
+	var newID string
+	for _, p := range s.All() {
+		if p.Title == "Theirs" {
+			newID = p.ID
+		}
+	}
+	if newID == "" || output != "Renamed peb-aaaa in peb-aaaa--theirs.md to "+newID {
+		t.Fatalf("unexpected output %q", output)
+	}
+
+	if old, _ := s.Get("peb-bbbb"); !slices.Equal(old.BlockedBy, []string{"peb-aaaa"}) {
+		t.Errorf("expected peb-bbbb to stay blocked by peb-aaaa, got %v", old.BlockedBy)
+	}
+	if changed, _ := s.Get("peb-cccc"); !slices.Equal(changed.BlockedBy, []string{"peb-aaaa", newID}) {
+		t.Errorf("expected peb-cccc blocked by both, got %v", changed.BlockedBy)
+	}
+	if !strings.Contains(warnings, "peb-cccc is now blocked by both peb-aaaa and "+newID) {
+		t.Errorf("expected warning about peb-cccc, got %q", warnings)
+	}
+
+	if output, _, err := runDedupe(); err != nil || output != "No duplicate IDs found" {
+		t.Errorf("second dedupe = %q, %v", output, err)
+	}
+}
+
+func TestDedupeMutualDuplicates(t *testing.T) {
+	pebblesDir, _, cleanup := setupTestStore(t)
+	defer cleanup()
+	t.Chdir(pebblesDir)
+
+	write := func(id, title, created, changed string, blockedBy ...string) {
+		t.Helper()
+		p := peb.New(id, title, peb.TypeTask, peb.StatusNew, "")
+		p.Created, p.Changed, p.BlockedBy = created, changed, blockedBy
+		if err := peb.WriteFile(pebblesDir, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Both IDs collide, and the pebs of each block those of the other.
+	write("peb-aaaa", "Ours A", "2026-01-01T10:00:00+00:00", "2026-01-05T10:00:00+00:00", "peb-bbbb")
+	write("peb-aaaa", "Theirs A", "2026-01-02T10:00:00+00:00", "2026-01-02T10:00:00+00:00", "peb-bbbb")
+	write("peb-bbbb", "Ours B", "2026-01-01T11:00:00+00:00", "2026-01-01T11:00:00+00:00", "peb-aaaa")
+	write("peb-bbbb", "Theirs B", "2026-01-03T10:00:00+00:00", "2026-01-03T10:00:00+00:00", "peb-aaaa")
+
+	if _, _, err := runDedupe(); err != nil {
+		t.Fatalf("dedupe failed: %v", err)
+	}
+	s := store.New(pebblesDir, "peb")
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if ids := s.DuplicateIDs(); len(ids) != 0 {
+		t.Fatalf("expected no duplicates, got %v", ids)
+	}
+	byTitle := map[string]*peb.Peb{}
+	for _, p := range s.All() {
+		byTitle[p.Title] = p
+	}
+	if len(byTitle) != 4 {
+		t.Fatalf("expected 4 pebs, got %v", byTitle)
+	}
+	newA, newB := byTitle["Theirs A"].ID, byTitle["Theirs B"].ID
+
+	for title, want := range map[string][]string{
+		"Ours A":   {"peb-bbbb", newB},
+		"Theirs A": {"peb-bbbb"},
+		"Ours B":   {"peb-aaaa"},
+		"Theirs B": {"peb-aaaa", newA},
+	} {
+		if got := byTitle[title].BlockedBy; !slices.Equal(got, want) {
+			t.Errorf("expected %s blocked by %v, got %v", title, want, got)
+		}
+	}
+}
+
+func TestDedupeSequentialBranches(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	pebblesDir, _, cleanup := setupTestStore(t)
+	defer cleanup()
+	t.Chdir(pebblesDir)
+	config := "prefix = \"peb\"\nid_length = 4\nid_scheme = \"sequential\"\n"
+	if err := os.WriteFile(filepath.Join(pebblesDir, "config.toml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = filepath.Dir(pebblesDir)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	create := func(title, want string) {
+		t.Helper()
+		output, err := runInProcess(`{"title":"`+title+`","content":"`+title+`"}`, "new")
+		if err != nil || output != "Created new peb "+want {
+			t.Fatalf("expected %s, got %q, %v", want, output, err)
+		}
+		git("add", "-A")
+		git("commit", "-q", "-m", title)
+	}
+
+	// The numbers of the branches main and other are spread to the slots 7
+	// and 8 of the default stride 10.
+	git("init", "-q", "-b", "main")
+	create("First", "peb-7")
+	git("branch", "other")
+	create("Alpha", "peb-17")
+	git("checkout", "-q", "other")
+	create("Beta", "peb-8")
+	git("checkout", "-q", "main")
+	git("merge", "-q", "--no-edit", "other")
+	if output, _, err := runDedupe(); err != nil || output != "No duplicate IDs found" {
+		t.Fatalf("dedupe = %q, %v", output, err)
+	}
+	create("Gamma", "peb-27")
+
+	// Dense numbers collide and are renumbered by dedupe.
+	config += "id_stride = 1\n"
+	if err := os.WriteFile(filepath.Join(pebblesDir, "config.toml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git("commit", "-q", "-a", "-m", "Dense numbers")
+	git("branch", "dense")
+	create("Delta", "peb-28")
+	git("checkout", "-q", "dense")
+	create("Epsilon", "peb-28")
+	git("checkout", "-q", "main")
+	git("merge", "-q", "--no-edit", "dense")
+
+	if _, err := runInProcess("", "read", "peb-28"); err == nil || !strings.Contains(err.Error(), "duplicate peb ID peb-28") {
+		t.Fatalf("expected read to refuse the duplicate ID, got %v", err)
+	}
+	output, _, err := runDedupe()
+	if err != nil || output != "Renamed peb-28 in peb-28--epsilon.md to peb-29" {
+		t.Fatalf("dedupe = %q, %v", output, err)
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"github.com/urfave/cli/v2"
 	"go.yozora.eu/pebbles/internal/config"
 	"go.yozora.eu/pebbles/internal/peb"
-	"go.yozora.eu/pebbles/internal/store"
 )
 
 func DeleteCommand() *cli.Command {
@@ -36,23 +35,21 @@ Examples:
 				return fmt.Errorf("failed to update plugin: %w", err)
 			}
 
-			s := store.New(cfg.PebblesDir(), cfg.Prefix)
-			if err := s.Load(); err != nil {
+			s, err := openStore(cfg)
+			if err != nil {
 				return err
 			}
 
 			pebIDs := c.Args().Slice()
 
-			for _, pebID := range pebIDs {
-				if _, ok := s.Get(pebID); !ok {
-					return fmt.Errorf("peb %s not found", pebID)
-				}
-			}
-
 			pebsToDelete := make([]*peb.Peb, 0, len(pebIDs))
-			for _, pebID := range pebIDs {
-				p, _ := s.Get(pebID)
+			for i, pebID := range pebIDs {
+				p, err := getPeb(s, pebID)
+				if err != nil {
+					return err
+				}
 				pebsToDelete = append(pebsToDelete, p)
+				pebIDs[i] = p.ID
 			}
 
 			for _, p := range pebsToDelete {
@@ -62,9 +59,9 @@ Examples:
 			}
 
 			if len(pebIDs) == 1 {
-				fmt.Printf("Deleted peb %s.\n", pebIDs[0])
+				fmt.Fprintf(c.App.Writer, "Deleted peb %s.\n", pebIDs[0])
 			} else {
-				fmt.Printf("Deleted pebs %s.\n", strings.Join(pebIDs, " "))
+				fmt.Fprintf(c.App.Writer, "Deleted pebs %s.\n", strings.Join(pebIDs, " "))
 			}
 
 			return nil
@@ -0,0 +1,288 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"go.yozora.eu/pebbles/internal/config"
+	"go.yozora.eu/pebbles/internal/peb"
+	"go.yozora.eu/pebbles/internal/store"
+)
+
+const editErrorPrefix = "# ERROR: "
+
+var errNoChanges = errors.New("no changes")
+
+func EditCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "edit",
+		Usage:     "Edit a peb in your editor",
+		ArgsUsage: "<id>",
+		Description: `Open a peb, its frontmatter and its markdown content, in $VISUAL or $EDITOR
+(default: vi). When the editor exits, the peb is checked like "peb update"
+checks changes: the file must parse, the type and status must be valid and
+the blocked-by list must refer to existing pebs without creating a cycle. If
+it is invalid, the editor opens again with the errors as comments at the top;
+save it unchanged to give up. The id cannot be changed, and a new title
+renames the file.
+
+Use "peb new --edit" to write a new peb in the editor.
+
+Examples:
+  peb edit peb-xxxx
+  EDITOR="code --wait" peb edit peb-xxxx`,
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("peb ID is required")
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			if err := config.MaybeUpdatePlugin(cfg); err != nil {
+				return fmt.Errorf("failed to update plugin: %w", err)
+			}
+
+			s, err := openStore(cfg)
+			if err != nil {
+				return err
+			}
+
+			p, err := getPeb(s, c.Args().First())
+			if err != nil {
+				return err
+			}
+
+			changed, err := editPeb(c, s, p)
+			if err != nil {
+				return err
+			}
+			if !changed {
+				fmt.Fprintf(c.App.Writer, "No changes to %s.\n", p.ID)
+				return nil
+			}
+			fmt.Fprintf(c.App.Writer, "Updated %s.\n", p.ID)
+			return nil
+		},
+	}
+}
+
+// editPeb reports whether p was changed.
+func editPeb(c *cli.Context, s *store.Store, p *peb.Peb) (bool, error) {
+	// The peb keeps its own line endings when it is saved.
+	original := *p
+	original.Format = peb.Format{}
+	data, err := peb.Marshal(&original)
+	if err != nil {
+		return false, err
+	}
+
+	var edited *peb.Peb
+	err = editText(c, p.ID, data, func(data []byte) []error {
+		var errs []error
+		edited, errs = checkEdit(s, p, data)
+		return errs
+	})
+	if errors.Is(err, errNoChanges) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	edited.Format = p.Format
+	edited.UpdateTimestamp()
+	if err := s.Save(edited); err != nil {
+		return false, fmt.Errorf("failed to save peb: %w", err)
+	}
+	return true, nil
+}
+
+func checkEdit(s *store.Store, old *peb.Peb, data []byte) (*peb.Peb, []error) {
+	p, err := peb.Unmarshal(data)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	var errs []error
+	if p.ID != old.ID {
+		errs = append(errs, fmt.Errorf("id cannot be changed from %s", old.ID))
+		p.ID = old.ID
+	}
+	errs = append(errs, validateEdited(p)...)
+	if err := peb.ValidateBlockedBy(s, p, p.BlockedBy); err != nil {
+		errs = append(errs, err)
+	} else if err := peb.CheckCycle(s, p.ID, p.BlockedBy); err != nil {
+		errs = append(errs, err)
+	}
+	return p, errs
+}
+
+func validateEdited(p *peb.Peb) []error {
+	var errs []error
+	if strings.TrimSpace(p.Title) == "" {
+		errs = append(errs, errors.New("title is required"))
+	}
+	if err := peb.ValidateType(p.Type); err != nil {
+		errs = append(errs, err)
+	}
+	if err := peb.ValidateStatus(p.Status); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+const newTemplate = `---
+# Write the title here and the markdown description after the closing ---.
+title: ""
+type: bug
+blocked-by: []
+---
+`
+
+func editNewInput(c *cli.Context, s *store.Store) (NewInput, error) {
+	var input NewInput
+	err := editText(c, "new", []byte(newTemplate), func(data []byte) []error {
+		p, err := peb.Unmarshal(data)
+		if err != nil {
+			return []error{err}
+		}
+		input = NewInput{Title: p.Title, Content: p.Content, Type: string(p.Type), BlockedBy: p.BlockedBy}
+
+		var errs []error
+		if p.ID != "" {
+			errs = append(errs, errors.New("id is assigned when the peb is created"))
+		}
+		if strings.TrimSpace(p.Title) == "" {
+			errs = append(errs, errors.New("title is required"))
+		}
+		if err := peb.ValidateType(p.Type); err != nil {
+			errs = append(errs, err)
+		}
+		if strings.TrimSpace(p.Content) == "" {
+			errs = append(errs, errors.New("content is required"))
+		}
+		if err := peb.ValidateBlockedBy(s, nil, p.BlockedBy); err != nil {
+			errs = append(errs, err)
+		}
+		return errs
+	})
+	if errors.Is(err, errNoChanges) {
+		return input, errors.New("no peb created: the template was not changed")
+	}
+	return input, err
+}
+
+// editText opens the editor again with the errors of check added as comments
+// until it accepts the text, or gives up if an invalid text is saved
+// unchanged.
+func editText(c *cli.Context, name string, data []byte, check func([]byte) []error) error {
+	f, err := os.CreateTemp("", name+"-*.md")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	path := f.Name()
+	defer os.Remove(path)
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+
+	previous := data
+	var errs []error
+	for {
+		if err := os.WriteFile(path, withEditErrors(previous, errs), 0600); err != nil {
+			return fmt.Errorf("failed to write temporary file: %w", err)
+		}
+		if err := runEditor(c, path); err != nil {
+			return err
+		}
+		edited, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read temporary file: %w", err)
+		}
+		edited = withoutEditErrors(edited)
+
+		if bytes.Equal(edited, previous) {
+			if errs == nil {
+				return errNoChanges
+			}
+			return fmt.Errorf("edit cancelled: %w", errors.Join(errs...))
+		}
+		if errs = check(edited); len(errs) == 0 {
+			return nil
+		}
+		previous = edited
+	}
+}
+
+func runEditor(c *cli.Context, path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", path)
+	cmd.Stdin = c.App.Reader
+	cmd.Stdout = c.App.Writer
+	cmd.Stderr = c.App.ErrWriter
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+	return nil
+}
+
+// The line numbers of parse errors count the lines withEditErrors adds.
+func withEditErrors(data []byte, errs []error) []byte {
+	if len(errs) == 0 {
+		return data
+	}
+	first, _, _ := bytes.Cut(data, []byte("\n"))
+	insertAt := 0
+	if string(bytes.TrimRight(first, "\r")) == "---" {
+		insertAt = len(first) + 1
+	}
+
+	var comments bytes.Buffer
+	for _, err := range errs {
+		var parseErr *peb.ParseError
+		if errors.As(err, &parseErr) {
+			err = parseErr.Err
+			if line := parseErr.Line; line > 0 {
+				if insertAt > 0 && line > 1 {
+					line += len(errs)
+				}
+				err = fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		comments.WriteString(editErrorPrefix + strings.ReplaceAll(err.Error(), "\n", " ") + "\n")
+	}
+
+	result := append([]byte{}, data[:insertAt]...)
+	result = append(result, comments.Bytes()...)
+	return append(result, data[insertAt:]...)
+}
+
+func withoutEditErrors(data []byte) []byte {
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	start := 0
+	if len(lines) > 0 && string(bytes.TrimRight(lines[0], "\r\n")) == "---" {
+		start = 1
+	}
+	end := start
+	for end < len(lines) && bytes.HasPrefix(lines[end], []byte(editErrorPrefix)) {
+		end++
+	}
+	if end == start {
+		return data
+	}
+	return bytes.Join(append(lines[:start:start], lines[end:]...), nil)
+}
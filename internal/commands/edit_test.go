@@ -0,0 +1,189 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+	"go.yozora.eu/pebbles/internal/peb"
+	"go.yozora.eu/pebbles/internal/store"
+)
+
+func runEdit(args ...string) (string, error) {
+	var out bytes.Buffer
+	app := &cli.App{
+		Name:      "peb",
+		Reader:    strings.NewReader(""),
+		Writer:    &out,
+		ErrWriter: &out,
+		Commands:  []*cli.Command{EditCommand(), NewCommand()},
+	}
+	err := app.Run(append([]string{"peb"}, args...))
+	return strings.TrimSpace(out.String()), err
+}
+
+// fakeEditor makes $EDITOR run the shell scripts steps, one per time the
+// editor is opened, on the edited file, which is "$1". It returns a function
+// that returns the file as the editor saw it the n-th time.
+func fakeEditor(t *testing.T, steps ...string) func(n int) string {
+	t.Helper()
+	dir := t.TempDir()
+	script := `n=$(($(cat "$EDIT_DIR/count" 2>/dev/null || echo 0) + 1))
+echo $n > "$EDIT_DIR/count"
+cp "$1" "$EDIT_DIR/seen$n"
+sh "$EDIT_DIR/step$n" "$1"
+`
+	if err := os.WriteFile(filepath.Join(dir, "editor.sh"), []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for i, step := range steps {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("step%d", i+1)), []byte(step), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("EDIT_DIR", dir)
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "sh "+filepath.Join(dir, "editor.sh"))
+	return func(n int) string {
+		data, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf("seen%d", n)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+}
+
+// sedStep returns an editor step that applies a sed script to the file.
+func sedStep(script string) string {
+	return fmt.Sprintf(`sed '%s' "$1" > "$1.tmp" && mv "$1.tmp" "$1"`, script)
+}
+
+func TestEditCommand(t *testing.T) {
+	pebblesDir, s, cleanup := setupTestStore(t)
+	defer cleanup()
+	t.Chdir(pebblesDir)
+
+	p := peb.New("peb-aaaa", "Old title", peb.TypeBug, peb.StatusNew, "Some content\n")
+	if err := s.Save(p); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := fakeEditor(t,
+		sedStep("s/^title: .*/title: New title/; s/^type: .*/type: chore/"),
+		sedStep("s/^type: .*/type: feature/"),
+	)
+	output, err := runEdit("edit", "aaaa")
+	if err != nil {
+		t.Fatalf("peb edit failed: %v", err)
+	}
+	if output != "Updated peb-aaaa." {
+		t.Errorf("unexpected output %q", output)
+	}
+	if !strings.Contains(seen(2), "---\n# ERROR: invalid type \"chore\": must be one of bug, feature, epic, task\n") {
+		t.Errorf("expected the error as a comment, got:\n%s", seen(2))
+	}
+
+	s = store.New(pebblesDir, "peb")
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	edited, ok := s.Get("peb-aaaa")
+	if !ok {
+		t.Fatal("expected peb to exist")
+	}
+	if edited.Title != "New title" || edited.Type != peb.TypeFeature || edited.Content != "Some content\n" {
+		t.Errorf("unexpected peb %+v", edited)
+	}
+	if filename, _ := s.Filename("peb-aaaa"); filename != "peb-aaaa--new-title.md" {
+		t.Errorf("expected file to be renamed, got %s", filename)
+	}
+	if _, err := os.Stat(filepath.Join(pebblesDir, "peb-aaaa--old-title.md")); !os.IsNotExist(err) {
+		t.Errorf("expected old file to be removed, got %v", err)
+	}
+}
+
+func TestEditCommandErrors(t *testing.T) {
+	pebblesDir, s, cleanup := setupTestStore(t)
+	defer cleanup()
+	t.Chdir(pebblesDir)
+
+	if err := s.Save(peb.New("peb-aaaa", "Title", peb.TypeBug, peb.StatusNew, "Content\n")); err != nil {
+		t.Fatal(err)
+	}
+	blocked := peb.New("peb-bbbb", "Blocked", peb.TypeBug, peb.StatusNew, "")
+	blocked.BlockedBy = []string{"peb-aaaa"}
+	if err := s.Save(blocked); err != nil {
+		t.Fatal(err)
+	}
+	original, err := os.ReadFile(filepath.Join(pebblesDir, "peb-aaaa--title.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fakeEditor(t, "true")
+	if output, err := runEdit("edit", "peb-aaaa"); err != nil || output != "No changes to peb-aaaa." {
+		t.Errorf("expected no changes, got %q, %v", output, err)
+	}
+
+	seen := fakeEditor(t, sedStep("s/^status: .*/status: a: b/"), "true")
+	_, err = runEdit("edit", "peb-aaaa")
+	if err == nil || !strings.Contains(err.Error(), "edit cancelled") {
+		t.Fatalf("expected edit to be cancelled, got %v", err)
+	}
+	// The line number of the parse error counts the error comment.
+	lines := strings.Split(seen(2), "\n")
+	var line int
+	if _, err := fmt.Sscanf(lines[1], "# ERROR: line %d:", &line); err != nil || !strings.HasPrefix(lines[line-1], "status: a: b") {
+		t.Errorf("expected the error to point at the status line, got:\n%s", seen(2))
+	}
+
+	fakeEditor(t, `awk '{ print } /^title:/ { print "blocked-by: [peb-bbbb]" }' "$1" | sed 's/^id: .*/id: peb-cccc/' > "$1.tmp" && mv "$1.tmp" "$1"`, "true")
+	_, err = runEdit("edit", "peb-aaaa")
+	if err == nil || !strings.Contains(err.Error(), "id cannot be changed from peb-aaaa") || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("expected id and cycle errors, got %v", err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(pebblesDir, "peb-aaaa--title.md")); !bytes.Equal(data, original) {
+		t.Errorf("expected peb to be unchanged, got:\n%s", data)
+	}
+}
+
+func TestNewCommandEdit(t *testing.T) {
+	pebblesDir, _, cleanup := setupTestStore(t)
+	defer cleanup()
+	t.Chdir(pebblesDir)
+
+	fakeEditor(t, "true")
+	if _, err := runEdit("new", "--edit"); err == nil || !strings.Contains(err.Error(), "template was not changed") {
+		t.Errorf("expected error for unchanged template, got %v", err)
+	}
+
+	seen := fakeEditor(t,
+		sedStep("s/^title: .*/title: From the editor/"),
+		`printf 'Written in the editor.\n' >> "$1"`,
+	)
+	output, err := runEdit("new", "--edit")
+	if err != nil {
+		t.Fatalf("peb new --edit failed: %v", err)
+	}
+	if !strings.Contains(seen(2), "# ERROR: content is required\n") {
+		t.Errorf("expected content error, got:\n%s", seen(2))
+	}
+	id := strings.TrimPrefix(output, "Created new peb ")
+
+	s := store.New(pebblesDir, "peb")
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	p, ok := s.Get(id)
+	if !ok {
+		t.Fatalf("expected peb %q to be created", id)
+	}
+	if p.Title != "From the editor" || p.Type != peb.TypeBug || p.Content != "Written in the editor.\n" {
+		t.Errorf("unexpected peb %+v", p)
+	}
+}
@@ -0,0 +1,146 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"go.yozora.eu/pebbles/internal/config"
+	"go.yozora.eu/pebbles/internal/interop"
+	"go.yozora.eu/pebbles/internal/peb"
+	"go.yozora.eu/pebbles/internal/store"
+)
+
+// Only the dump formats write the config.
+type exporter func(w io.Writer, s *store.Store, d *interop.Dump) error
+
+var exportFormats = map[string]exporter{
+	"json": func(w io.Writer, s *store.Store, d *interop.Dump) error {
+		return interop.WriteDump(w, d)
+	},
+	"jsonl": func(w io.Writer, s *store.Store, d *interop.Dump) error {
+		return interop.WriteDumpLines(w, d)
+	},
+	"github-json": func(w io.Writer, s *store.Store, d *interop.Dump) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(interop.ToGitHub(d.Pebs, s.Get))
+	},
+	"beads": func(w io.Writer, s *store.Store, d *interop.Dump) error {
+		return interop.WriteBeads(w, interop.ToBeads(d.Pebs))
+	},
+}
+
+func ExportCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "export",
+		Usage:     "Export pebs as a dump or for other issue trackers",
+		ArgsUsage: "[filters]",
+		Description: `Export the pebs matching the filters (default: all) to stdout.
+
+Accepts the same filters as "peb query".
+
+Formats:
+  json         A full-fidelity dump of the pebs as a JSON document (default).
+               Every field and the content are kept exactly, so that
+               "peb import" restores the pebs unchanged. With --config, the
+               dump includes config.toml.
+  jsonl        The same dump as JSON lines: a header line with the format
+               version and config, then one line per peb
+  github-json  A JSON array of issues in the format of "gh issue list --json".
+               The type and the "labels" frontmatter key become labels,
+               in-progress pebs get the label in-progress, and closed pebs are closed as completed (fixed)
+               or not planned (wont-fix). Pebs imported from GitHub keep
+               their issue number, and blockers with an issue number are
+               appended to the body as "Blocked by #N".
+  beads        beads JSONL issues. Blocked-by references become "blocks"
+               dependencies; the peb IDs are kept as issue IDs.
+
+Pebs are exported sorted by ID, so exporting the same pebs always writes
+the same bytes, and a dump survives an import and export unchanged.
+
+Examples:
+  peb export --config > backup.json
+  peb export --to jsonl status:open > open.jsonl
+  peb export --to github-json > issues.json
+  peb export --to github-json status:open type:bug
+  peb export --to beads > issues.jsonl`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "to",
+				Usage: "Output format: " + strings.Join(sortedKeys(exportFormats), ", "),
+				Value: "json",
+			},
+			&cli.BoolFlag{
+				Name:  "config",
+				Usage: "Include config.toml in the dump",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			format := c.String("to")
+			exp, ok := exportFormats[format]
+			if !ok {
+				return fmt.Errorf("unknown export format %q (valid formats: %s)", format, strings.Join(sortedKeys(exportFormats), ", "))
+			}
+			if !dumpFormats[format] && c.Bool("config") {
+				return fmt.Errorf("--config requires the json or jsonl format")
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			if err := config.MaybeUpdatePlugin(cfg); err != nil {
+				return fmt.Errorf("failed to update plugin: %w", err)
+			}
+
+			s := store.New(cfg.PebblesDir(), cfg.Prefix)
+			if err := s.Load(); err != nil {
+				return err
+			}
+			warnInvalid(c.App.ErrWriter, s, cfg.Prefix)
+
+			args, err := expandSavedQueries(c.Args().Slice(), cfg.Queries)
+			if err != nil {
+				return err
+			}
+
+			filters, err := parseFilters(args, s.Resolve)
+			if err != nil {
+				return err
+			}
+
+			pebs := s.All()
+			sort.Slice(pebs, func(i, j int) bool {
+				return pebs[i].ID < pebs[j].ID
+			})
+
+			matches := []*peb.Peb{}
+			for _, p := range pebs {
+				if applyFilters(p, filters) {
+					matches = append(matches, p)
+				}
+			}
+
+			d := &interop.Dump{Version: interop.DumpVersion, Pebs: matches}
+			if c.Bool("config") {
+				data, err := os.ReadFile(filepath.Join(cfg.PebblesDir(), "config.toml"))
+				if err != nil {
+					return fmt.Errorf("failed to read config: %w", err)
+				}
+				d.Config = string(data)
+			}
+
+			if err := exp(c.App.Writer, s, d); err != nil {
+				return fmt.Errorf("failed to export pebs: %w", err)
+			}
+			return nil
+		},
+	}
+}
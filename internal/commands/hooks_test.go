@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.yozora.eu/pebbles/internal/peb"
+)
+
+func TestUpdateCommandHooks(t *testing.T) {
+	pebblesDir, s, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	config := `prefix = "peb"
+id_length = 4
+
+[hooks]
+pre_update = 'grep -q "\"title\":\"Forbidden\"" && { echo "title is forbidden"; exit 1; } || exit 0'
+on_close = 'cat > closed.json'
+`
+	if err := os.WriteFile(filepath.Join(pebblesDir, "config.toml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := peb.New("peb-aaaa", "Peb", peb.TypeTask, peb.StatusNew, "Content")
+	if err := s.Save(p); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(pebblesDir)
+
+	output := runCommand([]string{"update", "peb-aaaa", `{"title":"Forbidden"}`})
+	if !strings.Contains(output, "pre_update hook rejected the change: title is forbidden") {
+		t.Errorf("expected veto, got %q", output)
+	}
+	if _, err := os.Stat(filepath.Join(pebblesDir, "peb-aaaa--peb.md")); err != nil {
+		t.Errorf("expected vetoed update to leave the peb unchanged: %v", err)
+	}
+
+	output = runCommand([]string{"update", "peb-aaaa", `{"status":"fixed"}`})
+	if strings.Contains(output, "Error") {
+		t.Fatalf("unexpected error %q", output)
+	}
+
+	// Hooks run in the project directory, the parent of .pebbles/.
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(pebblesDir), "closed.json"))
+	if err != nil {
+		t.Fatalf("expected on_close hook to run: %v", err)
+	}
+	if !strings.Contains(string(data), `"hook":"on_close"`) || !strings.Contains(string(data), `"status":"fixed"`) {
+		t.Errorf("unexpected hook input %s", data)
+	}
+}
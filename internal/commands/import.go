@@ -0,0 +1,304 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"go.yozora.eu/pebbles/internal/config"
+	"go.yozora.eu/pebbles/internal/interop"
+	"go.yozora.eu/pebbles/internal/peb"
+	"go.yozora.eu/pebbles/internal/store"
+)
+
+type importInput struct {
+	path       string
+	stdin      io.Reader
+	existing   interop.Existing
+	exists     func(id string) bool
+	newID      interop.IDGenerator
+	onConflict interop.ConflictPolicy
+	// config requires the input to include a config.toml.
+	config bool
+}
+
+type importer func(in importInput) (*interop.Result, error)
+
+var importFormats = map[string]importer{
+	"json":  importDump,
+	"jsonl": importDump,
+	"github-json": func(in importInput) (*interop.Result, error) {
+		var issues []interop.GitHubIssue
+		err := withInput(in.path, in.stdin, func(r io.Reader) (err error) {
+			issues, err = interop.ReadGitHub(r)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return interop.FromGitHub(issues, in.existing, in.newID)
+	},
+	"beads": func(in importInput) (*interop.Result, error) {
+		var issues []interop.BeadsIssue
+		err := withInput(in.path, in.stdin, func(r io.Reader) (err error) {
+			issues, err = interop.ReadBeads(r)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return interop.FromBeads(issues, in.existing, in.newID)
+	},
+	"beans": func(in importInput) (*interop.Result, error) {
+		beans, err := interop.ReadBeans(in.path)
+		if err != nil {
+			return nil, err
+		}
+		return interop.FromBeans(beans, in.existing, in.newID)
+	},
+}
+
+func importDump(in importInput) (*interop.Result, error) {
+	var d *interop.Dump
+	err := withInput(in.path, in.stdin, func(r io.Reader) (err error) {
+		d, err = interop.ReadDump(r)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if in.config && d.Config == "" {
+		return nil, fmt.Errorf("the dump does not include a config")
+	}
+	result, err := interop.FromDump(d, in.exists, in.onConflict, in.newID)
+	if err != nil && in.onConflict == interop.ConflictError {
+		return nil, fmt.Errorf("%w (use --on-conflict skip, overwrite or reid)", err)
+	}
+	return result, err
+}
+
+var dumpFormats = map[string]bool{"json": true, "jsonl": true}
+
+func withInput(path string, stdin io.Reader, fn func(io.Reader) error) error {
+	if path == "-" {
+		return fn(stdin)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open input: %w", err)
+	}
+	defer f.Close()
+	return fn(f)
+}
+
+func ImportCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "import",
+		Usage:     "Import pebs from a dump or from other issue trackers",
+		ArgsUsage: "<file>",
+		Description: `Import pebs from a file, or from stdin if the file is "-".
+
+Formats:
+  json         A dump written by "peb export" (default). JSON and JSONL
+               dumps are both accepted.
+  jsonl        Same as json
+  github-json  A JSON array of issues as printed by
+               "gh issue list --json number,title,body,state,stateReason,labels,createdAt,updatedAt"
+               or returned by the GitHub or GitLab REST API
+  beads        A beads JSONL file, usually .beads/issues.jsonl
+  beans        A beans directory, usually .beans/
+
+Dumps are restored exactly: IDs, timestamps, blocked-by references and
+content are kept as they are. --on-conflict decides what happens to pebs
+whose ID already exists:
+  error      Fail the import without changing anything (default)
+  skip       Keep the existing peb
+  overwrite  Replace the existing peb with the one from the dump
+  reid       Import the peb under a new ID, and rewrite blocked-by
+             references and mentions of the old ID in the imported pebs
+--config also restores the config.toml included in the dump.
+
+Pebs imported from other issue trackers record where they came from in
+their source field, e.g. "github#12", "beads:bd-a1b2" or "beans:beans-x1y2".
+Imports skip issues imported before and resolve references to them, so the
+source fields are the mapping table between the old and the new IDs.
+
+GitHub issues are imported as follows:
+  - The title and body become the title and content of the peb
+  - The first of the labels bug, feature, enhancement, epic and task sets
+    the type (default: bug); the label in-progress marks open issues as
+    in progress; other labels are kept in the frontmatter key "labels"
+  - Closed issues become fixed, or wont-fix if closed as not planned
+  - The issue number is recorded in the source field, e.g. "github#12"
+  - "Blocked by #12" in the body becomes a blocked-by reference
+
+beads issues and beans are imported as follows:
+  - Statuses and types are mapped to the closest peb status and type
+    (closed beads issues become fixed, scrapped beans wont-fix)
+  - beads design, acceptance criteria and notes become content sections
+  - "blocks" dependencies become blocked-by references, and parents are
+    blocked by their children like epics
+  - Mentions of imported IDs in the content are rewritten to the new IDs
+
+Anything that can't be mapped, such as priorities, beads labels, tags,
+assignees and related links, is reported as a warning, as are references to
+unknown issues and blocked-by references that would create a cycle.
+
+All pebs are imported at once, or none if an error occurs. Prints the peb
+ID assigned to every imported issue.
+
+Examples:
+  peb import backup.json
+  peb import --on-conflict reid other-project.jsonl
+  peb import --config backup.json
+  gh issue list --state all --json number,title,body,state,stateReason,labels > issues.json
+  peb import --from github-json issues.json
+  peb import --from beads .beads/issues.jsonl
+  peb import --from beans .beans`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "from",
+				Usage: "Input format: " + strings.Join(sortedKeys(importFormats), ", "),
+				Value: "json",
+			},
+			&cli.StringFlag{
+				Name:  "on-conflict",
+				Usage: "What to do with dumped pebs whose ID exists: error, skip, overwrite, reid",
+				Value: string(interop.ConflictError),
+			},
+			&cli.BoolFlag{
+				Name:  "config",
+				Usage: "Restore the config.toml included in the dump",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("input file is required (use - for stdin)")
+			}
+
+			format := c.String("from")
+			imp, ok := importFormats[format]
+			if !ok {
+				return fmt.Errorf("unknown import format %q (valid formats: %s)", format, strings.Join(sortedKeys(importFormats), ", "))
+			}
+			if !dumpFormats[format] && (c.IsSet("on-conflict") || c.Bool("config")) {
+				return fmt.Errorf("--on-conflict and --config require a json or jsonl dump")
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			if err := config.MaybeUpdatePlugin(cfg); err != nil {
+				return fmt.Errorf("failed to update plugin: %w", err)
+			}
+
+			s, err := openStore(cfg)
+			if err != nil {
+				return err
+			}
+
+			in := importInput{
+				path:       c.Args().First(),
+				stdin:      c.App.Reader,
+				onConflict: interop.ConflictPolicy(c.String("on-conflict")),
+				config:     c.Bool("config"),
+			}
+			result, err := importPebs(s, cfg, in, imp)
+			if err != nil {
+				return err
+			}
+
+			for _, w := range result.Warnings {
+				fmt.Fprintf(c.App.ErrWriter, "Warning: %s\n", w)
+			}
+			refs := make(map[string]string, len(result.Mapping))
+			for ref, id := range result.Mapping {
+				refs[id] = ref
+			}
+			for _, p := range result.Pebs {
+				if ref := refs[p.ID]; ref != p.ID {
+					fmt.Fprintf(c.App.Writer, "Imported %s as %s\n", ref, p.ID)
+				} else {
+					fmt.Fprintf(c.App.Writer, "Imported %s\n", p.ID)
+				}
+			}
+			fmt.Fprintf(c.App.Writer, "Imported %d pebs.\n", len(result.Pebs))
+
+			if in.config {
+				if err := os.WriteFile(filepath.Join(cfg.PebblesDir(), "config.toml"), []byte(result.Config), 0644); err != nil {
+					return fmt.Errorf("failed to restore config: %w", err)
+				}
+				fmt.Fprintln(c.App.Writer, "Restored config.toml.")
+			}
+			return nil
+		},
+	}
+}
+
+// importPebs drops blocked-by references that don't exist or would create a
+// cycle with a warning.
+func importPebs(s *store.Store, cfg *config.Config, in importInput, imp importer) (*interop.Result, error) {
+	b := &batch{
+		s:       s,
+		current: make(map[string]*peb.Peb),
+	}
+
+	in.existing = interop.SourceIndex(s.All())
+	in.exists = s.Exists
+	in.newID = func() (string, error) {
+		id, err := b.generateID(cfg, "")
+		if err != nil {
+			return "", err
+		}
+		// Reserve the ID for the rest of the import.
+		b.current[id] = nil
+		return id, nil
+	}
+	result, err := imp(in)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range result.Pebs {
+		b.current[p.ID] = p
+	}
+
+	// Add the blocked-by references one at a time, so that a cycle is
+	// reported at the reference that closes it.
+	blockers := make([][]string, len(result.Pebs))
+	for i, p := range result.Pebs {
+		blockers[i] = p.BlockedBy
+		p.BlockedBy = nil
+	}
+
+	changes := make([]store.Change, 0, len(result.Pebs))
+	for i, p := range result.Pebs {
+		for _, id := range blockers[i] {
+			if _, ok := b.Get(id); !ok {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("%s: blocker %s not found", p.ID, id))
+				continue
+			}
+			if err := peb.CheckCycle(b, p.ID, append(slices.Clone(p.BlockedBy), id)); err != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("%s: blocked-by %s dropped: %v", p.ID, id, err))
+				continue
+			}
+			p.BlockedBy = append(p.BlockedBy, id)
+		}
+		change := store.Change{New: p}
+		if old, ok := s.Get(p.ID); ok {
+			change.Old = old
+		}
+		changes = append(changes, change)
+	}
+
+	if err := s.Apply(changes); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,229 @@
+package commands
+
+import (
+	"bytes"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+	"go.yozora.eu/pebbles/internal/config"
+	"go.yozora.eu/pebbles/internal/interop"
+	"go.yozora.eu/pebbles/internal/peb"
+	"go.yozora.eu/pebbles/internal/store"
+)
+
+func TestImportGitHub(t *testing.T) {
+	pebblesDir, _, cleanup := setupTestStore(t)
+	defer cleanup()
+	t.Chdir(pebblesDir)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := openStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := `[
+  {"number": 1, "title": "One", "body": "Blocked by #2", "state": "OPEN", "labels": [{"name": "bug"}]},
+  {"number": 2, "title": "Two", "body": "Blocked by #1", "state": "OPEN", "labels": [{"name": "task"}]}
+]`
+	in := importInput{path: "-", stdin: strings.NewReader(input)}
+	result, err := importPebs(s, cfg, in, importFormats["github-json"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Pebs) != 2 {
+		t.Fatalf("expected 2 imported pebs, got %d", len(result.Pebs))
+	}
+	one, two := result.Pebs[0], result.Pebs[1]
+
+	// The reference closing the cycle is dropped.
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "cycle") {
+		t.Errorf("expected a cycle warning, got %q", result.Warnings)
+	}
+
+	s = store.New(pebblesDir, "peb")
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := s.Get(one.ID); !slices.Equal(p.BlockedBy, []string{two.ID}) || p.Source != "github#1" {
+		t.Errorf("unexpected imported peb %+v", p)
+	}
+	if p, _ := s.Get(two.ID); len(p.BlockedBy) != 0 {
+		t.Errorf("expected cyclic reference to be dropped, got %v", p.BlockedBy)
+	}
+
+	var out bytes.Buffer
+	d := &interop.Dump{Pebs: []*peb.Peb{s.All()[0]}}
+	if err := exportFormats["github-json"](&out, s, d); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `"number": `) {
+		t.Errorf("expected issue number in export, got %s", out.String())
+	}
+}
+
+func runDumpCommand(stdin string, args ...string) (string, error) {
+	var out bytes.Buffer
+	app := &cli.App{
+		Name:      "peb",
+		Reader:    strings.NewReader(stdin),
+		Writer:    &out,
+		ErrWriter: &out,
+		Commands:  []*cli.Command{ExportCommand(), ImportCommand()},
+	}
+	err := app.Run(append([]string{"peb"}, args...))
+	return out.String(), err
+}
+
+func readPebFiles(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "peb-*.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents := make(map[string]string, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents[filepath.Base(file)] = string(data)
+	}
+	return contents
+}
+
+func TestDumpRoundTrip(t *testing.T) {
+	srcDir, s, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	blocker := peb.New("peb-aaaa", "Blocker", peb.TypeTask, peb.StatusFixed, "Some <html> & \"quotes\"\r\n")
+	blocked := peb.New("peb-bbbb", "Blocked", peb.TypeBug, peb.StatusNew, "Needs peb-aaaa first.\n")
+	blocked.BlockedBy = []string{"peb-aaaa"}
+	blocked.Source = "github#3"
+	for _, p := range []*peb.Peb{blocker, blocked} {
+		if err := s.Save(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Chdir(srcDir)
+	dump, err := runDumpCommand("", "export", "--config")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dstDir, _, cleanup := setupTestStore(t)
+	defer cleanup()
+	if err := os.WriteFile(filepath.Join(dstDir, "config.toml"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dstDir)
+
+	if _, err := runDumpCommand(dump, "import", "--config", "-"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readPebFiles(t, dstDir), readPebFiles(t, srcDir); !maps.Equal(got, want) {
+		t.Errorf("restored files differ:\n%v\nwant:\n%v", got, want)
+	}
+	srcConfig, _ := os.ReadFile(filepath.Join(srcDir, "config.toml"))
+	dstConfig, _ := os.ReadFile(filepath.Join(dstDir, "config.toml"))
+	if !bytes.Equal(srcConfig, dstConfig) {
+		t.Errorf("config not restored, got %q", dstConfig)
+	}
+
+	for _, format := range []string{"json", "jsonl"} {
+		first, err := runDumpCommand("", "export", "--config", "--to", format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if format == "json" && first != dump {
+			t.Errorf("dump changed after round trip:\n%s\nwant:\n%s", first, dump)
+		}
+		if _, err := runDumpCommand(first, "import", "--on-conflict", "overwrite", "-"); err != nil {
+			t.Fatal(err)
+		}
+		second, err := runDumpCommand("", "export", "--config", "--to", format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if second != first {
+			t.Errorf("%s dump changed after round trip:\n%s\nwant:\n%s", format, second, first)
+		}
+	}
+}
+
+func TestDumpConflicts(t *testing.T) {
+	pebblesDir, s, cleanup := setupTestStore(t)
+	defer cleanup()
+	t.Chdir(pebblesDir)
+
+	blocker := peb.New("peb-aaaa", "Blocker", peb.TypeTask, peb.StatusNew, "")
+	blocked := peb.New("peb-bbbb", "Blocked", peb.TypeBug, peb.StatusNew, "Needs peb-aaaa first.\n")
+	blocked.BlockedBy = []string{"peb-aaaa"}
+	for _, p := range []*peb.Peb{blocker, blocked} {
+		if err := s.Save(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dump, err := runDumpCommand("", "export", "--to", "jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := runDumpCommand(dump, "import", "-"); err == nil || !strings.Contains(err.Error(), "peb-aaaa, peb-bbbb") {
+		t.Errorf("expected conflict error, got %v", err)
+	}
+
+	out, err := runDumpCommand(dump, "import", "--on-conflict", "skip", "-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Imported 0 pebs.") {
+		t.Errorf("expected pebs to be skipped, got %s", out)
+	}
+
+	renamed := strings.Replace(dump, `"title":"Blocker"`, `"title":"Renamed"`, 1)
+	if _, err := runDumpCommand(renamed, "import", "--on-conflict", "overwrite", "-"); err != nil {
+		t.Fatal(err)
+	}
+	files := readPebFiles(t, pebblesDir)
+	if _, ok := files["peb-aaaa--renamed.md"]; !ok || len(files) != 2 {
+		t.Errorf("expected peb-aaaa to be overwritten, got %v", slices.Sorted(maps.Keys(files)))
+	}
+
+	out, err = runDumpCommand(dump, "import", "--on-conflict", "reid", "-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Imported peb-aaaa as ") || !strings.Contains(out, "Imported 2 pebs.") {
+		t.Errorf("unexpected output %s", out)
+	}
+
+	s = store.New(pebblesDir, "peb")
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.All()) != 4 {
+		t.Fatalf("expected 4 pebs, got %d", len(s.All()))
+	}
+	for _, p := range s.All() {
+		if p.ID == "peb-aaaa" || p.ID == "peb-bbbb" || p.Title != "Blocked" {
+			continue
+		}
+		newBlocker := p.BlockedBy[0]
+		if newBlocker == "peb-aaaa" || p.Content != "Needs "+newBlocker+" first.\n" {
+			t.Errorf("expected references to be rewritten, got %+v", p)
+		}
+		if b, ok := s.Get(newBlocker); !ok || b.Title != "Blocker" {
+			t.Errorf("expected %s to be the re-IDed blocker, got %+v", newBlocker, b)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/urfave/cli/v2"
 	"go.yozora.eu/pebbles/internal/config"
+	"go.yozora.eu/pebbles/internal/migrate"
 )
 
 func InitCommand() *cli.Command {
@@ -33,7 +34,7 @@ func InitCommand() *cli.Command {
 				if err := os.WriteFile(configPath, []byte(config.DefaultConfigContent()), 0644); err != nil {
 					return fmt.Errorf("failed to create config.toml: %w", err)
 				}
-				fmt.Println("Initialized pebbles in .pebbles/")
+				fmt.Fprintln(c.App.Writer, "Initialized pebbles in .pebbles/")
 			}
 
 			cfg, err := config.Load()
@@ -45,18 +46,30 @@ func InitCommand() *cli.Command {
 				return fmt.Errorf("failed to update plugin: %w", err)
 			}
 
+			if pending := migrate.Pending(cfg.Version); len(pending) > 0 {
+				fmt.Fprintf(c.App.Writer, "Run \"peb migrate\" to upgrade .pebbles/ from format version %d to %d\n", cfg.Version, pending[len(pending)-1].Version)
+			}
+
+			installed, err := installMergeDriver(cfg.ProjectDir())
+			if err != nil {
+				return err
+			}
+			if installed {
+				fmt.Fprintln(c.App.Writer, "Configured git merge driver for peb files")
+			}
+
 			if c.Bool("opencode") {
 				if err := config.InstallOpencodePlugin(cfg); err != nil {
 					return fmt.Errorf("failed to install opencode plugin: %w", err)
 				}
-				fmt.Println("Installed opencode MCP plugin")
+				fmt.Fprintln(c.App.Writer, "Installed opencode MCP plugin")
 			}
 
 			if c.Bool("pi") {
 				if err := config.InstallPiExtension(cfg); err != nil {
 					return fmt.Errorf("failed to install pi extension: %w", err)
 				}
-				fmt.Println("Installed pi agent extension")
+				fmt.Fprintln(c.App.Writer, "Installed pi agent extension")
 			}
 
 			return nil
@@ -0,0 +1,181 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"go.yozora.eu/pebbles/internal/config"
+	"go.yozora.eu/pebbles/internal/peb"
+	"go.yozora.eu/pebbles/internal/store"
+	"go.yozora.eu/pebbles/internal/vcs"
+)
+
+func LinkCommitCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "link-commit",
+		Usage:     "Link pebs to the commits that implement them",
+		ArgsUsage: "<id> <rev> [<rev> ...]",
+		Description: `Record commits in the commits field of a peb, so that "peb query
+commit:<rev>" finds the peb a commit belongs to and "peb read" lists the
+commits of a peb.
+
+Revisions are resolved in the git or jj repository of the project. git
+commits are recorded by commit hash, jj commits by change ID, which stays
+the same when the commit is rewritten.
+
+With --scan, the commit messages of the history are searched for peb IDs
+instead, and every commit is linked to the pebs it mentions. --revs limits
+the scan to a git revision range or jj revset (default: the history of the
+working copy). Scanning again only adds new links, so it can run from a
+post-commit hook.
+
+Linking commits does not change the changed timestamp of a peb.
+
+Examples:
+  peb link-commit peb-xxxx HEAD
+  peb link-commit peb-xxxx @- kxqpmwvz
+  peb link-commit --scan
+  peb link-commit --scan --revs v1.2.0..HEAD`,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "scan",
+				Usage: "Link commits to the pebs mentioned in their messages",
+			},
+			&cli.StringFlag{
+				Name:  "revs",
+				Usage: "Revision range or revset to scan with --scan",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			scan := c.Bool("scan")
+			if scan && c.NArg() > 0 {
+				return fmt.Errorf("--scan takes no arguments")
+			}
+			if !scan && c.NArg() < 2 {
+				return fmt.Errorf("peb ID and at least one revision are required")
+			}
+			if !scan && c.IsSet("revs") {
+				return fmt.Errorf("--revs requires --scan")
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			if err := config.MaybeUpdatePlugin(cfg); err != nil {
+				return fmt.Errorf("failed to update plugin: %w", err)
+			}
+
+			repo, err := vcs.Detect(cfg.ProjectDir())
+			if err != nil {
+				return err
+			}
+
+			s, err := openStore(cfg)
+			if err != nil {
+				return err
+			}
+
+			var links []commitLink
+			if scan {
+				commits, err := repo.Log(c.String("revs"))
+				if err != nil {
+					return err
+				}
+				links = scanCommits(s, cfg, commits)
+			} else {
+				p, err := getPeb(s, c.Args().First())
+				if err != nil {
+					return err
+				}
+				for _, rev := range c.Args().Slice()[1:] {
+					commit, err := repo.Resolve(rev)
+					if err != nil {
+						return err
+					}
+					links = append(links, commitLink{id: p.ID, commit: commit})
+				}
+			}
+
+			linked, err := linkCommits(s, links)
+			if err != nil {
+				return err
+			}
+			for _, l := range linked {
+				fmt.Fprintf(c.App.Writer, "Linked %s to %s\n", vcs.ShortID(l.commit), l.id)
+			}
+			if scan {
+				fmt.Fprintf(c.App.Writer, "Linked %d commits.\n", len(linked))
+			}
+			return nil
+		},
+	}
+}
+
+type commitLink struct {
+	id     string
+	commit string
+}
+
+func scanCommits(s *store.Store, cfg *config.Config, commits []vcs.Commit) []commitLink {
+	pattern := regexp.MustCompile(`(?i)\b` + peb.IDPattern(cfg.Prefix, cfg.IDLength) + `\b`)
+
+	var links []commitLink
+	for _, commit := range slices.Backward(commits) {
+		seen := make(map[string]bool)
+		for _, match := range pattern.FindAllString(commit.Message, -1) {
+			id := strings.ToLower(match)
+			if seen[id] || !s.Exists(id) {
+				continue
+			}
+			seen[id] = true
+			links = append(links, commitLink{id: id, commit: commit.ID})
+		}
+	}
+	return links
+}
+
+// linkCommits returns the links that were not recorded before.
+func linkCommits(s *store.Store, links []commitLink) ([]commitLink, error) {
+	updated := make(map[string]*peb.Peb)
+	var order []string
+	var added []commitLink
+	for _, l := range links {
+		p, ok := updated[l.id]
+		if !ok {
+			old, ok := s.Get(l.id)
+			if !ok {
+				return nil, notFound(s, l.id)
+			}
+			copied := *old
+			copied.Commits = slices.Clone(old.Commits)
+			p = &copied
+			updated[l.id] = p
+			order = append(order, l.id)
+		}
+		if slices.Contains(p.Commits, l.commit) {
+			continue
+		}
+		p.Commits = append(p.Commits, l.commit)
+		added = append(added, l)
+	}
+
+	var changes []store.Change
+	for _, id := range order {
+		old, _ := s.Get(id)
+		if len(updated[id].Commits) != len(old.Commits) {
+			changes = append(changes, store.Change{Old: old, New: updated[id]})
+		}
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	if err := s.Apply(changes); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+	"go.yozora.eu/pebbles/internal/peb"
+	"go.yozora.eu/pebbles/internal/store"
+)
+
+func runLinkCommit(args ...string) (string, error) {
+	var out bytes.Buffer
+	app := &cli.App{
+		Name:     "peb",
+		Writer:   &out,
+		Commands: []*cli.Command{LinkCommitCommand()},
+	}
+	err := app.Run(append([]string{"peb", "link-commit"}, args...))
+	return strings.TrimSpace(out.String()), err
+}
+
+func TestLinkCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	pebblesDir, s, cleanup := setupTestStore(t)
+	defer cleanup()
+	t.Chdir(pebblesDir)
+
+	p := peb.New("peb-ab12", "Crash", peb.TypeBug, peb.StatusFixed, "")
+	if err := s.Save(p); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = filepath.Dir(pebblesDir)
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("git %v: %v", args, err)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git("init", "-q")
+	git("commit", "-q", "--allow-empty", "-m", "Fix crash\n\nFixes PEB-AB12, see also peb-zzzz")
+	fix := git("rev-parse", "HEAD")
+	git("commit", "-q", "--allow-empty", "-m", "Unrelated")
+	unrelated := git("rev-parse", "HEAD")
+
+	out, err := runLinkCommit("--scan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Linked " + fix[:12] + " to peb-ab12\nLinked 1 commits."; out != want {
+		t.Errorf("unexpected output %q, want %q", out, want)
+	}
+
+	// Scanning again finds nothing new.
+	if out, err := runLinkCommit("--scan"); err != nil || out != "Linked 0 commits." {
+		t.Errorf("expected no new links, got %q, %v", out, err)
+	}
+
+	// Like other commands, an abbreviated ID in any case is resolved.
+	if out, err := runLinkCommit("AB12", "HEAD", fix[:7]); err != nil || out != "Linked "+unrelated[:12]+" to peb-ab12" {
+		t.Fatalf("unexpected output %q, %v", out, err)
+	}
+	if _, err := runLinkCommit("peb-ab12", "no-such-rev"); err == nil {
+		t.Error("expected an error for an unknown revision")
+	}
+	if _, err := runLinkCommit("peb-zzzz", "HEAD"); err == nil {
+		t.Error("expected an error for an unknown peb")
+	}
+
+	s = store.New(pebblesDir, "peb")
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := s.Get("peb-ab12")
+	if !slices.Equal(got.Commits, []string{fix, unrelated}) {
+		t.Errorf("unexpected commits %v", got.Commits)
+	}
+	if got.Changed != p.Changed {
+		t.Errorf("expected changed timestamp to be kept, got %s", got.Changed)
+	}
+
+	for _, rev := range []string{fix[:7], strings.ToUpper(unrelated[:10])} {
+		out, err := runInProcess("", "query", "--fields", "id", "commit:"+rev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != `{"id":"peb-ab12"}` {
+			t.Errorf("commit:%s: unexpected output %q", rev, out)
+		}
+	}
+	if out, _ := runInProcess("", "query", "commit:0000000"); out != "" {
+		t.Errorf("expected no match, got %q", out)
+	}
+	if _, err := os.Stat(filepath.Join(pebblesDir, "peb-ab12--crash.md")); err != nil {
+		t.Error(err)
+	}
+}
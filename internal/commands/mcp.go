@@ -0,0 +1,366 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"go.yozora.eu/pebbles/internal/config"
+	"go.yozora.eu/pebbles/internal/mcp"
+	"go.yozora.eu/pebbles/internal/store"
+)
+
+const pebResourcePrefix = "peb://"
+
+func MCPCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "mcp",
+		Usage: "Run an MCP server over stdio",
+		Description: `Serve the Model Context Protocol (JSON-RPC 2.0) over stdin/stdout.
+
+The server exposes the tools peb_new, peb_read, peb_update, peb_query,
+peb_delete and peb_prime, the agent instructions as the prompt "prime", and
+every peb as a resource with the URI peb://<id>.
+
+Example MCP client configuration:
+  {"command": "peb", "args": ["mcp"]}`,
+		Action: func(c *cli.Context) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			if err := config.MaybeUpdatePlugin(cfg); err != nil {
+				return fmt.Errorf("failed to update plugin: %w", err)
+			}
+
+			return newMCPServer().Serve(c.App.Reader, c.App.Writer)
+		},
+	}
+}
+
+func runInProcess(stdin string, args ...string) (string, error) {
+	var out bytes.Buffer
+	app := &cli.App{
+		Name:      "peb",
+		Reader:    strings.NewReader(stdin),
+		Writer:    &out,
+		ErrWriter: &out,
+		Commands: []*cli.Command{
+			NewCommand(),
+			ReadCommand(),
+			UpdateCommand(),
+			DeleteCommand(),
+			BatchCommand(),
+			QueryCommand(),
+			PrimeCommand(),
+		},
+	}
+	err := app.Run(append([]string{"peb"}, args...))
+	return strings.TrimSpace(out.String()), err
+}
+
+func buildVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
+
+func newMCPServer() *mcp.Server {
+	prime, _ := runInProcess("", "prime", "--mcp")
+
+	return &mcp.Server{
+		Name:         "pebbles",
+		Version:      buildVersion(),
+		Instructions: prime,
+		Tools:        mcpTools(),
+		Prompts: []mcp.Prompt{
+			{
+				Name:        "prime",
+				Description: "Instructions for using pebbles to track work",
+				Handler: func() (string, error) {
+					return runInProcess("", "prime", "--mcp")
+				},
+			},
+		},
+		ListResources: listPebResources,
+		ReadResource:  readPebResource,
+	}
+}
+
+// mcpNewInput uses blocked_by like all tool arguments.
+type mcpNewInput struct {
+	Title     string        `json:"title"`
+	Content   string        `json:"content"`
+	Type      string        `json:"type"`
+	BlockedBy []string      `json:"blocked_by"`
+	Parent    string        `json:"parent"`
+	Children  []mcpNewInput `json:"children"`
+}
+
+func (in mcpNewInput) newInput() NewInput {
+	input := NewInput{Title: in.Title, Content: in.Content, Type: in.Type, BlockedBy: in.BlockedBy, Parent: in.Parent}
+	for _, child := range in.Children {
+		input.Children = append(input.Children, child.newInput())
+	}
+	return input
+}
+
+func mcpTools() []mcp.Tool {
+	return []mcp.Tool{
+		{
+			Name:        "peb_new",
+			Description: "Create a new peb (task/bug/feature/epic). Required: title, content. Optional: type (bug|feature|epic|task, default: bug), blocked_by (array of peb IDs), parent (ID of an existing peb that becomes blocked by this one), children (array of pebs created atomically with this one, which is then blocked by them; returns the ID tree)",
+			InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "title": {"type": "string", "description": "Short description of the peb"},
+    "content": {"type": "string", "description": "Markdown description of the peb"},
+    "type": {"type": "string", "enum": ["bug", "feature", "epic", "task"], "description": "Type (default: bug, or epic with children)"},
+    "blocked_by": {"type": "array", "items": {"type": "string"}, "description": "Array of peb IDs that block this peb"},
+    "parent": {"type": "string", "description": "ID of an existing peb to add this peb to as a subtask (type defaults to task)"},
+    "children": {
+      "type": "array",
+      "description": "Subtasks to create together with this peb (type defaults to task); may have children themselves",
+      "items": {
+        "type": "object",
+        "properties": {
+          "title": {"type": "string"},
+          "content": {"type": "string"},
+          "type": {"type": "string", "enum": ["bug", "feature", "epic", "task"]},
+          "blocked_by": {"type": "array", "items": {"type": "string"}},
+          "children": {"type": "array", "items": {"type": "object"}}
+        },
+        "required": ["title", "content"]
+      }
+    }
+  },
+  "required": ["title", "content"]
+}`),
+			Handler: func(args json.RawMessage) (string, error) {
+				var p mcpNewInput
+				if err := json.Unmarshal(args, &p); err != nil {
+					return "", err
+				}
+				input, err := json.Marshal(p.newInput())
+				if err != nil {
+					return "", err
+				}
+				return runInProcess(string(input), "new")
+			},
+		},
+		{
+			Name:        "peb_read",
+			Description: "Read one or more pebs by ID. Returns full pebs data as JSON.",
+			InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "id": {"type": "array", "items": {"type": "string"}, "description": "Array of peb IDs to read"}
+  },
+  "required": ["id"]
+}`),
+			Handler: func(args json.RawMessage) (string, error) {
+				var p struct {
+					ID []string `json:"id"`
+				}
+				if err := json.Unmarshal(args, &p); err != nil {
+					return "", err
+				}
+				return runInProcess("", append([]string{"read"}, p.ID...)...)
+			},
+		},
+		{
+			Name:        "peb_update",
+			Description: "Update a peb. Optional fields: status (new|in-progress|fixed|wont-fix), title, content, type (bug|feature|epic|task), blocked_by (array of peb IDs)",
+			InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "id": {"type": "string", "description": "The peb ID to update"},
+    "status": {"type": "string", "enum": ["new", "in-progress", "fixed", "wont-fix"]},
+    "title": {"type": "string", "description": "Short description of the peb"},
+    "content": {"type": "string", "description": "Markdown description of the peb"},
+    "type": {"type": "string", "enum": ["bug", "feature", "epic", "task"]},
+    "blocked_by": {"type": "array", "items": {"type": "string"}, "description": "Array of peb IDs that block this peb"}
+  },
+  "required": ["id"]
+}`),
+			Handler: func(args json.RawMessage) (string, error) {
+				var p struct {
+					ID        string    `json:"id"`
+					Title     *string   `json:"title"`
+					Content   *string   `json:"content"`
+					Type      *string   `json:"type"`
+					Status    *string   `json:"status"`
+					BlockedBy *[]string `json:"blocked_by"`
+				}
+				if err := json.Unmarshal(args, &p); err != nil {
+					return "", err
+				}
+				input, err := json.Marshal(UpdateInput{Title: p.Title, Content: p.Content, Type: p.Type, Status: p.Status, BlockedBy: p.BlockedBy})
+				if err != nil {
+					return "", err
+				}
+				return runInProcess(string(input), "update", p.ID)
+			},
+		},
+		{
+			Name:        "peb_query",
+			Description: "Query pebs with optional filters (id:ID, id:(ID1|ID2), status:new|in-progress|fixed|wont-fix|open|closed, type:bug|feature|epic|task, blocked-by:ID, commit:REV, @saved-query). Returns one JSON object per peb.",
+			InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "preset": {"type": "string", "description": "Name of a saved query to apply before the filters"},
+    "filters": {"type": "array", "items": {"type": "string"}, "description": "Array of filters (e.g., ['status:new', 'type:bug'])"},
+    "fields": {"type": "array", "items": {"type": "string"}, "description": "Array of fields to output (e.g., ['id', 'title'])"}
+  }
+}`),
+			Handler: func(args json.RawMessage) (string, error) {
+				var p struct {
+					Preset  string   `json:"preset"`
+					Filters []string `json:"filters"`
+					Fields  []string `json:"fields"`
+				}
+				if err := json.Unmarshal(args, &p); err != nil {
+					return "", err
+				}
+				cmdArgs := []string{"query"}
+				if len(p.Fields) > 0 {
+					cmdArgs = append(cmdArgs, "--fields", strings.Join(p.Fields, ","))
+				}
+				if p.Preset != "" {
+					cmdArgs = append(cmdArgs, "@"+p.Preset)
+				}
+				return runInProcess("", append(cmdArgs, p.Filters...)...)
+			},
+		},
+		{
+			Name:        "peb_delete",
+			Description: "Delete pebs by ID.",
+			InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "id": {"type": "array", "items": {"type": "string"}, "description": "Array of peb IDs to delete"}
+  },
+  "required": ["id"]
+}`),
+			Handler: func(args json.RawMessage) (string, error) {
+				var p struct {
+					ID []string `json:"id"`
+				}
+				if err := json.Unmarshal(args, &p); err != nil {
+					return "", err
+				}
+				return runInProcess("", append([]string{"delete"}, p.ID...)...)
+			},
+		},
+		{
+			Name:        "peb_batch",
+			Description: "Apply many operations all-or-nothing, e.g. to create an epic with its tasks. Operations: new (ref, parent, title, content, type, blocked-by; the parent becomes blocked by the new peb), update (id, status, title, content, type, blocked-by), delete (id), link (id, blocked-by; adds blockers). Later operations refer to pebs created with a ref as \"$<ref>\". Returns the peb ID of every operation.",
+			InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "operations": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "op": {"type": "string", "enum": ["new", "update", "delete", "link"]},
+          "ref": {"type": "string", "description": "Name for a new peb, used as \"$<ref>\" in later operations"},
+          "parent": {"type": "string", "description": "Peb ID or \"$<ref>\" of the parent of a new peb"},
+          "id": {"type": "string", "description": "Peb ID or \"$<ref>\" for update, delete and link"},
+          "title": {"type": "string"},
+          "content": {"type": "string"},
+          "type": {"type": "string", "enum": ["bug", "feature", "epic", "task"]},
+          "status": {"type": "string", "enum": ["new", "in-progress", "fixed", "wont-fix"]},
+          "blocked-by": {"type": "array", "items": {"type": "string"}}
+        },
+        "required": ["op"]
+      }
+    }
+  },
+  "required": ["operations"]
+}`),
+			Handler: func(args json.RawMessage) (string, error) {
+				var p struct {
+					Operations json.RawMessage `json:"operations"`
+				}
+				if err := json.Unmarshal(args, &p); err != nil {
+					return "", err
+				}
+				return runInProcess(string(p.Operations), "batch")
+			},
+		},
+		{
+			Name:        "peb_prime",
+			Description: "Show the instructions for using pebbles.",
+			InputSchema: json.RawMessage(`{"type": "object", "properties": {}}`),
+			Handler: func(args json.RawMessage) (string, error) {
+				return runInProcess("", "prime", "--mcp")
+			},
+		},
+	}
+}
+
+func loadStoreForMCP() (*store.Store, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	s := store.New(cfg.PebblesDir(), cfg.Prefix)
+	if err := s.Load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func listPebResources() ([]mcp.Resource, error) {
+	s, err := loadStoreForMCP()
+	if err != nil {
+		return nil, err
+	}
+
+	pebs := s.All()
+	sort.Slice(pebs, func(i, j int) bool {
+		return pebs[i].ID < pebs[j].ID
+	})
+
+	resources := make([]mcp.Resource, 0, len(pebs))
+	for _, p := range pebs {
+		resources = append(resources, mcp.Resource{
+			URI:         pebResourcePrefix + p.ID,
+			Name:        p.ID,
+			Title:       p.Title,
+			Description: fmt.Sprintf("%s, %s", p.Type, p.Status),
+			MimeType:    "application/json",
+		})
+	}
+	return resources, nil
+}
+
+func readPebResource(uri string) (*mcp.ResourceContents, error) {
+	id, ok := strings.CutPrefix(uri, pebResourcePrefix)
+	if !ok {
+		return nil, mcp.ErrUnknownResource
+	}
+
+	s, err := loadStoreForMCP()
+	if err != nil {
+		return nil, err
+	}
+
+	p, ok := s.Get(id)
+	if !ok {
+		return nil, mcp.ErrUnknownResource
+	}
+
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode peb: %w", err)
+	}
+	return &mcp.ResourceContents{URI: uri, MimeType: "application/json", Text: string(data)}, nil
+}
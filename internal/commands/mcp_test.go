@@ -0,0 +1,191 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+	"go.yozora.eu/pebbles/internal/peb"
+)
+
+// mcpClient talks to a "peb mcp" server running in-process.
+type mcpClient struct {
+	t      *testing.T
+	in     *io.PipeWriter
+	out    *bufio.Scanner
+	nextID int
+	done   chan error
+}
+
+func startMCP(t *testing.T) *mcpClient {
+	t.Helper()
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	app := &cli.App{
+		Reader:   inR,
+		Writer:   outW,
+		Commands: []*cli.Command{MCPCommand()},
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		err := app.Run([]string{"peb", "mcp"})
+		outW.Close()
+		done <- err
+	}()
+
+	client := &mcpClient{t: t, in: inW, out: bufio.NewScanner(outR), done: done}
+	client.out.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	t.Cleanup(client.close)
+	return client
+}
+
+func (c *mcpClient) close() {
+	c.in.Close()
+	if err := <-c.done; err != nil {
+		c.t.Errorf("mcp server failed: %v", err)
+	}
+}
+
+func (c *mcpClient) call(method string, params any) json.RawMessage {
+	c.t.Helper()
+	c.nextID++
+
+	msg, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      c.nextID,
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if _, err := fmt.Fprintf(c.in, "%s\n", msg); err != nil {
+		c.t.Fatal(err)
+	}
+
+	if !c.out.Scan() {
+		c.t.Fatalf("no response to %s", method)
+	}
+	var resp struct {
+		ID     int             `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(c.out.Bytes(), &resp); err != nil {
+		c.t.Fatalf("invalid response %s: %v", c.out.Bytes(), err)
+	}
+	if resp.ID != c.nextID {
+		c.t.Fatalf("expected response id %d, got %d", c.nextID, resp.ID)
+	}
+	if resp.Error != nil {
+		c.t.Fatalf("%s failed: %s", method, resp.Error.Message)
+	}
+	return resp.Result
+}
+
+func (c *mcpClient) callTool(name string, args any) (string, bool) {
+	c.t.Helper()
+	var result struct {
+		Content []struct {
+			Text string `json:"text"`
+		} `json:"content"`
+		IsError bool `json:"isError"`
+	}
+	raw := c.call("tools/call", map[string]any{"name": name, "arguments": args})
+	if err := json.Unmarshal(raw, &result); err != nil {
+		c.t.Fatal(err)
+	}
+	if len(result.Content) != 1 {
+		c.t.Fatalf("expected one content item, got %s", raw)
+	}
+	return result.Content[0].Text, result.IsError
+}
+
+func TestMCPCommand(t *testing.T) {
+	pebblesDir, _, cleanup := setupTestStore(t)
+	defer cleanup()
+	t.Chdir(pebblesDir)
+
+	client := startMCP(t)
+
+	init := client.call("initialize", map[string]any{"protocolVersion": "2025-06-18"})
+	if !strings.Contains(string(init), `"name":"pebbles"`) {
+		t.Errorf("unexpected initialize result %s", init)
+	}
+
+	tools := client.call("tools/list", nil)
+	for _, name := range []string{"peb_new", "peb_read", "peb_update", "peb_query", "peb_delete", "peb_batch", "peb_prime"} {
+		if !strings.Contains(string(tools), `"name":"`+name+`"`) {
+			t.Errorf("expected tool %s in %s", name, tools)
+		}
+	}
+
+	text, isError := client.callTool("peb_new", map[string]any{"title": "MCP peb", "content": "Created via MCP", "type": "task"})
+	if isError || !strings.HasPrefix(text, "Created new peb peb-") {
+		t.Fatalf("unexpected peb_new result %q", text)
+	}
+	id := strings.TrimPrefix(text, "Created new peb ")
+
+	text, isError = client.callTool("peb_update", map[string]any{"id": id, "status": "in-progress"})
+	if isError || !strings.Contains(text, "Updated status of "+id+" to in-progress.") {
+		t.Errorf("unexpected peb_update result %q", text)
+	}
+
+	text, isError = client.callTool("peb_read", map[string]any{"id": []string{id}})
+	if isError {
+		t.Fatalf("peb_read failed: %s", text)
+	}
+	var p peb.Peb
+	if err := json.Unmarshal([]byte(text), &p); err != nil {
+		t.Fatalf("failed to parse peb_read output: %v", err)
+	}
+	if p.Title != "MCP peb" || p.Status != peb.StatusInProgress {
+		t.Errorf("unexpected peb %+v", p)
+	}
+
+	text, isError = client.callTool("peb_query", map[string]any{"filters": []string{"status:open"}, "fields": []string{"title"}})
+	if isError || text != `{"id":"`+id+`","title":"MCP peb"}` {
+		t.Errorf("unexpected peb_query result %q", text)
+	}
+
+	resources := client.call("resources/list", nil)
+	if !strings.Contains(string(resources), `"uri":"peb://`+id+`"`) {
+		t.Errorf("expected peb resource in %s", resources)
+	}
+	contents := client.call("resources/read", map[string]any{"uri": "peb://" + id})
+	if !strings.Contains(string(contents), "Created via MCP") {
+		t.Errorf("unexpected resource contents %s", contents)
+	}
+
+	prompt := client.call("prompts/get", map[string]any{"name": "prime"})
+	if !strings.Contains(string(prompt), "Pebbles") {
+		t.Errorf("unexpected prime prompt %s", prompt)
+	}
+
+	text, isError = client.callTool("peb_delete", map[string]any{"id": []string{id}})
+	if isError || text != "Deleted peb "+id+"." {
+		t.Errorf("unexpected peb_delete result %q", text)
+	}
+
+	text, isError = client.callTool("peb_read", map[string]any{"id": []string{id}})
+	if !isError || !strings.Contains(text, "not found") {
+		t.Errorf("expected peb_read to fail for deleted peb, got %q", text)
+	}
+
+	text, isError = client.callTool("peb_batch", map[string]any{"operations": []map[string]any{
+		{"op": "new", "ref": "task", "title": "Task", "content": "Task", "type": "task"},
+		{"op": "new", "title": "Epic", "content": "Epic", "type": "epic", "blocked-by": []string{"$task"}},
+	}})
+	if isError || !strings.Contains(text, `"ref":"task"`) {
+		t.Errorf("unexpected peb_batch result %q", text)
+	}
+}
@@ -0,0 +1,158 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"go.yozora.eu/pebbles/internal/merge"
+	"go.yozora.eu/pebbles/internal/peb"
+)
+
+const mergeDriverAttribute = ".pebbles/*.md merge=peb"
+
+const mergeDriverCommand = "peb merge-driver %O %A %B"
+
+func MergeDriverCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "merge-driver",
+		Usage:     "Merge concurrent changes to a peb file (run by git)",
+		ArgsUsage: "<base> <ours> <theirs>",
+		Description: `Merge two versions of a peb file field by field and write the result to
+<ours>. git runs this command for files in .pebbles/ once "peb init" has
+set it up as the merge driver "peb".
+
+A field changed on one side only takes the value of that side. Scalar fields
+changed on both sides (title, type, status, source) take the value of the
+side with the later changed timestamp, and so do unknown frontmatter keys.
+blocked-by, commits and a labels list combine the additions and removals of
+both sides. The content is merged line by line; conflicting lines are marked
+like in any git merge and make the command fail, but the frontmatter stays
+valid, so the peb can still be read.
+
+Files that are not valid pebs are merged as plain text, and so are files
+added on both sides with different created timestamps: those are different
+pebs that got the same ID and title, e.g. with sequential IDs, so the conflict
+must be resolved by hand. Pebs with the same ID but different titles end up
+in separate files instead; "peb dedupe" gives them unique IDs.`,
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 3 {
+				return fmt.Errorf("base, ours and theirs files are required")
+			}
+			basePath, oursPath, theirsPath := c.Args().Get(0), c.Args().Get(1), c.Args().Get(2)
+
+			conflict, err := mergePebFiles(basePath, oursPath, theirsPath, merge.GitMergeFile)
+			if err != nil {
+				return err
+			}
+			if conflict {
+				return fmt.Errorf("conflicting changes to the content of %s", oursPath)
+			}
+			return nil
+		},
+	}
+}
+
+// mergePebFiles falls back to mergeText for the whole file if one of them is
+// not a valid peb.
+func mergePebFiles(basePath, oursPath, theirsPath string, mergeText merge.TextMerger) (bool, error) {
+	var files [3][]byte
+	for i, path := range []string{basePath, oursPath, theirsPath} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return false, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		files[i] = data
+	}
+
+	merged, conflict, err := mergePebs(basePath, oursPath, theirsPath, files[0], mergeText)
+	if err != nil {
+		text, textConflict, textErr := mergeText(string(files[0]), string(files[1]), string(files[2]))
+		if textErr != nil {
+			return false, textErr
+		}
+		merged, conflict = []byte(text), textConflict
+	}
+
+	if err := os.WriteFile(oursPath, merged, 0644); err != nil {
+		return false, fmt.Errorf("failed to write merge result: %w", err)
+	}
+	return conflict, nil
+}
+
+// base is empty if the peb was added on both sides.
+func mergePebs(basePath, oursPath, theirsPath string, base []byte, mergeText merge.TextMerger) ([]byte, bool, error) {
+	var basePeb *peb.Peb
+	if len(base) > 0 {
+		p, err := peb.ReadFile(basePath)
+		if err != nil {
+			return nil, false, err
+		}
+		basePeb = p
+	}
+	ours, err := peb.ReadFile(oursPath)
+	if err != nil {
+		return nil, false, err
+	}
+	theirs, err := peb.ReadFile(theirsPath)
+	if err != nil {
+		return nil, false, err
+	}
+
+	merged, conflict, err := merge.Merge(basePeb, ours, theirs, mergeText)
+	if err != nil {
+		return nil, false, err
+	}
+	data, err := peb.Marshal(merged)
+	if err != nil {
+		return nil, false, err
+	}
+	return data, conflict, nil
+}
+
+// installMergeDriver reports whether anything changed.
+func installMergeDriver(projectDir string) (bool, error) {
+	git := func(args ...string) (string, error) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = projectDir
+		out, err := cmd.Output()
+		return strings.TrimSpace(string(out)), err
+	}
+	if _, err := git("rev-parse", "--git-dir"); err != nil {
+		return false, nil
+	}
+
+	changed := false
+	if driver, _ := git("config", "--get", "merge.peb.driver"); driver != mergeDriverCommand {
+		if _, err := git("config", "merge.peb.name", "pebbles field-wise merge"); err != nil {
+			return false, fmt.Errorf("failed to configure merge driver: %w", err)
+		}
+		if _, err := git("config", "merge.peb.driver", mergeDriverCommand); err != nil {
+			return false, fmt.Errorf("failed to configure merge driver: %w", err)
+		}
+		changed = true
+	}
+
+	path := filepath.Join(projectDir, ".gitattributes")
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("failed to read .gitattributes: %w", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == mergeDriverAttribute {
+			return changed, nil
+		}
+	}
+	if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(data, '\n')
+	}
+	data = append(data, mergeDriverAttribute+"\n"...)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return false, fmt.Errorf("failed to write .gitattributes: %w", err)
+	}
+	return true, nil
+}
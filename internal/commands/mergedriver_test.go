@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.yozora.eu/pebbles/internal/merge"
+	"go.yozora.eu/pebbles/internal/peb"
+)
+
+func writePebFile(t *testing.T, path string, p *peb.Peb) {
+	t.Helper()
+	data, err := peb.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMergePebFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	basePath, oursPath, theirsPath := filepath.Join(dir, "base"), filepath.Join(dir, "ours"), filepath.Join(dir, "theirs")
+
+	base := peb.New("peb-aaaa", "Title", peb.TypeBug, peb.StatusNew, "one\ntwo\nthree\n")
+	ours := *base
+	ours.Status = peb.StatusFixed
+	ours.Content = "ONE\ntwo\nthree\n"
+	theirs := *base
+	theirs.BlockedBy = []string{"peb-bbbb"}
+	theirs.Content = "one\ntwo\nTHREE\n"
+
+	writePebFile(t, basePath, base)
+	writePebFile(t, oursPath, &ours)
+	writePebFile(t, theirsPath, &theirs)
+
+	conflict, err := mergePebFiles(basePath, oursPath, theirsPath, merge.GitMergeFile)
+	if err != nil || conflict {
+		t.Fatalf("mergePebFiles() = %v, %v", conflict, err)
+	}
+	merged, err := peb.ReadFile(oursPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.Status != peb.StatusFixed || !slices.Equal(merged.BlockedBy, []string{"peb-bbbb"}) || merged.Content != "ONE\ntwo\nTHREE\n" {
+		t.Errorf("unexpected merge result %+v", merged)
+	}
+
+	// A content conflict keeps the frontmatter readable.
+	ours.Content = "ours\n"
+	theirs.Content = "theirs\n"
+	writePebFile(t, oursPath, &ours)
+	if err := os.WriteFile(basePath, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	writePebFile(t, theirsPath, &theirs)
+	conflict, err = mergePebFiles(basePath, oursPath, theirsPath, merge.GitMergeFile)
+	if err != nil || !conflict {
+		t.Fatalf("expected a conflict, got %v, %v", conflict, err)
+	}
+	merged, err = peb.ReadFile(oursPath)
+	if err != nil {
+		t.Fatalf("conflicted peb is not readable: %v", err)
+	}
+	if !strings.Contains(merged.Content, "<<<<<<< ours") {
+		t.Errorf("expected conflict markers, got %q", merged.Content)
+	}
+
+	// Different pebs added with the same file name are not combined.
+	theirs.Created = "2026-01-02T10:00:00+00:00"
+	theirs.Content = "ours\n"
+	writePebFile(t, oursPath, &ours)
+	writePebFile(t, theirsPath, &theirs)
+	if conflict, err := mergePebFiles(basePath, oursPath, theirsPath, merge.GitMergeFile); err != nil || !conflict {
+		t.Fatalf("expected a conflict for different pebs, got %v, %v", conflict, err)
+	}
+
+	// Invalid pebs are merged as text.
+	for path, text := range map[string]string{basePath: "a\nx\nb\n", oursPath: "A\nx\nb\n", theirsPath: "a\nx\nB\n"} {
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if conflict, err := mergePebFiles(basePath, oursPath, theirsPath, merge.GitMergeFile); err != nil || conflict {
+		t.Fatalf("mergePebFiles() = %v, %v", conflict, err)
+	}
+	if data, _ := os.ReadFile(oursPath); string(data) != "A\nx\nB\n" {
+		t.Errorf("unexpected text merge %q", data)
+	}
+}
+
+func TestInstallMergeDriver(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+
+	if installed, err := installMergeDriver(dir); err != nil || installed {
+		t.Fatalf("expected nothing to be installed outside of git, got %v, %v", installed, err)
+	}
+
+	cmd := exec.Command("git", "init", "-q")
+	cmd.Dir = dir
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	attributes := filepath.Join(dir, ".gitattributes")
+	if err := os.WriteFile(attributes, []byte("*.png binary"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if installed, err := installMergeDriver(dir); err != nil || !installed {
+		t.Fatalf("expected the merge driver to be installed, got %v, %v", installed, err)
+	}
+	if installed, err := installMergeDriver(dir); err != nil || installed {
+		t.Fatalf("expected a second install to change nothing, got %v, %v", installed, err)
+	}
+
+	data, _ := os.ReadFile(attributes)
+	if string(data) != "*.png binary\n"+mergeDriverAttribute+"\n" {
+		t.Errorf("unexpected .gitattributes %q", data)
+	}
+	cmd = exec.Command("git", "check-attr", "merge", ".pebbles/peb-aaaa--title.md")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil || !strings.Contains(string(out), "merge: peb") {
+		t.Errorf("unexpected attributes %q, %v", out, err)
+	}
+}
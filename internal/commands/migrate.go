@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+	"go.yozora.eu/pebbles/internal/config"
+	"go.yozora.eu/pebbles/internal/migrate"
+)
+
+func MigrateCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "migrate",
+		Usage: "Upgrade .pebbles/ to the current format version",
+		Description: `Upgrade a .pebbles/ directory written by an older version of pebbles to the
+format version of this one, by running the pending migration steps in order.
+The format version is recorded as "version" in config.toml.
+
+Migrations rewrite data, so they never run on their own. Use --dry-run to see
+the steps and a diff of the files they would change; "peb init" tells you
+when a migration is pending. A peb older than the directory refuses to work
+on it.
+
+Examples:
+  peb migrate --dry-run
+  peb migrate`,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "Show the pending steps and the diff without writing anything",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			if err := config.MaybeUpdatePlugin(cfg); err != nil {
+				return fmt.Errorf("failed to update plugin: %w", err)
+			}
+
+			m, err := migrate.Plan(cfg)
+			if err != nil {
+				return err
+			}
+			if len(m.Steps) == 0 {
+				fmt.Fprintf(c.App.Writer, "Already at format version %d\n", m.From)
+				return nil
+			}
+
+			if c.Bool("dry-run") {
+				fmt.Fprintf(c.App.Writer, "Would migrate from format version %d to %d:\n", m.From, m.To)
+				for _, step := range m.Steps {
+					fmt.Fprintf(c.App.Writer, "  %d: %s\n", step.Version, step.Description)
+				}
+				fmt.Fprintln(c.App.Writer)
+				return m.WriteDiff(c.App.Writer)
+			}
+
+			if err := m.Apply(); err != nil {
+				return err
+			}
+			fmt.Fprintf(c.App.Writer, "Migrated from format version %d to %d:\n", m.From, m.To)
+			for _, step := range m.Steps {
+				fmt.Fprintf(c.App.Writer, "  %d: %s\n", step.Version, step.Description)
+			}
+			return nil
+		},
+	}
+}
@@ -2,8 +2,9 @@ package commands
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"strings"
 
 	"github.com/urfave/cli/v2"
 	"go.yozora.eu/pebbles/internal/config"
@@ -12,17 +13,26 @@ import (
 )
 
 type NewInput struct {
-	Title     string   `json:"title"`
-	Content   string   `json:"content"`
-	Type      string   `json:"type"`
-	BlockedBy []string `json:"blocked-by"`
+	Title     string     `json:"title"`
+	Content   string     `json:"content"`
+	Type      string     `json:"type"`
+	BlockedBy []string   `json:"blocked-by"`
+	Parent    string     `json:"parent,omitempty"`
+	Children  []NewInput `json:"children,omitempty"`
+}
+
+type NewTree struct {
+	ID       string    `json:"id"`
+	Title    string    `json:"title"`
+	Children []NewTree `json:"children,omitempty"`
 }
 
 func NewCommand() *cli.Command {
 	return &cli.Command{
 		Name:  "new",
 		Usage: "Create a new peb",
-		Description: `Create a new peb from JSON input via stdin.
+		Description: `Create a new peb from JSON input via stdin, or with --edit in $VISUAL or
+$EDITOR (see "peb edit").
 
 Required fields:
   title     Short description of the peb
@@ -31,6 +41,17 @@ Required fields:
 Optional fields:
   type      One of: bug, feature, epic, task (default: bug)
   blocked-by Array of peb IDs this peb depends on
+  parent    ID of an existing peb to add this peb to as a child
+  children  Array of pebs (same fields) to create together with this peb
+
+With children, the peb and all its children are created atomically and the
+peb is blocked by its children. The type defaults to epic for the peb and
+to task for the children. The IDs of all created pebs are printed as a JSON
+tree of {"id", "title", "children"} objects.
+
+With a parent, the parent becomes blocked by the new peb, whose type
+defaults to task. With id_scheme = "hierarchical" in config.toml, children
+get the ID of their parent followed by a number, e.g. peb-ab12.1.
 
 Examples:
   peb new <<'EOF'
@@ -43,7 +64,26 @@ Examples:
   
   peb new <<'EOF'
   {"title":"Dependent task","content":"...","blocked-by":["peb-xxxx"]}
+  EOF
+
+  peb new --edit
+
+  peb new <<'EOF'
+  {"title":"Subtask","content":"...","parent":"peb-xxxx"}
+  EOF
+
+  peb new <<'EOF'
+  {"title":"Epic","content":"...","children":[
+    {"title":"First task","content":"..."},
+    {"title":"Second task","content":"..."}
+  ]}
   EOF`,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "edit",
+				Usage: "Write the peb in $VISUAL or $EDITOR instead of reading JSON from stdin",
+			},
+		},
 		Action: func(c *cli.Context) error {
 			cfg, err := config.Load()
 			if err != nil {
@@ -54,54 +94,150 @@ Examples:
 				return fmt.Errorf("failed to update plugin: %w", err)
 			}
 
-			s := store.New(cfg.PebblesDir(), cfg.Prefix)
-			if err := s.Load(); err != nil {
+			s, err := openStore(cfg)
+			if err != nil {
 				return err
 			}
 
 			var input NewInput
-			decoder := json.NewDecoder(os.Stdin)
-			if err := decoder.Decode(&input); err != nil {
+			if c.Bool("edit") {
+				if input, err = editNewInput(c, s); err != nil {
+					return err
+				}
+			} else if err := json.NewDecoder(c.App.Reader).Decode(&input); err != nil {
 				return fmt.Errorf("failed to parse JSON input: %w", err)
 			}
 
-			if input.Title == "" {
-				return fmt.Errorf("title is required")
-			}
-			if input.Content == "" {
-				return fmt.Errorf("content is required")
-			}
-
-			if len(input.BlockedBy) > 0 {
-				if err := peb.ValidateBlockedBy(s, nil, input.BlockedBy); err != nil {
-					if peb.IsInvalidReference(err) {
-						return fmt.Errorf("Referenced peb(s) not found: %s", extractInvalidID(err))
-					}
+			if len(input.Children) > 0 {
+				_, tree, err := createPebTree(s, cfg, input)
+				if err != nil {
 					return err
 				}
+				output, err := json.Marshal(tree)
+				if err != nil {
+					return fmt.Errorf("failed to marshal JSON: %w", err)
+				}
+				fmt.Fprintln(c.App.Writer, string(output))
+				return nil
 			}
 
-			pebType := peb.TypeBug
-			if input.Type != "" {
-				pebType = peb.Type(input.Type)
+			p, err := createPeb(s, cfg, input)
+			if err != nil {
+				return err
 			}
 
-			id, err := s.GenerateUniqueID(cfg.Prefix, cfg.IDLength)
-			if err != nil {
-				return fmt.Errorf("failed to generate ID: %w", err)
+			fmt.Fprintf(c.App.Writer, "Created new peb %s\n", p.ID)
+			return nil
+		},
+	}
+}
+
+func createPeb(s *store.Store, cfg *config.Config, input NewInput) (*peb.Peb, error) {
+	if len(input.Children) > 0 || input.Parent != "" {
+		p, _, err := createPebTree(s, cfg, input)
+		return p, err
+	}
+
+	if input.Title == "" {
+		return nil, fmt.Errorf("title is required")
+	}
+	if input.Content == "" {
+		return nil, fmt.Errorf("content is required")
+	}
+
+	if len(input.BlockedBy) > 0 {
+		if err := peb.ValidateBlockedBy(s, nil, input.BlockedBy); err != nil {
+			if peb.IsInvalidReference(err) {
+				return nil, fmt.Errorf("Referenced peb(s) not found: %s", extractInvalidID(err))
 			}
+			return nil, err
+		}
+	}
+
+	pebType := peb.TypeBug
+	if input.Type != "" {
+		pebType = peb.Type(input.Type)
+	}
+
+	id, err := cfg.IDScheme.NextID(cfg.Prefix, cfg.IDLength, "", s.IDs(), cfg.IDSpread())
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ID: %w", err)
+	}
 
-			p := peb.New(id, input.Title, pebType, peb.StatusNew, input.Content)
-			p.BlockedBy = input.BlockedBy
+	p := peb.New(id, input.Title, pebType, peb.StatusNew, input.Content)
+	p.BlockedBy = input.BlockedBy
 
-			if err := s.Save(p); err != nil {
-				return fmt.Errorf("failed to save peb: %w", err)
+	if err := s.Save(p); err != nil {
+		return nil, fmt.Errorf("failed to save peb: %w", err)
+	}
+
+	return p, nil
+}
+
+// createPebTree creates parents before their children, which may derive
+// their IDs from them.
+func createPebTree(s *store.Store, cfg *config.Config, input NewInput) (*peb.Peb, *NewTree, error) {
+	var ops []BatchOp
+	var paths []string
+
+	var add func(in NewInput, path, parent, defaultType string)
+	add = func(in NewInput, path, parent, defaultType string) {
+		pebType := in.Type
+		if pebType == "" {
+			pebType = defaultType
+		}
+		ops = append(ops, BatchOp{
+			Op:        "new",
+			Ref:       path,
+			Parent:    parent,
+			Title:     &in.Title,
+			Content:   &in.Content,
+			Type:      &pebType,
+			BlockedBy: &in.BlockedBy,
+		})
+		paths = append(paths, path)
+
+		for i, child := range in.Children {
+			add(child, fmt.Sprintf("%s.children[%d]", path, i), "$"+path, string(peb.TypeTask))
+		}
+	}
+	defaultType := peb.TypeEpic
+	if len(input.Children) == 0 {
+		defaultType = peb.TypeTask
+	}
+	add(input, "peb", input.Parent, string(defaultType))
+
+	results, err := runBatch(s, cfg, ops)
+	if err != nil {
+		var batchErr *batchError
+		if errors.As(err, &batchErr) {
+			// Report the failing peb by its position in the input.
+			path := strings.TrimPrefix(strings.TrimPrefix(paths[batchErr.Index], "peb"), ".")
+			if path == "" {
+				return nil, nil, batchErr.Err
 			}
+			return nil, nil, fmt.Errorf("%s: %w", path, batchErr.Err)
+		}
+		return nil, nil, err
+	}
 
-			fmt.Printf("Created new peb %s\n", id)
-			return nil
-		},
+	ids := make(map[string]string, len(results))
+	for _, r := range results {
+		ids[r.Ref] = r.ID
 	}
+
+	var build func(in NewInput, path string) NewTree
+	build = func(in NewInput, path string) NewTree {
+		tree := NewTree{ID: ids[path], Title: in.Title}
+		for i, child := range in.Children {
+			tree.Children = append(tree.Children, build(child, fmt.Sprintf("%s.children[%d]", path, i)))
+		}
+		return tree
+	}
+	tree := build(input, "peb")
+
+	p, _ := s.Get(tree.ID)
+	return p, &tree, nil
 }
 
 func extractInvalidID(err error) string {
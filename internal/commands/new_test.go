@@ -1,8 +1,10 @@
 package commands
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -234,3 +236,146 @@ func TestNewCommandOutput(t *testing.T) {
 		t.Errorf("expected output to contain ID %s", id)
 	}
 }
+
+func TestNewCommandWithChildren(t *testing.T) {
+	pebblesDir, _, cleanup := setupTestStore(t)
+	defer cleanup()
+	t.Chdir(pebblesDir)
+
+	output, err := runInProcess(`{"title":"Epic","content":"Goal","children":[
+		{"title":"First","content":"One"},
+		{"title":"Second","content":"Two","type":"feature","children":[{"title":"Nested","content":"Three"}]}
+	]}`, "new")
+	if err != nil {
+		t.Fatalf("peb new failed: %v", err)
+	}
+
+	var tree NewTree
+	if err := json.Unmarshal([]byte(output), &tree); err != nil {
+		t.Fatalf("expected JSON tree, got %q: %v", output, err)
+	}
+	if len(tree.Children) != 2 || len(tree.Children[1].Children) != 1 {
+		t.Fatalf("unexpected tree %+v", tree)
+	}
+
+	s := store.New(pebblesDir, "peb")
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	epic, ok := s.Get(tree.ID)
+	if !ok {
+		t.Fatal("expected epic to be created")
+	}
+	if epic.Type != peb.TypeEpic {
+		t.Errorf("expected default type epic, got %s", epic.Type)
+	}
+	want := []string{tree.Children[0].ID, tree.Children[1].ID}
+	if !slices.Equal(epic.BlockedBy, want) {
+		t.Errorf("expected epic blocked by %v, got %v", want, epic.BlockedBy)
+	}
+
+	first, _ := s.Get(tree.Children[0].ID)
+	if first.Title != "First" || first.Type != peb.TypeTask {
+		t.Errorf("unexpected first child %+v", first)
+	}
+	second, _ := s.Get(tree.Children[1].ID)
+	if second.Type != peb.TypeFeature || !slices.Equal(second.BlockedBy, []string{tree.Children[1].Children[0].ID}) {
+		t.Errorf("unexpected second child %+v", second)
+	}
+}
+
+func TestNewCommandWithInvalidChild(t *testing.T) {
+	pebblesDir, _, cleanup := setupTestStore(t)
+	defer cleanup()
+	t.Chdir(pebblesDir)
+
+	_, err := runInProcess(`{"title":"Epic","content":"Goal","children":[
+		{"title":"First","content":"One"},
+		{"title":"Second"}
+	]}`, "new")
+	if err == nil || err.Error() != "children[1]: content is required" {
+		t.Fatalf("expected error for second child, got %v", err)
+	}
+
+	entries, err := os.ReadDir(pebblesDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected no pebs to be created, got %d entries", len(entries))
+	}
+}
+
+func TestNewCommandIDSchemes(t *testing.T) {
+	pebblesDir, _, cleanup := setupTestStore(t)
+	defer cleanup()
+	t.Chdir(pebblesDir)
+
+	setScheme := func(scheme string) {
+		t.Helper()
+		config := "prefix = \"peb\"\nid_length = 4\nid_scheme = \"" + scheme + "\"\n"
+		if err := os.WriteFile(filepath.Join(pebblesDir, "config.toml"), []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	newTree := func(input string) NewTree {
+		t.Helper()
+		output, err := runInProcess(input, "new")
+		if err != nil {
+			t.Fatalf("peb new failed: %v", err)
+		}
+		var tree NewTree
+		if err := json.Unmarshal([]byte(output), &tree); err != nil {
+			t.Fatalf("expected JSON tree, got %q: %v", output, err)
+		}
+		return tree
+	}
+
+	setScheme("sequential")
+	if output, err := runInProcess(`{"title":"First","content":"One"}`, "new"); err != nil || output != "Created new peb peb-1" {
+		t.Fatalf("expected peb-1, got %q, %v", output, err)
+	}
+	tree := newTree(`{"title":"Epic","content":"Goal","children":[{"title":"Task","content":"Two"}]}`)
+	if tree.ID != "peb-2" || tree.Children[0].ID != "peb-3" {
+		t.Errorf("expected peb-2 with child peb-3, got %+v", tree)
+	}
+
+	setScheme("hierarchical")
+	tree = newTree(`{"title":"Epic","content":"Goal","children":[
+		{"title":"First","content":"One"},
+		{"title":"Second","content":"Two","children":[{"title":"Nested","content":"Three"}]}
+	]}`)
+	epicID := tree.ID
+	if strings.Contains(epicID, ".") {
+		t.Errorf("expected random ID for top-level peb, got %s", epicID)
+	}
+	if tree.Children[0].ID != epicID+".1" || tree.Children[1].ID != epicID+".2" || tree.Children[1].Children[0].ID != epicID+".2.1" {
+		t.Errorf("unexpected hierarchical IDs %+v", tree)
+	}
+
+	output, err := runInProcess(`{"title":"Third","content":"Four","parent":"`+epicID+`"}`, "new")
+	if err != nil {
+		t.Fatalf("peb new with parent failed: %v", err)
+	}
+	if output != "Created new peb "+epicID+".3" {
+		t.Errorf("expected child %s.3, got %q", epicID, output)
+	}
+
+	s := store.New(pebblesDir, "peb")
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	epic, _ := s.Get(epicID)
+	want := []string{epicID + ".1", epicID + ".2", epicID + ".3"}
+	if !slices.Equal(epic.BlockedBy, want) {
+		t.Errorf("expected epic blocked by %v, got %v", want, epic.BlockedBy)
+	}
+	third, _ := s.Get(epicID + ".3")
+	if third.Type != peb.TypeTask {
+		t.Errorf("expected child type task, got %s", third.Type)
+	}
+	if filename, _ := s.Filename(epicID + ".3"); filename != epicID+".3--third.md" {
+		t.Errorf("unexpected filename %s", filename)
+	}
+}
@@ -3,12 +3,12 @@ package commands
 import (
 	_ "embed"
 	"fmt"
-	"os"
 	"strings"
 	"text/template"
 
 	"github.com/urfave/cli/v2"
 	"go.yozora.eu/pebbles/internal/config"
+	"go.yozora.eu/pebbles/internal/peb"
 )
 
 //go:embed data/prompt.md
@@ -39,21 +39,33 @@ func PrimeCommand() *cli.Command {
 				return err
 			}
 
+			examples := cfg.IDScheme.Examples(cfg.Prefix, cfg.IDLength)
 			data := struct {
-				PebbleIDSuffix   string
+				PebbleIDFormat   string
 				PebbleIDPattern  string
 				PebbleIDPattern2 string
 				PebbleIDPattern3 string
 				MCP              bool
 			}{
-				PebbleIDSuffix:   strings.Repeat("x", cfg.IDLength),
-				PebbleIDPattern:  cfg.Prefix + "-" + strings.Repeat("x", cfg.IDLength),
-				PebbleIDPattern2: cfg.Prefix + "-" + strings.Repeat("y", cfg.IDLength),
-				PebbleIDPattern3: cfg.Prefix + "-" + strings.Repeat("z", cfg.IDLength),
+				PebbleIDFormat:   idFormat(cfg),
+				PebbleIDPattern:  examples[0],
+				PebbleIDPattern2: examples[1],
+				PebbleIDPattern3: examples[2],
 				MCP:              c.Bool("mcp"),
 			}
 
-			return tmpl.Execute(os.Stdout, data)
+			return tmpl.Execute(c.App.Writer, data)
 		},
 	}
 }
+
+func idFormat(cfg *config.Config) string {
+	random := strings.Repeat("x", cfg.IDLength)
+	switch cfg.IDScheme {
+	case peb.IDSchemeSequential:
+		return fmt.Sprintf("format: `%s-N` where `N` is a sequential number", cfg.Prefix)
+	case peb.IDSchemeHierarchical:
+		return fmt.Sprintf("format: `%s-%s` where `%s` is a random ID; pebs created with a parent get the parent's ID followed by a number, e.g. `%s-%s.1`", cfg.Prefix, random, random, cfg.Prefix, random)
+	}
+	return fmt.Sprintf("format: `%s-%s` where `%s` is a random ID", cfg.Prefix, random, random)
+}
@@ -2,10 +2,13 @@ package commands
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/urfave/cli/v2"
 	"go.yozora.eu/pebbles/internal/config"
@@ -46,6 +49,24 @@ OR syntax:
 
 Other filters:
   blocked-by:peb-xxxx  Show pebs blocked by a specific peb ID
+  commit:<rev>         Show pebs linked to a commit (hash or jj change ID,
+                       abbreviations match)
+
+Fields:
+  id, type, status, title, created, changed, blocked-by
+  content              Markdown content, "content:N" truncates it to N characters
+  content-summary      First paragraph of the content
+  blocks               IDs of pebs blocked by this peb
+  ready                Whether the peb is open and has no open blockers
+  filename             Name of the peb file in .pebbles/
+  age                  Time since the peb was created
+  source               Where an imported peb came from, e.g. github#12
+  commits              Linked commits (see "peb link-commit")
+  extra                Frontmatter keys unknown to pebbles, e.g. owner
+
+Saved queries:
+  @name                Expand the named query from the [queries] table of
+                       config.toml. Can be combined with further filters.
 
 Examples:
   peb query                          List all pebs
@@ -56,7 +77,13 @@ Examples:
   peb query status:new type:feature  Show new features only
   peb query type:(bug|feature)       Show bugs or features
   peb query blocked-by:peb-xxxx      Show pebs blocked by peb-xxxx
-  peb query --fields id,title        Show only id and title fields`,
+  peb query commit:3f2a9c1           Show the pebs implemented by commit 3f2a9c1
+  peb query --fields id,title        Show only id and title fields
+  peb query --fields title,content:200  Include the first 200 characters of the content
+  peb query @triage status:new       Expand saved query "triage", then filter
+  peb query --list-saved             List saved queries
+  peb query --count status:open      Count open pebs
+  peb query --group-by type          Count pebs per type`,
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:    "fields",
@@ -64,6 +91,18 @@ Examples:
 				Value:   "id,type,status,title,blocked-by",
 				Aliases: []string{"f"},
 			},
+			&cli.BoolFlag{
+				Name:  "count",
+				Usage: "Print the number of matching pebs instead of the pebs",
+			},
+			&cli.StringFlag{
+				Name:  "group-by",
+				Usage: "Count matching pebs per status, type, label or priority (implies --count)",
+			},
+			&cli.BoolFlag{
+				Name:  "list-saved",
+				Usage: "List the saved queries from config.toml",
+			},
 		},
 		Action: func(c *cli.Context) error {
 			cfg, err := config.Load()
@@ -75,12 +114,31 @@ Examples:
 				return fmt.Errorf("failed to update plugin: %w", err)
 			}
 
+			if c.Bool("list-saved") {
+				queries := cfg.Queries
+				if queries == nil {
+					queries = map[string]string{}
+				}
+				encoder := json.NewEncoder(c.App.Writer)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(queries); err != nil {
+					return fmt.Errorf("failed to encode saved queries: %w", err)
+				}
+				return nil
+			}
+
 			s := store.New(cfg.PebblesDir(), cfg.Prefix)
 			if err := s.Load(); err != nil {
 				return err
 			}
+			warnInvalid(c.App.ErrWriter, s, cfg.Prefix)
 
-			filters, err := parseFilters(c.Args().Slice())
+			args, err := expandSavedQueries(c.Args().Slice(), cfg.Queries)
+			if err != nil {
+				return err
+			}
+
+			filters, err := parseFilters(args, s.Resolve)
 			if err != nil {
 				return err
 			}
@@ -96,12 +154,38 @@ Examples:
 				return pebs[i].ID < pebs[j].ID
 			})
 
+			var matches []*peb.Peb
 			for _, p := range pebs {
 				if applyFilters(p, filters) {
-					output := buildOutput(p, fields)
-					if err := json.NewEncoder(os.Stdout).Encode(output); err != nil {
-						return fmt.Errorf("failed to encode peb: %w", err)
-					}
+					matches = append(matches, p)
+				}
+			}
+
+			if groupBy := c.String("group-by"); groupBy != "" {
+				counts, err := countBy(matches, groupBy)
+				if err != nil {
+					return err
+				}
+				if err := json.NewEncoder(c.App.Writer).Encode(counts); err != nil {
+					return fmt.Errorf("failed to encode counts: %w", err)
+				}
+				return nil
+			}
+
+			if c.Bool("count") {
+				output := struct {
+					Count int `json:"count"`
+				}{Count: len(matches)}
+				if err := json.NewEncoder(c.App.Writer).Encode(output); err != nil {
+					return fmt.Errorf("failed to encode count: %w", err)
+				}
+				return nil
+			}
+
+			for _, p := range matches {
+				output := buildOutput(s, p, fields)
+				if err := json.NewEncoder(c.App.Writer).Encode(output); err != nil {
+					return fmt.Errorf("failed to encode peb: %w", err)
 				}
 			}
 
@@ -110,8 +194,21 @@ Examples:
 	}
 }
 
-func parseFilters(args []string) ([]filterFunc, error) {
+// parseFilters resolves the IDs in id: and blocked-by: filters with resolve,
+// or matches them exactly if it is nil. Unknown IDs match no peb, ambiguous
+// ones are an error.
+func parseFilters(args []string, resolve func(string) (string, error)) ([]filterFunc, error) {
 	var filters []filterFunc
+	resolveID := func(id string) (string, error) {
+		if resolve == nil {
+			return id, nil
+		}
+		resolved, err := resolve(id)
+		if errors.Is(err, store.ErrNotFound) {
+			return id, nil
+		}
+		return resolved, err
+	}
 
 	for _, arg := range args {
 		if !strings.Contains(arg, ":") {
@@ -176,6 +273,13 @@ func parseFilters(args []string) ([]filterFunc, error) {
 			}
 		case "id":
 			if values := parseOrValues(value); len(values) > 0 {
+				for i, v := range values {
+					id, err := resolveID(v)
+					if err != nil {
+						return nil, err
+					}
+					values[i] = id
+				}
 				filters = append(filters, func(p *peb.Peb) bool {
 					for _, v := range values {
 						if p.ID == v {
@@ -185,19 +289,42 @@ func parseFilters(args []string) ([]filterFunc, error) {
 					return false
 				})
 			} else {
+				id, err := resolveID(value)
+				if err != nil {
+					return nil, err
+				}
 				filters = append(filters, func(p *peb.Peb) bool {
-					return p.ID == value
+					return p.ID == id
 				})
 			}
 		case "blocked-by":
+			blocker, err := resolveID(value)
+			if err != nil {
+				return nil, err
+			}
 			filters = append(filters, func(p *peb.Peb) bool {
 				for _, id := range p.BlockedBy {
-					if id == value {
+					if id == blocker {
 						return true
 					}
 				}
 				return false
 			})
+		case "commit":
+			values := parseOrValues(value)
+			if len(values) == 0 {
+				values = []string{value}
+			}
+			filters = append(filters, func(p *peb.Peb) bool {
+				for _, commit := range p.Commits {
+					for _, v := range values {
+						if matchesCommit(commit, v) {
+							return true
+						}
+					}
+				}
+				return false
+			})
 		default:
 			return nil, fmt.Errorf("unknown filter key: %s", key)
 		}
@@ -206,6 +333,69 @@ func parseFilters(args []string) ([]filterFunc, error) {
 	return filters, nil
 }
 
+func matchesCommit(commit, rev string) bool {
+	return rev != "" && strings.HasPrefix(strings.ToLower(commit), strings.ToLower(rev))
+}
+
+func expandSavedQueries(args []string, queries map[string]string) ([]string, error) {
+	return expandSavedQueriesVisiting(args, queries, map[string]bool{})
+}
+
+func expandSavedQueriesVisiting(args []string, queries map[string]string, visiting map[string]bool) ([]string, error) {
+	var result []string
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "@") {
+			result = append(result, arg)
+			continue
+		}
+
+		name := arg[1:]
+		query, ok := queries[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown saved query: %s", arg)
+		}
+		if visiting[name] {
+			return nil, fmt.Errorf("saved query %s refers to itself", arg)
+		}
+
+		visiting[name] = true
+		expanded, err := expandSavedQueriesVisiting(splitFilters(query), queries, visiting)
+		if err != nil {
+			return nil, err
+		}
+		visiting[name] = false
+
+		result = append(result, expanded...)
+	}
+	return result, nil
+}
+
+// splitFilters keeps "type:( bug | feature )" a single filter.
+func splitFilters(s string) []string {
+	var filters []string
+	var current strings.Builder
+	depth := 0
+	for _, r := range s {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+		case (r == ' ' || r == '\t' || r == '\n') && depth == 0:
+			if current.Len() > 0 {
+				filters = append(filters, current.String())
+				current.Reset()
+			}
+			continue
+		}
+		current.WriteRune(r)
+	}
+	if current.Len() > 0 {
+		filters = append(filters, current.String())
+	}
+	return filters
+}
+
 func parseOrValues(value string) []string {
 	if !strings.HasPrefix(value, "(") || !strings.HasSuffix(value, ")") {
 		return nil
@@ -241,34 +431,51 @@ func applyFilters(p *peb.Peb, filters []filterFunc) bool {
 	return true
 }
 
-func parseFields(fieldsStr string) ([]string, error) {
+var validFields = []string{
+	"id", "type", "status", "title", "created", "changed", "blocked-by",
+	"content", "content-summary", "blocks", "ready", "filename", "age",
+	"source", "commits", "extra",
+}
+
+type field struct {
+	name  string
+	limit int // maximum content length, 0 for no limit
+}
+
+func parseFields(fieldsStr string) ([]field, error) {
 	fields := strings.Split(fieldsStr, ",")
-	var parsedFields []string
+	var parsedFields []field
 	hasID := false
-	for _, field := range fields {
-		field = strings.TrimSpace(field)
-		switch field {
-		case "id", "type", "status", "title", "created", "changed", "blocked-by":
-			parsedFields = append(parsedFields, field)
-			if field == "id" {
-				hasID = true
+	for _, name := range fields {
+		name = strings.TrimSpace(name)
+		f := field{name: name}
+		if rest, ok := strings.CutPrefix(name, "content:"); ok {
+			limit, err := strconv.Atoi(rest)
+			if err != nil || limit <= 0 {
+				return nil, fmt.Errorf("invalid content length: %s (expected a positive number)", rest)
 			}
-		default:
-			return nil, fmt.Errorf("unknown field: %s", field)
+			f = field{name: "content", limit: limit}
+		}
+		if !slices.Contains(validFields, f.name) {
+			return nil, fmt.Errorf("unknown field: %s (valid fields: %s)", name, strings.Join(validFields, ", "))
 		}
+		if f.name == "id" {
+			hasID = true
+		}
+		parsedFields = append(parsedFields, f)
 	}
 	if !hasID {
-		parsedFields = append([]string{"id"}, parsedFields...)
+		parsedFields = append([]field{{name: "id"}}, parsedFields...)
 	}
 	return parsedFields, nil
 }
 
-func buildOutput(p *peb.Peb, fields []string) *peb.PebJSON {
+func buildOutput(s *store.Store, p *peb.Peb, fields []field) *peb.PebJSON {
 	output := &peb.PebJSON{
 		ID: p.ID,
 	}
-	for _, field := range fields {
-		switch field {
+	for _, f := range fields {
+		switch f.name {
 		case "type":
 			output.Type = p.Type
 		case "status":
@@ -283,7 +490,49 @@ func buildOutput(p *peb.Peb, fields []string) *peb.PebJSON {
 			if len(p.BlockedBy) > 0 {
 				output.BlockedBy = p.BlockedBy
 			}
+		case "content":
+			content := truncateRunes(p.Content, f.limit)
+			output.Content = &content
+		case "content-summary":
+			summary := contentSummary(p.Content)
+			output.ContentSummary = &summary
+		case "blocks":
+			output.Blocks = s.Blocks(p.ID)
+		case "ready":
+			ready := peb.IsOpen(p.Status) && len(s.OpenBlockers(p)) == 0
+			output.Ready = &ready
+		case "filename":
+			output.Filename, _ = s.Filename(p.ID)
+		case "source":
+			output.Source = p.Source
+		case "commits":
+			output.Commits = p.Commits
+		case "extra":
+			output.Extra = p.Extra
+		case "age":
+			if created, err := peb.ParseTimestamp(p.Created); err == nil {
+				output.Age = time.Since(created).Round(time.Second).String()
+			}
 		}
 	}
 	return output
 }
+
+func truncateRunes(s string, limit int) string {
+	if limit <= 0 {
+		return s
+	}
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit]) + "..."
+}
+
+func contentSummary(content string) string {
+	content = strings.TrimSpace(strings.ReplaceAll(content, "\r\n", "\n"))
+	if idx := strings.Index(content, "\n\n"); idx != -1 {
+		content = content[:idx]
+	}
+	return strings.TrimSpace(content)
+}
@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"encoding/json"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/urfave/cli/v2"
@@ -642,3 +644,357 @@ func TestQueryCommandFieldOrderingWithBlockedBy(t *testing.T) {
 		t.Errorf("expected output:\n%s\ngot:\n%s", expected, output)
 	}
 }
+
+func TestQueryCommandCount(t *testing.T) {
+	pebblesDir, s, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	t.Chdir(pebblesDir)
+
+	pebs := []*peb.Peb{
+		peb.New("peb-aaaa", "Bug 1", peb.TypeBug, peb.StatusNew, "Content"),
+		peb.New("peb-bbbb", "Bug 2", peb.TypeBug, peb.StatusFixed, "Content"),
+		peb.New("peb-cccc", "Task", peb.TypeTask, peb.StatusNew, "Content"),
+	}
+	for _, p := range pebs {
+		if err := s.Save(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want map[string]int
+	}{
+		{
+			name: "count all",
+			args: []string{"--count"},
+			want: map[string]int{"count": 3},
+		},
+		{
+			name: "count with filter",
+			args: []string{"--count", "status:new"},
+			want: map[string]int{"count": 2},
+		},
+		{
+			name: "group by type",
+			args: []string{"--group-by", "type"},
+			want: map[string]int{"bug": 2, "task": 1},
+		},
+		{
+			name: "group by status with filter",
+			args: []string{"--count", "--group-by", "status", "type:bug"},
+			want: map[string]int{"new": 1, "fixed": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &cli.App{
+				Commands: []*cli.Command{QueryCommand()},
+			}
+
+			r, w, _ := os.Pipe()
+			oldStdout := os.Stdout
+			os.Stdout = w
+
+			err := app.Run(append([]string{"peb", "query"}, tt.args...))
+			w.Close()
+			os.Stdout = oldStdout
+			if err != nil {
+				t.Fatalf("command failed: %v", err)
+			}
+
+			var got map[string]int
+			if err := json.NewDecoder(r).Decode(&got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for key, count := range tt.want {
+				if got[key] != count {
+					t.Errorf("expected %s=%d, got %d", key, count, got[key])
+				}
+			}
+		})
+	}
+
+	app := &cli.App{
+		Commands: []*cli.Command{QueryCommand()},
+	}
+	if err := app.Run([]string{"peb", "query", "--group-by", "title"}); err == nil {
+		t.Error("expected error for unknown group-by key")
+	}
+	// The key is checked even if no peb matches.
+	if err := app.Run([]string{"peb", "query", "--group-by", "title", "status:wont-fix"}); err == nil {
+		t.Error("expected error for unknown group-by key without matches")
+	}
+}
+
+func TestQueryCommandSavedQueries(t *testing.T) {
+	pebblesDir, s, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	t.Chdir(pebblesDir)
+
+	configContent := `prefix = "peb"
+id_length = 4
+
+[queries]
+triage = "status:open type:( bug | feature )"
+bugs = "@triage type:bug"
+loop = "@loop"
+`
+	if err := os.WriteFile(filepath.Join(pebblesDir, "config.toml"), []byte(configContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pebs := []*peb.Peb{
+		peb.New("peb-aaaa", "Open bug", peb.TypeBug, peb.StatusNew, "Content"),
+		peb.New("peb-bbbb", "Open feature", peb.TypeFeature, peb.StatusInProgress, "Content"),
+		peb.New("peb-cccc", "Fixed bug", peb.TypeBug, peb.StatusFixed, "Content"),
+		peb.New("peb-dddd", "Open task", peb.TypeTask, peb.StatusNew, "Content"),
+	}
+	for _, p := range pebs {
+		if err := s.Save(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantIDs []string
+	}{
+		{
+			name:    "saved query",
+			args:    []string{"@triage"},
+			wantIDs: []string{"peb-aaaa", "peb-bbbb"},
+		},
+		{
+			name:    "saved query with extra filter",
+			args:    []string{"@triage", "status:in-progress"},
+			wantIDs: []string{"peb-bbbb"},
+		},
+		{
+			name:    "nested saved query",
+			args:    []string{"@bugs"},
+			wantIDs: []string{"peb-aaaa"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &cli.App{
+				Commands: []*cli.Command{QueryCommand()},
+			}
+
+			r, w, _ := os.Pipe()
+			oldStdout := os.Stdout
+			os.Stdout = w
+
+			err := app.Run(append([]string{"peb", "query"}, tt.args...))
+			w.Close()
+			os.Stdout = oldStdout
+			if err != nil {
+				t.Fatalf("command failed: %v", err)
+			}
+
+			var gotIDs []string
+			decoder := json.NewDecoder(r)
+			for decoder.More() {
+				var result peb.PebJSON
+				if err := decoder.Decode(&result); err != nil {
+					t.Fatalf("failed to parse JSON: %v", err)
+				}
+				gotIDs = append(gotIDs, result.ID)
+			}
+
+			if strings.Join(gotIDs, ",") != strings.Join(tt.wantIDs, ",") {
+				t.Errorf("expected %v, got %v", tt.wantIDs, gotIDs)
+			}
+		})
+	}
+
+	app := &cli.App{
+		Commands: []*cli.Command{QueryCommand()},
+	}
+	if err := app.Run([]string{"peb", "query", "@unknown"}); err == nil {
+		t.Error("expected error for unknown saved query")
+	}
+	if err := app.Run([]string{"peb", "query", "@loop"}); err == nil {
+		t.Error("expected error for self-referencing saved query")
+	}
+}
+
+func TestQueryCommandListSaved(t *testing.T) {
+	pebblesDir, _, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	t.Chdir(pebblesDir)
+
+	configContent := `prefix = "peb"
+id_length = 4
+
+[queries]
+triage = "status:open"
+`
+	if err := os.WriteFile(filepath.Join(pebblesDir, "config.toml"), []byte(configContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	app := &cli.App{
+		Commands: []*cli.Command{QueryCommand()},
+	}
+
+	r, w, _ := os.Pipe()
+	oldStdout := os.Stdout
+	os.Stdout = w
+
+	err := app.Run([]string{"peb", "query", "--list-saved"})
+	w.Close()
+	os.Stdout = oldStdout
+	if err != nil {
+		t.Fatalf("command failed: %v", err)
+	}
+
+	var queries map[string]string
+	if err := json.NewDecoder(r).Decode(&queries); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	if len(queries) != 1 || queries["triage"] != "status:open" {
+		t.Errorf("unexpected saved queries: %v", queries)
+	}
+}
+
+func TestSplitFilters(t *testing.T) {
+	got := splitFilters("  status:open   type:( bug | feature )\tid:peb-xxxx ")
+	want := []string{"status:open", "type:( bug | feature )", "id:peb-xxxx"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("splitFilters() = %q, want %q", got, want)
+	}
+}
+
+func TestQueryCommandContentAndComputedFields(t *testing.T) {
+	pebblesDir, s, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	t.Chdir(pebblesDir)
+
+	blocker := peb.New("peb-aaaa", "Blocker", peb.TypeTask, peb.StatusNew, "First paragraph\nstill first.\n\nSecond paragraph.")
+	if err := s.Save(blocker); err != nil {
+		t.Fatal(err)
+	}
+	blocked := peb.New("peb-bbbb", "Blocked", peb.TypeTask, peb.StatusNew, "Content")
+	blocked.BlockedBy = []string{"peb-aaaa"}
+	if err := s.Save(blocked); err != nil {
+		t.Fatal(err)
+	}
+
+	query := func(args ...string) map[string]peb.PebJSON {
+		app := &cli.App{
+			Commands: []*cli.Command{QueryCommand()},
+		}
+
+		r, w, _ := os.Pipe()
+		oldStdout := os.Stdout
+		os.Stdout = w
+
+		err := app.Run(append([]string{"peb", "query"}, args...))
+		w.Close()
+		os.Stdout = oldStdout
+		if err != nil {
+			t.Fatalf("command failed: %v", err)
+		}
+
+		results := make(map[string]peb.PebJSON)
+		decoder := json.NewDecoder(r)
+		for decoder.More() {
+			var result peb.PebJSON
+			if err := decoder.Decode(&result); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			results[result.ID] = result
+		}
+		return results
+	}
+
+	results := query("--fields", "content,content-summary,blocks,ready,filename,age")
+
+	a := results["peb-aaaa"]
+	if a.Content == nil || *a.Content != blocker.Content {
+		t.Errorf("expected full content, got %v", a.Content)
+	}
+	if a.ContentSummary == nil || *a.ContentSummary != "First paragraph\nstill first." {
+		t.Errorf("unexpected content summary %v", a.ContentSummary)
+	}
+	if len(a.Blocks) != 1 || a.Blocks[0] != "peb-bbbb" {
+		t.Errorf("expected peb-aaaa to block peb-bbbb, got %v", a.Blocks)
+	}
+	if a.Ready == nil || !*a.Ready {
+		t.Errorf("expected peb-aaaa to be ready")
+	}
+	if a.Filename != "peb-aaaa--blocker.md" {
+		t.Errorf("unexpected filename %q", a.Filename)
+	}
+	if a.Age == "" {
+		t.Errorf("expected age to be set")
+	}
+
+	b := results["peb-bbbb"]
+	if b.Ready == nil || *b.Ready {
+		t.Errorf("expected peb-bbbb not to be ready")
+	}
+	if len(b.Blocks) != 0 {
+		t.Errorf("expected peb-bbbb to block nothing, got %v", b.Blocks)
+	}
+
+	results = query("--fields", "content:5", "id:peb-aaaa")
+	if got := results["peb-aaaa"].Content; got == nil || *got != "First..." {
+		t.Errorf("expected truncated content, got %v", got)
+	}
+}
+
+func TestQueryCommandEmptyContentField(t *testing.T) {
+	pebblesDir, s, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	t.Chdir(pebblesDir)
+
+	if err := s.Save(peb.New("peb-aaaa", "No content", peb.TypeTask, peb.StatusNew, "")); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runInProcess("", "query", "--fields", "content,content-summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"id":"peb-aaaa","content-summary":"","content":""}`; out != want {
+		t.Errorf("expected %s, got %s", want, out)
+	}
+}
+
+func TestQueryCommandInvalidFieldListsValidFields(t *testing.T) {
+	pebblesDir, _, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	t.Chdir(pebblesDir)
+
+	app := &cli.App{
+		Commands: []*cli.Command{QueryCommand()},
+	}
+
+	err := app.Run([]string{"peb", "query", "--fields=bogus"})
+	if err == nil {
+		t.Fatal("expected error for invalid field")
+	}
+	if !strings.Contains(err.Error(), "content-summary") {
+		t.Errorf("expected error to list valid fields, got: %v", err)
+	}
+
+	if err := app.Run([]string{"peb", "query", "--fields=content:abc"}); err == nil {
+		t.Error("expected error for invalid content length")
+	}
+}
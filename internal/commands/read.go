@@ -3,7 +3,6 @@ package commands
 import (
 	"encoding/json"
 	"fmt"
-	"os"
 
 	"github.com/urfave/cli/v2"
 	"go.yozora.eu/pebbles/internal/config"
@@ -18,6 +17,7 @@ func ReadCommand() *cli.Command {
 
 This command shows all peb fields including id, title, type, status,
 created/changed timestamps, blocked-by list, and markdown content.
+Frontmatter keys unknown to pebbles are shown, in order, as "extra".
 
 Examples:
   peb read peb-xxxx
@@ -45,14 +45,14 @@ Examples:
 			pebs := make([]interface{}, 0, len(pebIDs))
 
 			for _, pebID := range pebIDs {
-				p, ok := s.Get(pebID)
-				if !ok {
-					return fmt.Errorf("peb %s not found", pebID)
+				p, err := getPeb(s, pebID)
+				if err != nil {
+					return err
 				}
 				pebs = append(pebs, p)
 			}
 
-			encoder := json.NewEncoder(os.Stdout)
+			encoder := json.NewEncoder(c.App.Writer)
 			encoder.SetIndent("", "  ")
 
 			if len(pebs) == 1 {
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"encoding/json"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/urfave/cli/v2"
 	"go.yozora.eu/pebbles/internal/peb"
 )
 
@@ -232,3 +234,41 @@ func TestReadCommandMultipleIDs(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestReadCommandResolvesIDs(t *testing.T) {
+	pebblesDir, s, cleanup := setupTestStore(t)
+	defer cleanup()
+	t.Chdir(pebblesDir)
+
+	for _, p := range []*peb.Peb{
+		peb.New("peb-ab12", "First", peb.TypeTask, peb.StatusNew, ""),
+		peb.New("peb-ab34", "Second", peb.TypeTask, peb.StatusNew, ""),
+	} {
+		if err := s.Save(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		app := &cli.App{Writer: &out, Commands: []*cli.Command{ReadCommand()}}
+		err := app.Run(append([]string{"peb", "read"}, args...))
+		return out.String(), err
+	}
+
+	out, err := run("AB12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p peb.Peb
+	if err := json.Unmarshal([]byte(out), &p); err != nil || p.ID != "peb-ab12" {
+		t.Errorf("read AB12 = %s, %v", out, err)
+	}
+
+	if _, err := run("ab"); err == nil || !strings.Contains(err.Error(), "peb-ab12") || !strings.Contains(err.Error(), "peb-ab34") {
+		t.Errorf("read ab: error = %v, want the candidates", err)
+	}
+	if _, err := run("ab13"); err == nil || !strings.Contains(err.Error(), "did you mean") {
+		t.Errorf("read ab13: error = %v, want a suggestion", err)
+	}
+}
@@ -0,0 +1,167 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"go.yozora.eu/pebbles/internal/config"
+	"go.yozora.eu/pebbles/internal/peb"
+	"go.yozora.eu/pebbles/internal/store"
+)
+
+// prefixPattern excludes "-", which separates the prefix from the rest of
+// the ID.
+var prefixPattern = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+func validPrefix(prefix string) bool {
+	return prefixPattern.MatchString(prefix)
+}
+
+func commonPrefix(names []string) string {
+	var common string
+	for _, name := range names {
+		prefix, _, ok := strings.Cut(name, "-")
+		if !ok || !strings.Contains(name, "--") || !validPrefix(prefix) || (common != "" && prefix != common) {
+			return ""
+		}
+		common = prefix
+	}
+	return common
+}
+
+func ReprefixCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "reprefix",
+		Usage:     "Change the ID prefix of all pebs",
+		ArgsUsage: "<new-prefix>",
+		Description: `Change the ID prefix of every peb, e.g. from peb-ab12 to web-ab12, and set
+it as the prefix in config.toml. Files are renamed and blocked-by references
+rewritten; with --content, mentions of the IDs in the content of the pebs are
+rewritten too. Either all pebs are changed or none.
+
+Use --from if prefix in config.toml was already changed by hand, which makes
+the pebs with the old prefix disappear from every command.
+
+To change the length of new IDs, just set id_length in config.toml; existing
+IDs keep working.
+
+Examples:
+  peb reprefix web
+  peb reprefix --content web
+  peb reprefix --from peb web`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "from",
+				Usage: "Prefix of the existing pebs (default: the prefix in config.toml)",
+			},
+			&cli.BoolFlag{
+				Name:  "content",
+				Usage: "Also rewrite mentions of the IDs in the content",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("new prefix is required")
+			}
+			newPrefix := c.Args().First()
+			if !validPrefix(newPrefix) {
+				return fmt.Errorf("invalid prefix %q: use only letters, digits and _", newPrefix)
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			if err := config.MaybeUpdatePlugin(cfg); err != nil {
+				return fmt.Errorf("failed to update plugin: %w", err)
+			}
+
+			oldPrefix := c.String("from")
+			if oldPrefix == "" {
+				oldPrefix = cfg.Prefix
+			}
+			if !validPrefix(oldPrefix) {
+				return fmt.Errorf("invalid prefix %q: use only letters, digits and _", oldPrefix)
+			}
+
+			// Renaming pebs doesn't run hooks.
+			s := store.New(cfg.PebblesDir(), oldPrefix)
+			if err := s.Load(); err != nil {
+				return err
+			}
+			if invalid := s.Invalid(); len(invalid) > 0 {
+				return fmt.Errorf("cannot rename pebs that cannot be read: %w", invalid[0])
+			}
+
+			configPath := filepath.Join(cfg.PebblesDir(), "config.toml")
+			data, err := os.ReadFile(configPath)
+			if err != nil {
+				return fmt.Errorf("failed to read config: %w", err)
+			}
+			if err := os.WriteFile(configPath, config.SetValue(data, "prefix", strconv.Quote(newPrefix)), 0644); err != nil {
+				return fmt.Errorf("failed to update config: %w", err)
+			}
+
+			renamed, err := reprefix(s, cfg.PebblesDir(), oldPrefix, newPrefix, c.Bool("content"))
+			if err != nil {
+				if restoreErr := os.WriteFile(configPath, data, 0644); restoreErr != nil {
+					return fmt.Errorf("%w (and failed to restore config: %v)", err, restoreErr)
+				}
+				return err
+			}
+
+			fmt.Fprintf(c.App.Writer, "Renamed %d peb(s) from %s-* to %s-*\n", renamed, oldPrefix, newPrefix)
+			return nil
+		},
+	}
+}
+
+// reprefix returns the number of renamed pebs.
+func reprefix(s *store.Store, dir, oldPrefix, newPrefix string, content bool) (int, error) {
+	if oldPrefix == newPrefix {
+		return 0, nil
+	}
+
+	pebs := s.All()
+	slices.SortFunc(pebs, func(a, b *peb.Peb) int { return strings.Compare(a.ID, b.ID) })
+	renamed := make(map[string]string, len(pebs))
+	for _, p := range pebs {
+		renamed[p.ID] = newPrefix + strings.TrimPrefix(p.ID, oldPrefix)
+	}
+
+	// s ignored the files with the new prefix.
+	target := store.New(dir, newPrefix)
+	if err := target.Load(); err != nil {
+		return 0, err
+	}
+	for _, id := range renamed {
+		if target.Exists(id) {
+			return 0, fmt.Errorf("peb %s already exists", id)
+		}
+	}
+
+	changes := make([]store.Change, 0, 2*len(pebs))
+	for _, p := range pebs {
+		updated := *p
+		updated.ID = renamed[p.ID]
+		updated.BlockedBy = nil
+		for _, id := range p.BlockedBy {
+			updated.BlockedBy = append(updated.BlockedBy, renamed[id])
+		}
+		if content {
+			updated.Content = peb.ReplaceIDs(p.Content, renamed)
+		}
+		changes = append(changes, store.Change{New: &updated}, store.Change{Old: p})
+	}
+	if err := s.Apply(changes); err != nil {
+		return 0, err
+	}
+	return len(pebs), nil
+}
@@ -0,0 +1,148 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+	"go.yozora.eu/pebbles/internal/config"
+	"go.yozora.eu/pebbles/internal/peb"
+	"go.yozora.eu/pebbles/internal/store"
+)
+
+func runReprefix(args ...string) (string, error) {
+	var out bytes.Buffer
+	app := &cli.App{
+		Name:     "peb",
+		Writer:   &out,
+		Commands: []*cli.Command{ReprefixCommand()},
+	}
+	err := app.Run(append([]string{"peb", "reprefix"}, args...))
+	return strings.TrimSpace(out.String()), err
+}
+
+func TestReprefix(t *testing.T) {
+	pebblesDir, s, cleanup := setupTestStore(t)
+	defer cleanup()
+	t.Chdir(pebblesDir)
+
+	blocker := peb.New("peb-aaaa", "Blocker", peb.TypeTask, peb.StatusNew, "See peb-bbbb and peb-zzzz.")
+	if err := s.Save(blocker); err != nil {
+		t.Fatal(err)
+	}
+	blocked := peb.New("peb-bbbb", "Blocked", peb.TypeTask, peb.StatusNew, "After peb-aaaa.")
+	blocked.BlockedBy = []string{"peb-aaaa"}
+	if err := s.Save(blocked); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runReprefix("--content", "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "Renamed 2 peb(s) from peb-* to web-*" {
+		t.Errorf("output = %q", out)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Prefix != "web" {
+		t.Errorf("prefix = %q, want web", cfg.Prefix)
+	}
+	s = store.New(pebblesDir, "web")
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Foreign()) != 0 {
+		t.Errorf("files left with the old prefix: %v", s.Foreign())
+	}
+	a, ok := s.Get("web-aaaa")
+	if !ok || a.Content != "See web-bbbb and peb-zzzz." || a.Created != blocker.Created {
+		t.Errorf("web-aaaa = %+v", a)
+	}
+	b, ok := s.Get("web-bbbb")
+	if !ok || !slices.Equal(b.BlockedBy, []string{"web-aaaa"}) || b.Content != "After web-aaaa." {
+		t.Errorf("web-bbbb = %+v", b)
+	}
+}
+
+func TestReprefixFrom(t *testing.T) {
+	pebblesDir, s, cleanup := setupTestStore(t)
+	defer cleanup()
+	t.Chdir(pebblesDir)
+
+	if err := s.Save(peb.New("peb-aaaa", "Task", peb.TypeTask, peb.StatusNew, "Mentions peb-aaaa")); err != nil {
+		t.Fatal(err)
+	}
+	// The prefix was changed by hand.
+	if err := os.WriteFile(filepath.Join(pebblesDir, "config.toml"), []byte("prefix = \"web\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := runReprefix("--from", "peb", "web"); err != nil {
+		t.Fatal(err)
+	}
+	s = store.New(pebblesDir, "web")
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	p, ok := s.Get("web-aaaa")
+	if !ok || p.Content != "Mentions peb-aaaa" {
+		t.Errorf("web-aaaa = %+v, want content unchanged without --content", p)
+	}
+}
+
+func TestReprefixConflict(t *testing.T) {
+	pebblesDir, s, cleanup := setupTestStore(t)
+	defer cleanup()
+	t.Chdir(pebblesDir)
+
+	if err := s.Save(peb.New("peb-aaaa", "Task", peb.TypeTask, peb.StatusNew, "")); err != nil {
+		t.Fatal(err)
+	}
+	if err := peb.WriteFile(pebblesDir, peb.New("web-aaaa", "Other", peb.TypeTask, peb.StatusNew, "")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{{"web"}, {"bad-prefix"}, {}} {
+		if _, err := runReprefix(args...); err == nil {
+			t.Errorf("reprefix %v: expected an error", args)
+		}
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Prefix != "peb" {
+		t.Errorf("prefix = %q, want the config to be restored", cfg.Prefix)
+	}
+	if _, err := os.Stat(filepath.Join(pebblesDir, "peb-aaaa--task.md")); err != nil {
+		t.Errorf("expected peb-aaaa to be left alone: %v", err)
+	}
+}
+
+func TestWarnInvalid(t *testing.T) {
+	pebblesDir, s, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	for _, name := range []string{"old-aaaa--one.md", "old-bbbb--two.md"} {
+		if err := os.WriteFile(filepath.Join(pebblesDir, name), []byte("---\n---\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	warnInvalid(&out, s, "peb")
+	want := `Warning: ignored 2 .md file(s) without the prefix "peb-", e.g. old-aaaa--one.md (run "peb reprefix --from old peb" to rename them)` + "\n"
+	if out.String() != want {
+		t.Errorf("warnInvalid() = %q, want %q", out.String(), want)
+	}
+}
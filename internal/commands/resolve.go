@@ -0,0 +1,35 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"go.yozora.eu/pebbles/internal/peb"
+	"go.yozora.eu/pebbles/internal/store"
+)
+
+// getPeb accepts abbreviated IDs, see store.Resolve.
+func getPeb(s *store.Store, id string) (*peb.Peb, error) {
+	resolved, err := s.Resolve(id)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := s.Get(resolved)
+	if !ok {
+		return nil, notFound(s, resolved)
+	}
+	return p, nil
+}
+
+// notFound explains why s.Get didn't find id if its file exists.
+func notFound(s *store.Store, id string) error {
+	err := s.ReadError(id)
+	var dup *store.DuplicateError
+	switch {
+	case errors.As(err, &dup):
+		return fmt.Errorf("%w%s", err, dedupeHint)
+	case err != nil:
+		return fmt.Errorf("peb %s cannot be read: %w", id, err)
+	}
+	return fmt.Errorf("peb %s %w", id, store.ErrNotFound)
+}
@@ -0,0 +1,320 @@
+package commands
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/urfave/cli/v2"
+	"go.yozora.eu/pebbles/internal/config"
+	"go.yozora.eu/pebbles/internal/peb"
+	"go.yozora.eu/pebbles/internal/store"
+)
+
+const defaultServeAddr = "127.0.0.1:7474"
+
+func ServeCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "serve",
+		Usage: "Serve a local HTTP/JSON API",
+		Description: `Serve an HTTP/JSON API over the pebs of this project.
+
+Endpoints:
+  GET    /pebs?q=<filters>&fields=<fields>  List pebs matching the query syntax
+  GET    /pebs/{id}                         Read a peb
+  POST   /pebs                              Create a peb (same body as "peb new")
+  PATCH  /pebs/{id}                         Update a peb (same body as "peb update")
+  DELETE /pebs/{id}                         Delete a peb
+  GET    /stats?q=<filters>                 Aggregated counts (same as "peb stats")
+
+Every request reads the pebs from disk, so changes made by other peb
+processes are visible immediately. Errors are returned as {"error": "..."}.
+{id} may be abbreviated as in other commands; an ID that matches several
+pebs is a 409 error.
+
+Requests that send a body must have "Content-Type: application/json".
+
+When --token is set, every request must send "Authorization: Bearer <token>".
+A token is required when listening on a non-loopback address. Without a
+token, requests must be addressed to a loopback host such as localhost, so
+that web pages cannot reach the API through DNS rebinding.
+
+Examples:
+  peb serve
+  peb serve --addr 127.0.0.1:8080
+  peb serve --addr 0.0.0.0:8080 --token secret`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "addr",
+				Usage: "Address to listen on",
+				Value: defaultServeAddr,
+			},
+			&cli.StringFlag{
+				Name:    "token",
+				Usage:   "Require this bearer token on every request",
+				EnvVars: []string{"PEB_SERVE_TOKEN"},
+			},
+		},
+		Action: func(c *cli.Context) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			if err := config.MaybeUpdatePlugin(cfg); err != nil {
+				return fmt.Errorf("failed to update plugin: %w", err)
+			}
+
+			addr := c.String("addr")
+			token := c.String("token")
+			if token == "" && !isLoopbackAddr(addr) {
+				return fmt.Errorf("refusing to listen on non-loopback address %s without --token", addr)
+			}
+
+			listener, err := net.Listen("tcp", addr)
+			if err != nil {
+				return fmt.Errorf("failed to listen: %w", err)
+			}
+			fmt.Fprintf(c.App.Writer, "Serving pebs on http://%s\n", listener.Addr())
+
+			return http.Serve(listener, newAPIHandler(cfg, token))
+		},
+	}
+}
+
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	return isLoopbackName(host)
+}
+
+func isLoopbackHost(hostport string) bool {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = strings.TrimSuffix(strings.TrimPrefix(hostport, "["), "]")
+	}
+	return isLoopbackName(host)
+}
+
+func isLoopbackName(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// apiServer serializes requests, since the store is not safe for concurrent
+// use.
+type apiServer struct {
+	cfg   *config.Config
+	token string
+	mu    sync.Mutex
+}
+
+func newAPIHandler(cfg *config.Config, token string) http.Handler {
+	api := &apiServer{cfg: cfg, token: token}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /pebs", api.handle(api.list))
+	mux.HandleFunc("POST /pebs", api.handle(api.create))
+	mux.HandleFunc("GET /pebs/{id}", api.handle(api.get))
+	mux.HandleFunc("PATCH /pebs/{id}", api.handle(api.patch))
+	mux.HandleFunc("DELETE /pebs/{id}", api.handle(api.delete))
+	mux.HandleFunc("GET /stats", api.handle(api.stats))
+	return mux
+}
+
+type apiFunc func(s *store.Store, r *http.Request) (int, any, error)
+
+func (api *apiServer) handle(fn apiFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if api.token != "" {
+			want := "Bearer " + api.token
+			got := r.Header.Get("Authorization")
+			if subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+				return
+			}
+		} else if !isLoopbackHost(r.Host) {
+			// A web page can point its own host name at 127.0.0.1, but
+			// the browser still sends that name as the Host.
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": fmt.Sprintf("host %q is not a loopback address", r.Host)})
+			return
+		}
+
+		// Browsers send cross-site form posts as text/plain or form data
+		// without asking first, but not JSON.
+		if r.Method == http.MethodPost || r.Method == http.MethodPatch {
+			mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if mediaType != "application/json" {
+				writeJSON(w, http.StatusUnsupportedMediaType, map[string]string{"error": "Content-Type must be application/json"})
+				return
+			}
+		}
+
+		api.mu.Lock()
+		defer api.mu.Unlock()
+
+		s, err := openStore(api.cfg)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+
+		status, body, err := fn(s, r)
+		if err != nil {
+			// A failing post-hook doesn't undo the change, so the client
+			// must not take it for an invalid request and retry.
+			var applied *store.AppliedError
+			if errors.As(err, &applied) {
+				status = http.StatusInternalServerError
+			}
+			if status == 0 {
+				status = http.StatusBadRequest
+			}
+			writeJSON(w, status, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, status, body)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	if body == nil {
+		w.WriteHeader(status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func (api *apiServer) filterRequest(s *store.Store, r *http.Request) ([]*peb.Peb, error) {
+	args, err := expandSavedQueries(splitFilters(r.URL.Query().Get("q")), api.cfg.Queries)
+	if err != nil {
+		return nil, err
+	}
+	filters, err := parseFilters(args, s.Resolve)
+	if err != nil {
+		return nil, err
+	}
+
+	pebs := s.All()
+	sort.Slice(pebs, func(i, j int) bool {
+		return pebs[i].ID < pebs[j].ID
+	})
+
+	matches := []*peb.Peb{}
+	for _, p := range pebs {
+		if applyFilters(p, filters) {
+			matches = append(matches, p)
+		}
+	}
+	return matches, nil
+}
+
+func (api *apiServer) list(s *store.Store, r *http.Request) (int, any, error) {
+	fieldsStr := r.URL.Query().Get("fields")
+	if fieldsStr == "" {
+		fieldsStr = "id,type,status,title,blocked-by"
+	}
+	fields, err := parseFields(fieldsStr)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	pebs, err := api.filterRequest(s, r)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	output := make([]*peb.PebJSON, 0, len(pebs))
+	for _, p := range pebs {
+		output = append(output, buildOutput(s, p, fields))
+	}
+	return http.StatusOK, output, nil
+}
+
+// pathPeb also returns the HTTP status for its error.
+func pathPeb(s *store.Store, r *http.Request) (*peb.Peb, int, error) {
+	p, err := getPeb(s, r.PathValue("id"))
+	var dup *store.DuplicateError
+	switch {
+	case err == nil:
+		return p, 0, nil
+	case errors.Is(err, store.ErrNotFound):
+		return nil, http.StatusNotFound, err
+	case errors.Is(err, store.ErrAmbiguous), errors.As(err, &dup):
+		return nil, http.StatusConflict, err
+	}
+	return nil, http.StatusInternalServerError, err
+}
+
+func (api *apiServer) get(s *store.Store, r *http.Request) (int, any, error) {
+	p, status, err := pathPeb(s, r)
+	if err != nil {
+		return status, nil, err
+	}
+	return http.StatusOK, p, nil
+}
+
+func (api *apiServer) create(s *store.Store, r *http.Request) (int, any, error) {
+	var input NewInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		return 0, nil, fmt.Errorf("failed to parse JSON input: %w", err)
+	}
+	p, err := createPeb(s, api.cfg, input)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusCreated, p, nil
+}
+
+func (api *apiServer) patch(s *store.Store, r *http.Request) (int, any, error) {
+	p, status, err := pathPeb(s, r)
+	if err != nil {
+		return status, nil, err
+	}
+
+	var input UpdateInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		return 0, nil, fmt.Errorf("failed to parse JSON input: %w", err)
+	}
+	// The store keeps p, so a rejected update must not change it.
+	updated := *p
+	updated.BlockedBy = slices.Clone(p.BlockedBy)
+	if err := applyUpdate(s, api.cfg, &updated, input); err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, &updated, nil
+}
+
+func (api *apiServer) delete(s *store.Store, r *http.Request) (int, any, error) {
+	p, status, err := pathPeb(s, r)
+	if err != nil {
+		return status, nil, err
+	}
+	if err := s.Delete(p); err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("failed to delete peb %s: %w", p.ID, err)
+	}
+	return http.StatusNoContent, nil, nil
+}
+
+func (api *apiServer) stats(s *store.Store, r *http.Request) (int, any, error) {
+	pebs, err := api.filterRequest(s, r)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, computeStats(s, pebs), nil
+}
@@ -0,0 +1,326 @@
+package commands
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.yozora.eu/pebbles/internal/config"
+	"go.yozora.eu/pebbles/internal/peb"
+)
+
+func startAPI(t *testing.T, pebblesDir, token string) *httptest.Server {
+	t.Helper()
+	t.Chdir(pebblesDir)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(newAPIHandler(cfg, token))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func doRequest(t *testing.T, method, url, token, body string) (int, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(data)
+}
+
+func TestServeAPI(t *testing.T) {
+	pebblesDir, s, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	server := startAPI(t, pebblesDir, "")
+
+	status, body := doRequest(t, "POST", server.URL+"/pebs", "", `{"title":"API peb","content":"Created via API","type":"task"}`)
+	if status != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", status, body)
+	}
+	var created peb.Peb
+	if err := json.Unmarshal([]byte(body), &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.Title != "API peb" || created.Type != peb.TypeTask || created.Status != peb.StatusNew {
+		t.Errorf("unexpected created peb %+v", created)
+	}
+
+	status, body = doRequest(t, "PATCH", server.URL+"/pebs/"+created.ID, "", `{"status":"in-progress","title":"Renamed"}`)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+
+	status, body = doRequest(t, "GET", server.URL+"/pebs/"+created.ID, "", "")
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+	var got peb.Peb
+	if err := json.Unmarshal([]byte(body), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "Renamed" || got.Status != peb.StatusInProgress || got.Content != "Created via API" {
+		t.Errorf("unexpected peb after update %+v", got)
+	}
+
+	// A peb written by another process must be visible without a restart.
+	other := peb.New("peb-zzzz", "Written elsewhere", peb.TypeBug, peb.StatusFixed, "Content")
+	if err := s.Save(other); err != nil {
+		t.Fatal(err)
+	}
+
+	status, body = doRequest(t, "GET", server.URL+"/pebs?q=status:open&fields=id,title", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+	if body != `[{"id":"`+created.ID+`","title":"Renamed"}]`+"\n" {
+		t.Errorf("unexpected list output %s", body)
+	}
+
+	status, body = doRequest(t, "GET", server.URL+"/pebs", "", "")
+	if status != http.StatusOK || !strings.Contains(body, "peb-zzzz") {
+		t.Errorf("expected externally created peb in list, got %d: %s", status, body)
+	}
+
+	status, body = doRequest(t, "GET", server.URL+"/stats", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+	var stats Stats
+	if err := json.Unmarshal([]byte(body), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Total != 2 || stats.Ready != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	status, body = doRequest(t, "DELETE", server.URL+"/pebs/"+created.ID, "", "")
+	if status != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", status, body)
+	}
+
+	status, _ = doRequest(t, "GET", server.URL+"/pebs/"+created.ID, "", "")
+	if status != http.StatusNotFound {
+		t.Errorf("expected 404 after delete, got %d", status)
+	}
+}
+
+func TestServeAPIErrors(t *testing.T) {
+	pebblesDir, _, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	server := startAPI(t, pebblesDir, "")
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{name: "missing content", method: "POST", path: "/pebs", body: `{"title":"x"}`, status: http.StatusBadRequest},
+		{name: "invalid JSON", method: "POST", path: "/pebs", body: `{`, status: http.StatusBadRequest},
+		{name: "unknown blocker", method: "POST", path: "/pebs", body: `{"title":"x","content":"y","blocked-by":["peb-nope"]}`, status: http.StatusBadRequest},
+		{name: "patch unknown peb", method: "PATCH", path: "/pebs/peb-nope", body: `{}`, status: http.StatusNotFound},
+		{name: "delete unknown peb", method: "DELETE", path: "/pebs/peb-nope", status: http.StatusNotFound},
+		{name: "invalid filter", method: "GET", path: "/pebs?q=bogus", status: http.StatusBadRequest},
+		{name: "invalid field", method: "GET", path: "/pebs?fields=bogus", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := doRequest(t, tt.method, server.URL+tt.path, "", tt.body)
+			if status != tt.status {
+				t.Errorf("expected %d, got %d: %s", tt.status, status, body)
+			}
+			if !strings.Contains(body, `"error"`) {
+				t.Errorf("expected JSON error body, got %s", body)
+			}
+		})
+	}
+}
+
+func TestServeAPIResolvesIDs(t *testing.T) {
+	pebblesDir, s, cleanup := setupTestStore(t)
+	defer cleanup()
+	first := peb.New("peb-abc1", "First", peb.TypeTask, peb.StatusNew, "")
+	second := peb.New("peb-abc2", "Second", peb.TypeTask, peb.StatusNew, "")
+	second.BlockedBy = []string{"peb-abc1"}
+	for _, p := range []*peb.Peb{first, second} {
+		if err := s.Save(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := startAPI(t, pebblesDir, "")
+
+	status, body := doRequest(t, "GET", server.URL+"/pebs/ABC1", "", "")
+	if status != http.StatusOK || !strings.Contains(body, `"id":"peb-abc1"`) {
+		t.Errorf("GET abbreviated ID = %d: %s", status, body)
+	}
+	if status, body := doRequest(t, "GET", server.URL+"/pebs/abc", "", ""); status != http.StatusConflict || !strings.Contains(body, "ambiguous") {
+		t.Errorf("GET ambiguous ID = %d: %s", status, body)
+	}
+	if status, body := doRequest(t, "GET", server.URL+"/pebs/peb-abd1", "", ""); status != http.StatusNotFound || !strings.Contains(body, "did you mean") {
+		t.Errorf("GET mistyped ID = %d: %s", status, body)
+	}
+
+	// A rejected update leaves the peb unchanged.
+	status, body = doRequest(t, "PATCH", server.URL+"/pebs/abc1", "", `{"title":"Cycle","blocked-by":["peb-abc2"]}`)
+	if status != http.StatusBadRequest {
+		t.Errorf("PATCH with a cycle = %d: %s", status, body)
+	}
+	if _, body := doRequest(t, "GET", server.URL+"/pebs/abc1", "", ""); !strings.Contains(body, `"title":"First"`) || strings.Contains(body, "blocked-by") {
+		t.Errorf("peb changed by a rejected update: %s", body)
+	}
+
+	duplicate := peb.New("peb-abc1", "Duplicate", peb.TypeTask, peb.StatusNew, "")
+	if err := peb.WriteFile(pebblesDir, duplicate); err != nil {
+		t.Fatal(err)
+	}
+	if status, body := doRequest(t, "DELETE", server.URL+"/pebs/peb-abc1", "", ""); status != http.StatusConflict || !strings.Contains(body, "peb dedupe") {
+		t.Errorf("DELETE duplicate ID = %d: %s", status, body)
+	}
+}
+
+func TestServeAPIToken(t *testing.T) {
+	pebblesDir, _, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	server := startAPI(t, pebblesDir, "secret")
+
+	if status, _ := doRequest(t, "GET", server.URL+"/pebs", "", ""); status != http.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got %d", status)
+	}
+	if status, _ := doRequest(t, "GET", server.URL+"/pebs", "wrong", ""); status != http.StatusUnauthorized {
+		t.Errorf("expected 401 with wrong token, got %d", status)
+	}
+	if status, _ := doRequest(t, "GET", server.URL+"/pebs", "secret", ""); status != http.StatusOK {
+		t.Errorf("expected 200 with token, got %d", status)
+	}
+}
+
+func TestServeAPIRejectsCrossSiteRequests(t *testing.T) {
+	pebblesDir, _, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	server := startAPI(t, pebblesDir, "")
+
+	// A form post from another site, without a JSON content type.
+	req, err := http.NewRequest("POST", server.URL+"/pebs", strings.NewReader(`{"title":"x","content":"y"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("expected 415 for text/plain, got %d", resp.StatusCode)
+	}
+
+	// A DNS rebinding attack reaches the server under a foreign host name.
+	req, err = http.NewRequest("GET", server.URL+"/pebs", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Host = "attacker.example:7474"
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 for a foreign host, got %d", resp.StatusCode)
+	}
+}
+
+func TestServeAPIPostHookFailure(t *testing.T) {
+	pebblesDir, _, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	config := `prefix = "peb"
+id_length = 4
+
+[hooks]
+on_create = 'exit 1'
+fail_on_error = true
+`
+	if err := os.WriteFile(filepath.Join(pebblesDir, "config.toml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	server := startAPI(t, pebblesDir, "")
+
+	status, body := doRequest(t, "POST", server.URL+"/pebs", "", `{"title":"Created","content":"x"}`)
+	if status != http.StatusInternalServerError {
+		t.Errorf("expected 500 for a failing post-hook, got %d: %s", status, body)
+	}
+	// The peb was created all the same.
+	status, body = doRequest(t, "GET", server.URL+"/pebs", "", "")
+	if status != http.StatusOK || !strings.Contains(body, `"title":"Created"`) {
+		t.Errorf("expected the peb to exist, got %d: %s", status, body)
+	}
+}
+
+func TestIsLoopbackHost(t *testing.T) {
+	tests := map[string]bool{
+		"127.0.0.1:7474":  true,
+		"localhost":       true,
+		"LOCALHOST:7474":  true,
+		"[::1]:8080":      true,
+		"[::1]":           true,
+		"example.com":     false,
+		"10.0.0.1:7474":   false,
+		"localhost.evil:": false,
+	}
+	for host, want := range tests {
+		if got := isLoopbackHost(host); got != want {
+			t.Errorf("isLoopbackHost(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestIsLoopbackAddr(t *testing.T) {
+	tests := map[string]bool{
+		"127.0.0.1:7474": true,
+		"localhost:80":   true,
+		"[::1]:8080":     true,
+		"0.0.0.0:8080":   false,
+		":8080":          false,
+		"10.0.0.1:8080":  false,
+		"invalid":        false,
+	}
+	for addr, want := range tests {
+		if got := isLoopbackAddr(addr); got != want {
+			t.Errorf("isLoopbackAddr(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
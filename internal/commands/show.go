@@ -0,0 +1,188 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"go.yozora.eu/pebbles/internal/config"
+	"go.yozora.eu/pebbles/internal/peb"
+	"go.yozora.eu/pebbles/internal/store"
+	"go.yozora.eu/pebbles/internal/term"
+)
+
+const progressWidth = 20
+
+func ShowCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "show",
+		Usage:     "Display a peb for reading in a terminal",
+		ArgsUsage: "<id>...",
+		Description: `Display one or more pebs for people rather than programs: a header with the
+status and type, the rendered markdown content, the pebs it is blocked by
+and the pebs it blocks with their titles and statuses, and the progress of
+the epic it belongs to.
+
+Output is colored when it goes to a terminal, unless NO_COLOR is set or TERM
+is "dumb". In a pipe, the markup is still rendered, without colors.
+
+Use "peb read" for the fields as JSON.
+
+Examples:
+  peb show peb-xxxx
+  peb show peb-xxxx | less -R`,
+		Action: func(c *cli.Context) error {
+			if c.NArg() < 1 {
+				return fmt.Errorf("at least one peb ID is required")
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			if err := config.MaybeUpdatePlugin(cfg); err != nil {
+				return fmt.Errorf("failed to update plugin: %w", err)
+			}
+
+			s := store.New(cfg.PebblesDir(), cfg.Prefix)
+			if err := s.Load(); err != nil {
+				return err
+			}
+
+			var pebs []*peb.Peb
+			for _, id := range c.Args().Slice() {
+				p, err := getPeb(s, id)
+				if err != nil {
+					return err
+				}
+				pebs = append(pebs, p)
+			}
+
+			st := term.Styles{Color: term.ColorEnabled(c.App.Writer)}
+			for i, p := range pebs {
+				if i > 0 {
+					fmt.Fprintln(c.App.Writer)
+				}
+				fmt.Fprint(c.App.Writer, renderPeb(s, p, st))
+			}
+			return nil
+		},
+	}
+}
+
+func renderPeb(s *store.Store, p *peb.Peb, st term.Styles) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s  %s\n", st.Bold(p.ID), st.Bold(p.Title))
+	fmt.Fprintf(&b, "%s %s  %s\n", statusBadge(st, p.Status), typeBadge(st, p.Type), st.Dim(pebDates(p)))
+	if p.Source != "" {
+		fmt.Fprintf(&b, "%s\n", st.Dim("Source: "+p.Source))
+	}
+	if len(p.Commits) > 0 {
+		fmt.Fprintf(&b, "%s\n", st.Dim("Commits: "+strings.Join(p.Commits, ", ")))
+	}
+
+	if content := strings.TrimSpace(p.Content); content != "" {
+		b.WriteString("\n" + term.Markdown(content, st))
+	}
+
+	if len(p.BlockedBy) > 0 {
+		done := 0
+		for _, id := range p.BlockedBy {
+			if blocker, ok := s.Get(id); ok && peb.IsClosed(blocker.Status) {
+				done++
+			}
+		}
+		fmt.Fprintf(&b, "\n%s %s\n", st.Bold("Blocked by"), st.Dim(fmt.Sprintf("(%d of %d done)", done, len(p.BlockedBy))))
+		for _, id := range p.BlockedBy {
+			b.WriteString(relatedLine(s, id, st))
+		}
+	}
+
+	if blocks := s.Blocks(p.ID); len(blocks) > 0 {
+		fmt.Fprintf(&b, "\n%s\n", st.Bold("Blocks"))
+		for _, id := range blocks {
+			b.WriteString(relatedLine(s, id, st))
+		}
+	}
+
+	if epic := parentEpic(s, p); epic != nil {
+		done, total := epicProgress(s, epic)
+		fmt.Fprintf(&b, "\n%s %s %s\n", st.Bold("Epic"), epic.ID, epic.Title)
+		fmt.Fprintf(&b, "  %s %d of %d done (%d%%)\n", progressBar(st, done, total), done, total, done*100/total)
+	}
+	return b.String()
+}
+
+func pebDates(p *peb.Peb) string {
+	dates := "created " + showTimestamp(p.Created)
+	if p.Changed != "" && p.Changed != p.Created {
+		dates += ", changed " + showTimestamp(p.Changed)
+	}
+	return dates
+}
+
+func showTimestamp(ts string) string {
+	t, err := peb.ParseTimestamp(ts)
+	if err != nil {
+		return ts
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+func relatedLine(s *store.Store, id string, st term.Styles) string {
+	p, ok := s.Get(id)
+	if !ok {
+		return fmt.Sprintf("  %s %s %s\n", st.Fg(term.Red, "?"), id, st.Dim("(not found)"))
+	}
+	mark := st.Fg(term.Yellow, "○")
+	switch p.Status {
+	case peb.StatusFixed:
+		mark = st.Fg(term.Green, "✓")
+	case peb.StatusWontFix:
+		mark = st.Fg(term.Gray, "✗")
+	}
+	return fmt.Sprintf("  %s %s %s %s\n", mark, id, statusBadge(st, p.Status), p.Title)
+}
+
+func epicProgress(s *store.Store, epic *peb.Peb) (done, total int) {
+	for _, id := range epic.BlockedBy {
+		total++
+		if p, ok := s.Get(id); ok && peb.IsClosed(p.Status) {
+			done++
+		}
+	}
+	return done, total
+}
+
+// progressBar requires a total that is not 0.
+func progressBar(st term.Styles, done, total int) string {
+	filled := done * progressWidth / total
+	return st.Fg(term.Green, strings.Repeat("█", filled)) + st.Dim(strings.Repeat("░", progressWidth-filled))
+}
+
+func statusBadge(st term.Styles, status peb.Status) string {
+	color := term.Gray
+	switch status {
+	case peb.StatusNew:
+		color = term.Blue
+	case peb.StatusInProgress:
+		color = term.Yellow
+	case peb.StatusFixed:
+		color = term.Green
+	}
+	return st.Badge(color, string(status))
+}
+
+func typeBadge(st term.Styles, t peb.Type) string {
+	color := term.Gray
+	switch t {
+	case peb.TypeBug:
+		color = term.Red
+	case peb.TypeFeature:
+		color = term.Magenta
+	case peb.TypeEpic:
+		color = term.Cyan
+	}
+	return st.Badge(color, string(t))
+}
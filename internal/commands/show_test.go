@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+	"go.yozora.eu/pebbles/internal/peb"
+)
+
+func runShow(args ...string) (string, error) {
+	var out bytes.Buffer
+	app := &cli.App{
+		Name:     "peb",
+		Writer:   &out,
+		Commands: []*cli.Command{ShowCommand()},
+	}
+	err := app.Run(append([]string{"peb", "show"}, args...))
+	return out.String(), err
+}
+
+func TestShowCommand(t *testing.T) {
+	pebblesDir, s, cleanup := setupTestStore(t)
+	defer cleanup()
+	t.Chdir(pebblesDir)
+
+	save := func(p *peb.Peb) {
+		t.Helper()
+		if err := s.Save(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	save(peb.New("peb-aaaa", "Write the parser", peb.TypeTask, peb.StatusFixed, "Done."))
+	save(peb.New("peb-bbbb", "Design the format", peb.TypeTask, peb.StatusNew, "Todo."))
+	p := peb.New("peb-cccc", "Render the output", peb.TypeFeature, peb.StatusInProgress, "## Steps\n\n- **bold** step\n- `code`")
+	p.Created = "2026-10-19T10:00:00+02:00"
+	p.Changed = p.Created
+	p.BlockedBy = []string{"peb-aaaa", "peb-bbbb"}
+	save(p)
+	epic := peb.New("peb-dddd", "Output epic", peb.TypeEpic, peb.StatusNew, "All of it.")
+	epic.BlockedBy = []string{"peb-aaaa", "peb-cccc"}
+	save(epic)
+
+	out, err := runShow("peb-cccc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "peb-cccc  Render the output\n" +
+		"[in-progress] [feature]  created 2026-10-19 10:00\n" +
+		"\n" +
+		"Steps\n" +
+		"\n" +
+		"• bold step\n" +
+		"• code\n" +
+		"\n" +
+		"Blocked by (1 of 2 done)\n" +
+		"  ✓ peb-aaaa [fixed] Write the parser\n" +
+		"  ○ peb-bbbb [new] Design the format\n" +
+		"\n" +
+		"Blocks\n" +
+		"  ○ peb-dddd [new] Output epic\n" +
+		"\n" +
+		"Epic peb-dddd Output epic\n" +
+		"  ██████████░░░░░░░░░░ 1 of 2 done (50%)\n"
+	if out != want {
+		t.Errorf("output =\n%s\nwant\n%s", out, want)
+	}
+	if strings.Contains(out, "\x1b[") {
+		t.Errorf("output to a buffer is colored: %q", out)
+	}
+}
+
+func TestShowCommandMultiple(t *testing.T) {
+	pebblesDir, s, cleanup := setupTestStore(t)
+	defer cleanup()
+	t.Chdir(pebblesDir)
+
+	for _, id := range []string{"peb-aaaa", "peb-bbbb"} {
+		if err := s.Save(peb.New(id, "Title "+id, peb.TypeBug, peb.StatusNew, "Content.")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, err := runShow("peb-aaaa", "peb-bbbb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Content.\n\npeb-bbbb  Title peb-bbbb\n") {
+		t.Errorf("pebs are not separated by a blank line:\n%s", out)
+	}
+
+	if _, err := runShow("peb-zzzz"); err == nil {
+		t.Error("expected an error for an unknown peb")
+	}
+}
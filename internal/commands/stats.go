@@ -0,0 +1,260 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"go.yozora.eu/pebbles/internal/config"
+	"go.yozora.eu/pebbles/internal/peb"
+	"go.yozora.eu/pebbles/internal/store"
+)
+
+type Stats struct {
+	Total             int            `json:"total"`
+	ByStatus          map[string]int `json:"by-status"`
+	ByType            map[string]int `json:"by-type"`
+	ByLabel           map[string]int `json:"by-label,omitempty"`
+	ByPriority        map[string]int `json:"by-priority,omitempty"`
+	Ready             int            `json:"ready"`
+	Blocked           int            `json:"blocked"`
+	OldestOpen        *peb.PebJSON   `json:"oldest-open,omitempty"`
+	MedianTimeToClose string         `json:"median-time-to-close,omitempty"`
+}
+
+var groupByKeys = []string{"status", "type", "label", "priority"}
+
+func StatsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "stats",
+		Usage: "Show aggregated counts over pebs",
+		Description: `Show counts by status and type, the number of ready and blocked pebs,
+the oldest open peb and the median time from creation to close. Counts by
+label and priority are added if any peb has the frontmatter key "labels" or
+"priority".
+
+Accepts the same filters as "peb query". An open peb is ready when none of
+its blockers is open, and blocked otherwise. The time to close is measured
+from the created to the changed timestamp of closed pebs.
+
+Examples:
+  peb stats                   Stats over all pebs
+  peb stats type:bug          Stats over bugs only
+  peb stats --table           Print a table instead of JSON`,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "table",
+				Usage: "Print a human-readable table instead of JSON",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			if err := config.MaybeUpdatePlugin(cfg); err != nil {
+				return fmt.Errorf("failed to update plugin: %w", err)
+			}
+
+			s := store.New(cfg.PebblesDir(), cfg.Prefix)
+			if err := s.Load(); err != nil {
+				return err
+			}
+			warnInvalid(c.App.ErrWriter, s, cfg.Prefix)
+
+			args, err := expandSavedQueries(c.Args().Slice(), cfg.Queries)
+			if err != nil {
+				return err
+			}
+
+			filters, err := parseFilters(args, s.Resolve)
+			if err != nil {
+				return err
+			}
+
+			var pebs []*peb.Peb
+			for _, p := range s.All() {
+				if applyFilters(p, filters) {
+					pebs = append(pebs, p)
+				}
+			}
+
+			stats := computeStats(s, pebs)
+
+			if c.Bool("table") {
+				return writeStatsTable(c.App.Writer, stats)
+			}
+
+			encoder := json.NewEncoder(c.App.Writer)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(stats); err != nil {
+				return fmt.Errorf("failed to encode stats: %w", err)
+			}
+			return nil
+		},
+	}
+}
+
+func computeStats(s *store.Store, pebs []*peb.Peb) *Stats {
+	stats := &Stats{
+		Total:    len(pebs),
+		ByStatus: make(map[string]int),
+		ByType:   make(map[string]int),
+	}
+	count := func(counts *map[string]int, values []string) {
+		for _, value := range values {
+			if *counts == nil {
+				*counts = make(map[string]int)
+			}
+			(*counts)[value]++
+		}
+	}
+
+	var oldest *peb.Peb
+	var oldestCreated time.Time
+	var closeDurations []time.Duration
+
+	for _, p := range pebs {
+		stats.ByStatus[string(p.Status)]++
+		stats.ByType[string(p.Type)]++
+		count(&stats.ByLabel, groupValues(p, "label"))
+		count(&stats.ByPriority, groupValues(p, "priority"))
+
+		if peb.IsOpen(p.Status) {
+			if len(s.OpenBlockers(p)) > 0 {
+				stats.Blocked++
+			} else {
+				stats.Ready++
+			}
+			created, err := peb.ParseTimestamp(p.Created)
+			if err == nil && (oldest == nil || created.Before(oldestCreated)) {
+				oldest = p
+				oldestCreated = created
+			}
+		}
+
+		if peb.IsClosed(p.Status) {
+			created, err1 := peb.ParseTimestamp(p.Created)
+			changed, err2 := peb.ParseTimestamp(p.Changed)
+			if err1 == nil && err2 == nil && !changed.Before(created) {
+				closeDurations = append(closeDurations, changed.Sub(created))
+			}
+		}
+	}
+
+	if oldest != nil {
+		stats.OldestOpen = &peb.PebJSON{
+			ID:      oldest.ID,
+			Type:    oldest.Type,
+			Status:  oldest.Status,
+			Title:   oldest.Title,
+			Created: oldest.Created,
+		}
+	}
+
+	if len(closeDurations) > 0 {
+		stats.MedianTimeToClose = median(closeDurations).Round(time.Second).String()
+	}
+
+	return stats
+}
+
+func median(durations []time.Duration) time.Duration {
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// countBy counts a peb with several labels once for each of them, and pebs
+// without a label or priority not at all.
+func countBy(pebs []*peb.Peb, key string) (map[string]int, error) {
+	if !slices.Contains(groupByKeys, key) {
+		return nil, fmt.Errorf("unknown group-by key: %s (valid keys: %v)", key, groupByKeys)
+	}
+	counts := make(map[string]int)
+	for _, p := range pebs {
+		for _, value := range groupValues(p, key) {
+			counts[value]++
+		}
+	}
+	return counts, nil
+}
+
+func groupValues(p *peb.Peb, key string) []string {
+	switch key {
+	case "status":
+		return []string{string(p.Status)}
+	case "type":
+		return []string{string(p.Type)}
+	case "label":
+		return p.Extra.Strings(peb.LabelsKey)
+	}
+	return p.Extra.Strings(peb.PriorityKey)
+}
+
+func writeStatsTable(w io.Writer, stats *Stats) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Total\t%d\n", stats.Total)
+	fmt.Fprintf(tw, "Ready\t%d\n", stats.Ready)
+	fmt.Fprintf(tw, "Blocked\t%d\n", stats.Blocked)
+
+	fmt.Fprintln(tw, "\nStatus\tCount")
+	for _, key := range sortedKeys(stats.ByStatus) {
+		fmt.Fprintf(tw, "%s\t%d\n", key, stats.ByStatus[key])
+	}
+
+	fmt.Fprintln(tw, "\nType\tCount")
+	for _, key := range sortedKeys(stats.ByType) {
+		fmt.Fprintf(tw, "%s\t%d\n", key, stats.ByType[key])
+	}
+
+	if len(stats.ByLabel) > 0 {
+		fmt.Fprintln(tw, "\nLabel\tCount")
+		for _, key := range sortedKeys(stats.ByLabel) {
+			fmt.Fprintf(tw, "%s\t%d\n", key, stats.ByLabel[key])
+		}
+	}
+
+	if len(stats.ByPriority) > 0 {
+		fmt.Fprintln(tw, "\nPriority\tCount")
+		for _, key := range sortedKeys(stats.ByPriority) {
+			fmt.Fprintf(tw, "%s\t%d\n", key, stats.ByPriority[key])
+		}
+	}
+
+	fmt.Fprintln(tw)
+	if stats.OldestOpen != nil {
+		fmt.Fprintf(tw, "Oldest open\t%s %s (%s)\n", stats.OldestOpen.ID, stats.OldestOpen.Title, stats.OldestOpen.Created)
+	} else {
+		fmt.Fprintln(tw, "Oldest open\t-")
+	}
+	if stats.MedianTimeToClose != "" {
+		fmt.Fprintf(tw, "Median time to close\t%s\n", stats.MedianTimeToClose)
+	} else {
+		fmt.Fprintln(tw, "Median time to close\t-")
+	}
+
+	return tw.Flush()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,187 @@
+package commands
+
+import (
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+	"go.yozora.eu/pebbles/internal/peb"
+)
+
+func TestStatsCommand(t *testing.T) {
+	pebblesDir, s, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	t.Chdir(pebblesDir)
+
+	blocker := peb.New("peb-aaaa", "Blocker", peb.TypeTask, peb.StatusInProgress, "Content")
+	blocker.Created = "2026-01-01T10:00:00+00:00"
+	if err := s.Save(blocker); err != nil {
+		t.Fatal(err)
+	}
+
+	blocked := peb.New("peb-bbbb", "Blocked", peb.TypeBug, peb.StatusNew, "Content")
+	blocked.Created = "2026-01-02T10:00:00+00:00"
+	blocked.BlockedBy = []string{"peb-aaaa"}
+	if err := s.Save(blocked); err != nil {
+		t.Fatal(err)
+	}
+
+	fixed1 := peb.New("peb-cccc", "Fixed 1", peb.TypeBug, peb.StatusFixed, "Content")
+	fixed1.Created = "2026-01-01T00:00:00+00:00"
+	fixed1.Changed = "2026-01-01T02:00:00+00:00"
+	if err := s.Save(fixed1); err != nil {
+		t.Fatal(err)
+	}
+
+	fixed2 := peb.New("peb-dddd", "Fixed 2", peb.TypeFeature, peb.StatusWontFix, "Content")
+	fixed2.Created = "2026-01-01T00:00:00+00:00"
+	fixed2.Changed = "2026-01-01T04:00:00+00:00"
+	if err := s.Save(fixed2); err != nil {
+		t.Fatal(err)
+	}
+
+	app := &cli.App{
+		Commands: []*cli.Command{StatsCommand()},
+	}
+
+	r, w, _ := os.Pipe()
+	oldStdout := os.Stdout
+	os.Stdout = w
+
+	err := app.Run([]string{"peb", "stats"})
+	if err != nil {
+		w.Close()
+		os.Stdout = oldStdout
+		t.Fatalf("command failed: %v", err)
+	}
+
+	w.Close()
+	os.Stdout = oldStdout
+
+	var stats Stats
+	if err := json.NewDecoder(r).Decode(&stats); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+
+	if stats.Total != 4 {
+		t.Errorf("expected total 4, got %d", stats.Total)
+	}
+	if stats.ByStatus["new"] != 1 || stats.ByStatus["in-progress"] != 1 || stats.ByStatus["fixed"] != 1 || stats.ByStatus["wont-fix"] != 1 {
+		t.Errorf("unexpected status counts: %v", stats.ByStatus)
+	}
+	if stats.ByType["bug"] != 2 || stats.ByType["task"] != 1 || stats.ByType["feature"] != 1 {
+		t.Errorf("unexpected type counts: %v", stats.ByType)
+	}
+	if stats.Ready != 1 {
+		t.Errorf("expected 1 ready peb, got %d", stats.Ready)
+	}
+	if stats.Blocked != 1 {
+		t.Errorf("expected 1 blocked peb, got %d", stats.Blocked)
+	}
+	if stats.OldestOpen == nil || stats.OldestOpen.ID != "peb-aaaa" {
+		t.Errorf("expected oldest open peb-aaaa, got %+v", stats.OldestOpen)
+	}
+	if stats.MedianTimeToClose != "3h0m0s" {
+		t.Errorf("expected median time to close 3h0m0s, got %q", stats.MedianTimeToClose)
+	}
+}
+
+func TestStatsCommandWithFilter(t *testing.T) {
+	pebblesDir, s, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	t.Chdir(pebblesDir)
+
+	if err := s.Save(peb.New("peb-aaaa", "Bug", peb.TypeBug, peb.StatusNew, "Content")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save(peb.New("peb-bbbb", "Task", peb.TypeTask, peb.StatusNew, "Content")); err != nil {
+		t.Fatal(err)
+	}
+
+	app := &cli.App{
+		Commands: []*cli.Command{StatsCommand()},
+	}
+
+	r, w, _ := os.Pipe()
+	oldStdout := os.Stdout
+	os.Stdout = w
+
+	err := app.Run([]string{"peb", "stats", "type:bug"})
+	w.Close()
+	os.Stdout = oldStdout
+	if err != nil {
+		t.Fatalf("command failed: %v", err)
+	}
+
+	var stats Stats
+	if err := json.NewDecoder(r).Decode(&stats); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+
+	if stats.Total != 1 || stats.ByType["bug"] != 1 {
+		t.Errorf("expected only the bug to be counted, got %+v", stats)
+	}
+	if stats.MedianTimeToClose != "" {
+		t.Errorf("expected no median time to close, got %q", stats.MedianTimeToClose)
+	}
+}
+
+func TestStatsCommandTable(t *testing.T) {
+	pebblesDir, s, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	t.Chdir(pebblesDir)
+
+	if err := s.Save(peb.New("peb-aaaa", "Bug", peb.TypeBug, peb.StatusNew, "Content")); err != nil {
+		t.Fatal(err)
+	}
+
+	output := runCommand([]string{"stats", "--table"})
+
+	for _, want := range []string{"Total", "Ready", "Blocked", "Status", "Type", "bug", "Oldest open", "peb-aaaa"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected table output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestStatsCommandLabelsAndPriority(t *testing.T) {
+	pebblesDir, _, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	t.Chdir(pebblesDir)
+
+	files := map[string]string{
+		"peb-aaaa--ui.md":    "labels: [ui, p1]\npriority: high\n",
+		"peb-bbbb--docs.md":  "labels: [docs]\npriority: high\n",
+		"peb-cccc--plain.md": "",
+	}
+	for name, extra := range files {
+		data := "---\nid: " + name[:8] + "\ntitle: Title\ntype: bug\nstatus: new\ncreated: \"2026-01-01T00:00:00+00:00\"\nchanged: \"2026-01-01T00:00:00+00:00\"\n" + extra + "---\n"
+		if err := os.WriteFile(filepath.Join(pebblesDir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	output := runCommand([]string{"stats"})
+	var stats Stats
+	if err := json.Unmarshal([]byte(output), &stats); err != nil {
+		t.Fatalf("failed to parse JSON %q: %v", output, err)
+	}
+	if !maps.Equal(stats.ByLabel, map[string]int{"ui": 1, "p1": 1, "docs": 1}) {
+		t.Errorf("unexpected label counts: %v", stats.ByLabel)
+	}
+	if !maps.Equal(stats.ByPriority, map[string]int{"high": 2}) {
+		t.Errorf("unexpected priority counts: %v", stats.ByPriority)
+	}
+
+	if output, err := runInProcess("", "query", "--group-by", "label"); err != nil || output != `{"docs":1,"p1":1,"ui":1}` {
+		t.Errorf("group by label = %q, %v", output, err)
+	}
+}
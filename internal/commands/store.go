@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"go.yozora.eu/pebbles/internal/config"
+	"go.yozora.eu/pebbles/internal/hooks"
+	"go.yozora.eu/pebbles/internal/store"
+)
+
+// Commands that modify pebs use openStore instead of store.New, so that
+// hooks run.
+func openStore(cfg *config.Config) (*store.Store, error) {
+	s := store.New(cfg.PebblesDir(), cfg.Prefix)
+	runner, err := hooks.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if runner != nil {
+		s.SetHooks(runner)
+	}
+	if err := s.Load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// warnInvalid keeps pebs that cannot be listed from just vanishing.
+func warnInvalid(w io.Writer, s *store.Store, prefix string) {
+	if foreign := s.Foreign(); len(foreign) > 0 {
+		fmt.Fprintf(w, "Warning: ignored %d .md file(s) without the prefix %q, e.g. %s", len(foreign), prefix+"-", foreign[0])
+		if old := commonPrefix(foreign); old != "" {
+			fmt.Fprintf(w, " (run \"peb reprefix --from %s %s\" to rename them)", old, prefix)
+		}
+		fmt.Fprintln(w)
+	}
+	for _, err := range s.Invalid() {
+		var dup *store.DuplicateError
+		if errors.As(err, &dup) {
+			fmt.Fprintf(w, "Warning: skipped %v%s\n", err, dedupeHint)
+			continue
+		}
+		fmt.Fprintf(w, "Warning: skipped %v\n", err)
+	}
+}
+
+const dedupeHint = ` (run "peb dedupe" to give the pebs unique IDs)`
@@ -0,0 +1,658 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/urfave/cli/v2"
+	"go.yozora.eu/pebbles/internal/config"
+	"go.yozora.eu/pebbles/internal/hooks"
+	"go.yozora.eu/pebbles/internal/peb"
+	"go.yozora.eu/pebbles/internal/store"
+	"go.yozora.eu/pebbles/internal/term"
+	"go.yozora.eu/pebbles/internal/watch"
+)
+
+const defaultTUIQuery = "status:open"
+
+const tuiHelp = "j/k move  / query  n/i/f/w status  e edit  b/u block/unblock  d delete  q quit"
+
+var tuiStatusKeys = map[term.Key]peb.Status{
+	"n": peb.StatusNew,
+	"i": peb.StatusInProgress,
+	"f": peb.StatusFixed,
+	"w": peb.StatusWontFix,
+}
+
+func TUICommand() *cli.Command {
+	return &cli.Command{
+		Name:      "tui",
+		Usage:     "Browse and triage pebs in a full-screen terminal interface",
+		ArgsUsage: "[filters]",
+		Description: `Show the pebs matching a query in a list, with the selected peb below it as
+"peb show" displays it. The list starts with the given filters, in the syntax
+of "peb query", or with "status:open". It is refreshed when files in
+.pebbles/ change, e.g. by an agent working in another terminal.
+
+Keys:
+  j/k, up/down     Select the next/previous peb
+  g/G, home/end    Select the first/last peb
+  pgup/pgdown      Scroll the selected peb
+  /                Change the query (enter applies it, esc cancels)
+  n, i, f, w       Set the status to new, in-progress, fixed or wont-fix
+  e                Edit the peb in $VISUAL or $EDITOR, like "peb edit"
+  b                Add a peb to the blocked-by list
+  u                Remove a peb from the blocked-by list
+  d                Delete the peb, after confirming with y
+  r                Reload all pebs
+  q, ctrl-c        Quit
+
+Changes are checked and run hooks like "peb update" and "peb delete".
+
+Examples:
+  peb tui
+  peb tui type:bug status:new
+  peb tui @triage`,
+		Action: func(c *cli.Context) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			if err := config.MaybeUpdatePlugin(cfg); err != nil {
+				return fmt.Errorf("failed to update plugin: %w", err)
+			}
+
+			in, inOK := c.App.Reader.(*os.File)
+			out, outOK := c.App.Writer.(*os.File)
+			if !inOK || !outOK || !term.IsTerminal(in) || !term.IsTerminal(out) {
+				return errors.New(`peb tui needs a terminal; use "peb query" in scripts`)
+			}
+
+			// Hook output would garble the screen.
+			var hookOutput bytes.Buffer
+			s, err := openTUIStore(cfg, &hookOutput)
+			if err != nil {
+				return err
+			}
+
+			query := strings.Join(c.Args().Slice(), " ")
+			if c.NArg() == 0 {
+				query = defaultTUIQuery
+			}
+			m, err := newTUIModel(s, cfg, term.Styles{Color: term.ColorEnabled(out)}, query, &hookOutput)
+			if err != nil {
+				return err
+			}
+
+			w, err := watch.New(cfg.PebblesDir(), cfg.Prefix, watch.Options{})
+			if err != nil {
+				return fmt.Errorf("failed to watch pebbles directory: %w", err)
+			}
+
+			return runTUI(c, m, in, out, w)
+		},
+	}
+}
+
+func openTUIStore(cfg *config.Config, output io.Writer) (*store.Store, error) {
+	s := store.New(cfg.PebblesDir(), cfg.Prefix)
+	runner, err := hooks.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if runner != nil {
+		runner.Output = output
+		s.SetHooks(runner)
+	}
+	if err := s.Load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func runTUI(c *cli.Context, m *tuiModel, in, out *os.File, w *watch.Watcher) error {
+	screen, err := term.OpenScreen(in, out)
+	if err != nil {
+		return fmt.Errorf("failed to set up terminal: %w", err)
+	}
+	defer screen.Close()
+
+	ctx, cancel := context.WithCancel(c.Context)
+	defer cancel()
+	changed := make(chan struct{}, 1)
+	go w.Run(ctx, func(watch.Event) error {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+		return nil
+	})
+
+	resized := make(chan os.Signal, 1)
+	term.NotifyResize(resized)
+	defer signal.Stop(resized)
+	resize := func() error {
+		width, height, err := screen.Size()
+		if err != nil {
+			return err
+		}
+		m.resize(width, height)
+		return nil
+	}
+	if err := resize(); err != nil {
+		return err
+	}
+
+	for {
+		if err := screen.Draw(m.view()); err != nil {
+			return err
+		}
+		keys, err := screen.ReadKeys()
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			switch m.handleKey(k) {
+			case tuiQuit:
+				return nil
+			case tuiEdit:
+				p := m.selected()
+				if err := screen.Suspend(); err != nil {
+					return err
+				}
+				updated, editErr := editPeb(c, m.s, p)
+				if err := screen.Resume(); err != nil {
+					return err
+				}
+				m.edited(p, updated, editErr)
+			}
+		}
+		select {
+		case <-changed:
+			m.reload()
+		case <-resized:
+			if err := resize(); err != nil {
+				return err
+			}
+		default:
+		}
+	}
+}
+
+type tuiAction int
+
+const (
+	tuiContinue tuiAction = iota
+	tuiQuit
+	// tuiEdit asks runTUI to open the editor and report back with edited.
+	tuiEdit
+)
+
+type tuiMode int
+
+const (
+	tuiBrowse tuiMode = iota
+	tuiQuery
+	tuiBlock
+	tuiUnblock
+	tuiConfirmDelete
+)
+
+var tuiPrompts = map[tuiMode]string{
+	tuiQuery:   "Query: ",
+	tuiBlock:   "Blocked by: ",
+	tuiUnblock: "Remove blocker: ",
+}
+
+// tuiModel doesn't touch the terminal, so it can be tested without one.
+type tuiModel struct {
+	s          *store.Store
+	cfg        *config.Config
+	styles     term.Styles
+	hookOutput *bytes.Buffer
+
+	width  int
+	height int
+
+	query   string
+	filters []filterFunc
+	matches []*peb.Peb
+	cursor  int
+	top     int
+	scroll  int // first line shown of the selected peb
+
+	mode tuiMode
+	// target stays the same if the list changes meanwhile.
+	target  string
+	input   string
+	message string
+	failed  bool
+}
+
+func newTUIModel(s *store.Store, cfg *config.Config, styles term.Styles, query string, hookOutput *bytes.Buffer) (*tuiModel, error) {
+	m := &tuiModel{s: s, cfg: cfg, styles: styles, hookOutput: hookOutput, width: 80, height: 24}
+	if err := m.setQuery(query); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *tuiModel) resize(width, height int) {
+	m.width, m.height = width, height
+}
+
+func (m *tuiModel) setQuery(query string) error {
+	args, err := expandSavedQueries(splitFilters(query), m.cfg.Queries)
+	if err != nil {
+		return err
+	}
+	filters, err := parseFilters(args, m.s.Resolve)
+	if err != nil {
+		return err
+	}
+	m.query, m.filters = strings.Join(splitFilters(query), " "), filters
+	m.refresh()
+	return nil
+}
+
+func (m *tuiModel) reload() {
+	if err := m.s.Load(); err != nil {
+		m.fail(err)
+	}
+	m.refresh()
+}
+
+func (m *tuiModel) refresh() {
+	var selectedID string
+	if p := m.selected(); p != nil {
+		selectedID = p.ID
+	}
+
+	pebs := m.s.All()
+	sort.Slice(pebs, func(i, j int) bool {
+		return pebs[i].ID < pebs[j].ID
+	})
+	m.matches = m.matches[:0]
+	for _, p := range pebs {
+		if applyFilters(p, m.filters) {
+			m.matches = append(m.matches, p)
+		}
+	}
+
+	if i := slices.IndexFunc(m.matches, func(p *peb.Peb) bool { return p.ID == selectedID }); i >= 0 {
+		m.cursor = i
+	} else {
+		m.cursor = min(m.cursor, len(m.matches)-1)
+		m.scroll = 0
+	}
+	m.cursor = max(m.cursor, 0)
+
+	if m.mode == tuiBlock || m.mode == tuiUnblock || m.mode == tuiConfirmDelete {
+		m.targetPeb()
+	}
+}
+
+// targetPeb cancels the mode and returns nil if the peb no longer exists,
+// e.g. because it was deleted in another terminal.
+func (m *tuiModel) targetPeb() *peb.Peb {
+	p, ok := m.s.Get(m.target)
+	if !ok {
+		m.mode = tuiBrowse
+		m.fail(fmt.Errorf("peb %s no longer exists", m.target))
+		return nil
+	}
+	return p
+}
+
+func (m *tuiModel) selected() *peb.Peb {
+	if m.cursor < len(m.matches) {
+		return m.matches[m.cursor]
+	}
+	return nil
+}
+
+func (m *tuiModel) move(delta int) {
+	cursor := max(0, min(m.cursor+delta, len(m.matches)-1))
+	if cursor != m.cursor {
+		m.cursor, m.scroll = cursor, 0
+	}
+}
+
+func (m *tuiModel) inform(msg string) {
+	if out := strings.TrimSpace(m.hookOutput.String()); out != "" {
+		lines := strings.Split(out, "\n")
+		msg += " Hook: " + lines[len(lines)-1]
+	}
+	m.hookOutput.Reset()
+	m.message, m.failed = msg, false
+}
+
+func (m *tuiModel) fail(err error) {
+	m.hookOutput.Reset()
+	m.message, m.failed = strings.ReplaceAll(err.Error(), "\n", " "), true
+}
+
+func (m *tuiModel) handleKey(k term.Key) tuiAction {
+	switch m.mode {
+	case tuiBrowse:
+		return m.browseKey(k)
+	case tuiConfirmDelete:
+		if k == "y" {
+			m.deleteTarget()
+		} else {
+			m.mode = tuiBrowse
+			m.inform("Not deleted.")
+		}
+	default:
+		m.promptKey(k)
+	}
+	return tuiContinue
+}
+
+func (m *tuiModel) browseKey(k term.Key) tuiAction {
+	m.message, m.failed = "", false
+	p := m.selected()
+	switch k {
+	case "q", term.KeyCtrlC:
+		return tuiQuit
+	case "j", term.KeyDown:
+		m.move(1)
+	case "k", term.KeyUp:
+		m.move(-1)
+	case "g", term.KeyHome:
+		m.move(-len(m.matches))
+	case "G", term.KeyEnd:
+		m.move(len(m.matches))
+	case term.KeyPageDown:
+		m.scroll += max(1, m.detailHeight()-1)
+	case term.KeyPageUp:
+		m.scroll = max(0, m.scroll-max(1, m.detailHeight()-1))
+	case "/":
+		m.mode, m.input = tuiQuery, m.query
+	case "r":
+		m.reload()
+	case "n", "i", "f", "w":
+		if p != nil {
+			m.setStatus(p, tuiStatusKeys[k])
+		}
+	case "e":
+		if p != nil {
+			return tuiEdit
+		}
+	case "b":
+		if p != nil {
+			m.mode, m.target, m.input = tuiBlock, p.ID, ""
+		}
+	case "u":
+		if p != nil && len(p.BlockedBy) > 0 {
+			m.mode, m.target, m.input = tuiUnblock, p.ID, ""
+			if len(p.BlockedBy) == 1 {
+				m.input = p.BlockedBy[0]
+			}
+		} else if p != nil {
+			m.inform(fmt.Sprintf("%s is not blocked by any peb.", p.ID))
+		}
+	case "d":
+		if p != nil {
+			m.mode, m.target = tuiConfirmDelete, p.ID
+		}
+	}
+	return tuiContinue
+}
+
+func (m *tuiModel) promptKey(k term.Key) {
+	switch k {
+	case term.KeyEnter:
+		m.submit()
+	case term.KeyEscape, term.KeyCtrlC:
+		m.mode, m.message, m.failed = tuiBrowse, "", false
+	case term.KeyBackspace:
+		if _, size := utf8.DecodeLastRuneInString(m.input); size > 0 {
+			m.input = m.input[:len(m.input)-size]
+		}
+	case term.KeyCtrlU:
+		m.input = ""
+	default:
+		// Named keys are longer than a character and ignored.
+		if utf8.RuneCountInString(string(k)) == 1 {
+			m.input += string(k)
+		}
+	}
+}
+
+func (m *tuiModel) submit() {
+	input := strings.TrimSpace(m.input)
+	var err error
+	switch m.mode {
+	case tuiQuery:
+		err = m.setQuery(input)
+	case tuiBlock, tuiUnblock:
+		p := m.targetPeb()
+		if p == nil {
+			return
+		}
+		if input != "" && m.mode == tuiBlock {
+			err = m.block(p, input)
+		} else if input != "" {
+			err = m.unblock(p, input)
+		}
+	}
+	if err != nil {
+		m.fail(err)
+		return
+	}
+	m.mode = tuiBrowse
+}
+
+// update leaves p unchanged if input is invalid.
+func (m *tuiModel) update(p *peb.Peb, input UpdateInput) (*peb.Peb, error) {
+	updated := *p
+	if err := applyUpdate(m.s, m.cfg, &updated, input); err != nil {
+		return nil, err
+	}
+	m.refresh()
+	return &updated, nil
+}
+
+func (m *tuiModel) setStatus(p *peb.Peb, status peb.Status) {
+	if p.Status == status {
+		m.inform(fmt.Sprintf("%s is already %s.", p.ID, status))
+		return
+	}
+	value := string(status)
+	if _, err := m.update(p, UpdateInput{Status: &value}); err != nil {
+		m.fail(err)
+		return
+	}
+	m.inform(fmt.Sprintf("Updated status of %s to %s.", p.ID, status))
+}
+
+func (m *tuiModel) block(p *peb.Peb, id string) error {
+	blocker, err := getPeb(m.s, id)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(p.BlockedBy, blocker.ID) {
+		return fmt.Errorf("%s is already blocked by %s", p.ID, blocker.ID)
+	}
+	blockedBy := append(slices.Clone(p.BlockedBy), blocker.ID)
+	updated, err := m.update(p, UpdateInput{BlockedBy: &blockedBy})
+	if err != nil {
+		return err
+	}
+	m.inform(fmt.Sprintf("Updated blocked-by list of %s to %v.", p.ID, updated.BlockedBy))
+	return nil
+}
+
+func (m *tuiModel) unblock(p *peb.Peb, id string) error {
+	if !slices.Contains(p.BlockedBy, id) {
+		resolved, err := m.s.Resolve(id)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(p.BlockedBy, resolved) {
+			return fmt.Errorf("%s is not blocked by %s", p.ID, resolved)
+		}
+		id = resolved
+	}
+	blockedBy := slices.DeleteFunc(slices.Clone(p.BlockedBy), func(b string) bool { return b == id })
+	updated, err := m.update(p, UpdateInput{BlockedBy: &blockedBy})
+	if err != nil {
+		return err
+	}
+	if len(updated.BlockedBy) == 0 {
+		m.inform(fmt.Sprintf("Cleared blocked-by list of %s.", p.ID))
+	} else {
+		m.inform(fmt.Sprintf("Updated blocked-by list of %s to %v.", p.ID, updated.BlockedBy))
+	}
+	return nil
+}
+
+func (m *tuiModel) deleteTarget() {
+	p := m.targetPeb()
+	if p == nil {
+		return
+	}
+	m.mode = tuiBrowse
+	if err := m.s.Delete(p); err != nil {
+		m.fail(fmt.Errorf("failed to delete peb %s: %w", p.ID, err))
+		return
+	}
+	m.refresh()
+	m.inform(fmt.Sprintf("Deleted peb %s.", p.ID))
+}
+
+func (m *tuiModel) edited(p *peb.Peb, updated bool, err error) {
+	m.refresh()
+	switch {
+	case err != nil:
+		m.fail(err)
+	case updated:
+		m.inform(fmt.Sprintf("Updated %s.", p.ID))
+	default:
+		m.inform(fmt.Sprintf("No changes to %s.", p.ID))
+	}
+}
+
+// The rest of the screen is taken by the header, the rule between the panes
+// and the footer.
+func (m *tuiModel) listHeight() int {
+	return max(1, (m.height-3)*2/5)
+}
+
+func (m *tuiModel) detailHeight() int {
+	return max(1, m.height-3-m.listHeight())
+}
+
+func (m *tuiModel) view() []string {
+	st := m.styles
+	header := st.Bold("Query: ") + m.query
+	if m.query == "" {
+		header += st.Dim("(all pebs)")
+	}
+	header += st.Dim(fmt.Sprintf("  %d of %d pebs", len(m.matches), len(m.s.IDs())))
+	if invalid := len(m.s.Invalid()); invalid > 0 {
+		header += st.Fg(term.Yellow, fmt.Sprintf("  %d unreadable file(s) skipped", invalid))
+	}
+	lines := []string{header}
+
+	lines = append(lines, m.listLines()...)
+	lines = append(lines, st.Dim(strings.Repeat("─", m.width)))
+	lines = append(lines, m.detailLines()...)
+	lines = append(lines, m.footer())
+
+	if len(lines) > m.height {
+		lines = lines[:max(m.height, 0)]
+	}
+	for i, line := range lines {
+		lines[i] = term.Truncate(line, m.width)
+	}
+	return lines
+}
+
+func (m *tuiModel) listLines() []string {
+	st := m.styles
+	height := m.listHeight()
+	lines := make([]string, 0, height)
+	if len(m.matches) == 0 {
+		lines = append(lines, st.Dim("  No pebs match the query."))
+	}
+
+	// Keep the selected peb in view.
+	m.top = max(0, min(m.top, m.cursor, len(m.matches)-height))
+	if m.cursor >= m.top+height {
+		m.top = m.cursor - height + 1
+	}
+
+	idWidth := 0
+	for _, p := range m.matches {
+		idWidth = max(idWidth, len(p.ID))
+	}
+	statusWidth := term.Width(statusBadge(st, peb.StatusInProgress))
+	typeWidth := term.Width(typeBadge(st, peb.TypeFeature))
+	for i := m.top; i < len(m.matches) && i < m.top+height; i++ {
+		p := m.matches[i]
+		row := fmt.Sprintf("%-*s %s %s %s", idWidth, p.ID,
+			term.Pad(statusBadge(st, p.Status), statusWidth), term.Pad(typeBadge(st, p.Type), typeWidth), p.Title)
+		if i == m.cursor {
+			row = st.Reverse(term.Pad("▸ "+row, m.width))
+		} else {
+			row = "  " + row
+		}
+		lines = append(lines, row)
+	}
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+func (m *tuiModel) detailLines() []string {
+	height := m.detailHeight()
+	var lines []string
+	if p := m.selected(); p != nil {
+		for _, line := range strings.Split(strings.TrimRight(renderPeb(m.s, p, m.styles), "\n"), "\n") {
+			lines = append(lines, term.Wrap(line, m.width)...)
+		}
+	}
+	m.scroll = max(0, min(m.scroll, len(lines)-height))
+	lines = lines[m.scroll:min(len(lines), m.scroll+height)]
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+func (m *tuiModel) footer() string {
+	st := m.styles
+	message := m.message
+	if m.failed {
+		message = st.Fg(term.Red, message)
+	}
+	switch m.mode {
+	case tuiConfirmDelete:
+		if p, ok := m.s.Get(m.target); ok {
+			return st.Fg(term.Red, fmt.Sprintf("Delete %s %q? (y/n)", p.ID, p.Title))
+		}
+	case tuiQuery, tuiBlock, tuiUnblock:
+		prompt := st.Bold(tuiPrompts[m.mode]) + m.input + st.Reverse(" ")
+		if message != "" {
+			prompt += "  " + message
+		}
+		return prompt
+	}
+	if message != "" {
+		return message
+	}
+	return st.Dim(tuiHelp)
+}
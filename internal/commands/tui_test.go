@@ -0,0 +1,342 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.yozora.eu/pebbles/internal/config"
+	"go.yozora.eu/pebbles/internal/peb"
+	"go.yozora.eu/pebbles/internal/store"
+	"go.yozora.eu/pebbles/internal/term"
+)
+
+// newTestTUI returns a model over a store with three open pebs and a fixed
+// one, listing the open pebs.
+func newTestTUI(t *testing.T) (*tuiModel, *store.Store) {
+	t.Helper()
+	pebblesDir, s, cleanup := setupTestStore(t)
+	t.Cleanup(cleanup)
+	t.Chdir(pebblesDir)
+
+	for _, p := range []*peb.Peb{
+		peb.New("peb-aaaa", "First bug", peb.TypeBug, peb.StatusNew, "The first one."),
+		peb.New("peb-bbbb", "Second task", peb.TypeTask, peb.StatusInProgress, "The second one."),
+		peb.New("peb-cccc", "Third feature", peb.TypeFeature, peb.StatusNew, "The third one."),
+		peb.New("peb-dddd", "Done task", peb.TypeTask, peb.StatusFixed, "Done."),
+	} {
+		if err := s.Save(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := newTUIModel(s, cfg, term.Styles{}, defaultTUIQuery, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.resize(60, 20)
+	return m, s
+}
+
+func pressKeys(m *tuiModel, keys ...term.Key) tuiAction {
+	action := tuiContinue
+	for _, k := range keys {
+		action = m.handleKey(k)
+	}
+	return action
+}
+
+func typeText(m *tuiModel, text string) {
+	for _, r := range text {
+		m.handleKey(term.Key(string(r)))
+	}
+}
+
+func listedIDs(m *tuiModel) []string {
+	var ids []string
+	for _, p := range m.matches {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+func TestTUIView(t *testing.T) {
+	m, _ := newTestTUI(t)
+	m.resize(50, 12)
+
+	lines := m.view()
+	if len(lines) != 12 {
+		t.Fatalf("view has %d lines, want 12", len(lines))
+	}
+	for i, line := range lines {
+		if w := term.Width(line); w > 50 {
+			t.Errorf("line %d is %d cells wide: %q", i, w, line)
+		}
+	}
+	want := []string{
+		"Query: status:open  3 of 4 pebs",
+		"▸ peb-aaaa [new]         [bug]     First bug         ",
+		"  peb-bbbb [in-progress] [task]    Second task",
+		"  peb-cccc [new]         [feature] Third feature",
+		"──────────────────────────────────────────────────",
+		"peb-aaaa  First bug",
+	}
+	for i, line := range want {
+		if lines[i] != term.Truncate(line, 50) {
+			t.Errorf("line %d = %q, want %q", i, lines[i], line)
+		}
+	}
+	if !strings.Contains(strings.Join(lines, "\n"), "The first one.") {
+		t.Errorf("view doesn't show the selected peb:\n%s", strings.Join(lines, "\n"))
+	}
+	if lines[11] != term.Truncate(tuiHelp, 50) {
+		t.Errorf("footer = %q", lines[11])
+	}
+}
+
+func TestTUINavigation(t *testing.T) {
+	m, _ := newTestTUI(t)
+
+	pressKeys(m, "j", term.KeyDown)
+	if p := m.selected(); p.ID != "peb-cccc" {
+		t.Errorf("selected %s after moving down twice", p.ID)
+	}
+	pressKeys(m, term.KeyDown)
+	if p := m.selected(); p.ID != "peb-cccc" {
+		t.Errorf("selected %s after moving past the end", p.ID)
+	}
+	pressKeys(m, "g")
+	if p := m.selected(); p.ID != "peb-aaaa" {
+		t.Errorf("selected %s after g", p.ID)
+	}
+	if action := pressKeys(m, "q"); action != tuiQuit {
+		t.Errorf("q returned %v", action)
+	}
+	if action := pressKeys(m, "e"); action != tuiEdit {
+		t.Errorf("e returned %v", action)
+	}
+}
+
+func TestTUIQuery(t *testing.T) {
+	m, _ := newTestTUI(t)
+
+	pressKeys(m, "/", term.KeyCtrlU)
+	typeText(m, "type:task")
+	pressKeys(m, term.KeyEnter)
+	if ids := listedIDs(m); !slices.Equal(ids, []string{"peb-bbbb", "peb-dddd"}) {
+		t.Errorf("listed %v for type:task", ids)
+	}
+
+	// An invalid query keeps the prompt open with the error.
+	pressKeys(m, "/", term.KeyBackspace, term.KeyBackspace, term.KeyBackspace, term.KeyBackspace)
+	typeText(m, "bogus")
+	if m.input != "type:bogus" {
+		t.Errorf("input = %q", m.input)
+	}
+	pressKeys(m, term.KeyCtrlU)
+	typeText(m, "bogus:x")
+	pressKeys(m, term.KeyEnter)
+	if m.mode != tuiQuery || !m.failed {
+		t.Errorf("invalid query: mode = %v, message = %q", m.mode, m.message)
+	}
+	if m.query != "type:task" {
+		t.Errorf("query = %q after an invalid query", m.query)
+	}
+	pressKeys(m, term.KeyEscape)
+	if m.mode != tuiBrowse {
+		t.Errorf("mode = %v after esc", m.mode)
+	}
+}
+
+func TestTUIStatus(t *testing.T) {
+	m, s := newTestTUI(t)
+
+	pressKeys(m, "j", "f")
+	if p, _ := s.Get("peb-bbbb"); p.Status != peb.StatusFixed {
+		t.Errorf("status = %s after f", p.Status)
+	}
+	if m.message != "Updated status of peb-bbbb to fixed." {
+		t.Errorf("message = %q", m.message)
+	}
+	// The fixed peb leaves the list of open pebs and the next one is selected.
+	if ids := listedIDs(m); !slices.Equal(ids, []string{"peb-aaaa", "peb-cccc"}) {
+		t.Errorf("listed %v", ids)
+	}
+	if p := m.selected(); p.ID != "peb-cccc" {
+		t.Errorf("selected %s", p.ID)
+	}
+}
+
+func TestTUIBlock(t *testing.T) {
+	m, s := newTestTUI(t)
+
+	pressKeys(m, "b")
+	typeText(m, "cccc")
+	pressKeys(m, term.KeyEnter)
+	if p, _ := s.Get("peb-aaaa"); !slices.Equal(p.BlockedBy, []string{"peb-cccc"}) {
+		t.Errorf("blocked-by = %v, message = %q", p.BlockedBy, m.message)
+	}
+
+	// A cycle is refused and leaves the peb unchanged.
+	pressKeys(m, "G", "b")
+	typeText(m, "peb-aaaa")
+	pressKeys(m, term.KeyEnter)
+	if !m.failed || m.mode != tuiBlock {
+		t.Errorf("cycle accepted: mode = %v, message = %q", m.mode, m.message)
+	}
+	if p, _ := s.Get("peb-cccc"); len(p.BlockedBy) != 0 {
+		t.Errorf("blocked-by = %v after a cycle", p.BlockedBy)
+	}
+	pressKeys(m, term.KeyEscape)
+
+	// u suggests the only blocker.
+	pressKeys(m, "g", "u")
+	if m.input != "peb-cccc" {
+		t.Errorf("unblock prompt = %q", m.input)
+	}
+	pressKeys(m, term.KeyEnter)
+	if p, _ := s.Get("peb-aaaa"); len(p.BlockedBy) != 0 {
+		t.Errorf("blocked-by = %v after unblocking", p.BlockedBy)
+	}
+	if m.message != "Cleared blocked-by list of peb-aaaa." {
+		t.Errorf("message = %q", m.message)
+	}
+}
+
+func TestTUIDelete(t *testing.T) {
+	m, s := newTestTUI(t)
+
+	pressKeys(m, "d")
+	if footer := m.view()[m.height-1]; footer != `Delete peb-aaaa "First bug"? (y/n)` {
+		t.Errorf("footer = %q", footer)
+	}
+	pressKeys(m, "n")
+	if !s.Exists("peb-aaaa") {
+		t.Error("peb deleted without confirmation")
+	}
+
+	pressKeys(m, "d", "y")
+	if s.Exists("peb-aaaa") {
+		t.Error("peb not deleted")
+	}
+	if ids := listedIDs(m); !slices.Equal(ids, []string{"peb-bbbb", "peb-cccc"}) {
+		t.Errorf("listed %v", ids)
+	}
+}
+
+func TestTUITargetChanged(t *testing.T) {
+	m, s := newTestTUI(t)
+	other := store.New(m.cfg.PebblesDir(), "peb")
+	if err := other.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The peb to delete is closed elsewhere and leaves the list, but it is
+	// still the one that is deleted.
+	pressKeys(m, "d")
+	p, _ := other.Get("peb-aaaa")
+	p.Status = peb.StatusFixed
+	if err := other.Save(p); err != nil {
+		t.Fatal(err)
+	}
+	m.reload()
+	if footer := m.view()[m.height-1]; footer != `Delete peb-aaaa "First bug"? (y/n)` {
+		t.Errorf("footer = %q", footer)
+	}
+	pressKeys(m, "y")
+	if s.Exists("peb-aaaa") || !s.Exists("peb-bbbb") {
+		t.Errorf("deleted the wrong peb: %v", s.IDs())
+	}
+
+	// Prompts are cancelled if their peb is deleted elsewhere.
+	pressKeys(m, "b")
+	if err := other.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Delete(m.selected()); err != nil {
+		t.Fatal(err)
+	}
+	m.reload()
+	if m.mode != tuiBrowse || m.message != "peb peb-bbbb no longer exists" {
+		t.Errorf("mode = %v, message = %q", m.mode, m.message)
+	}
+
+	// Removing every peb leaves nothing to confirm.
+	pressKeys(m, "d")
+	for _, id := range s.IDs() {
+		filename, _ := s.Filename(id)
+		if err := os.Remove(filepath.Join(m.cfg.PebblesDir(), filename)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.reload()
+	if footer := m.view()[m.height-1]; footer != "peb peb-cccc no longer exists" {
+		t.Errorf("footer = %q", footer)
+	}
+	pressKeys(m, "y")
+	if m.mode != tuiBrowse {
+		t.Errorf("mode = %v", m.mode)
+	}
+}
+
+func TestTUIReload(t *testing.T) {
+	m, _ := newTestTUI(t)
+	pressKeys(m, "j")
+
+	// Another process creates a peb and closes the selected one.
+	other := store.New(m.cfg.PebblesDir(), "peb")
+	if err := other.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Save(peb.New("peb-0000", "New bug", peb.TypeBug, peb.StatusNew, "From elsewhere.")); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := other.Get("peb-cccc")
+	p.Status = peb.StatusWontFix
+	if err := other.Save(p); err != nil {
+		t.Fatal(err)
+	}
+
+	m.reload()
+	if ids := listedIDs(m); !slices.Equal(ids, []string{"peb-0000", "peb-aaaa", "peb-bbbb"}) {
+		t.Errorf("listed %v after reload", ids)
+	}
+	if p := m.selected(); p.ID != "peb-bbbb" {
+		t.Errorf("selected %s after reload, want peb-bbbb", p.ID)
+	}
+
+	// An unreadable file is reported in the header.
+	if err := os.WriteFile(filepath.Join(m.cfg.PebblesDir(), "peb-eeee--broken.md"), []byte("---\nid: [\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m.reload()
+	m.resize(100, 20)
+	if header := m.view()[0]; !strings.Contains(header, "1 unreadable file(s) skipped") {
+		t.Errorf("header = %q", header)
+	}
+}
+
+func TestTUIEdited(t *testing.T) {
+	m, s := newTestTUI(t)
+	p := m.selected()
+
+	updated := *p
+	updated.Title = "Renamed bug"
+	if err := s.Save(&updated); err != nil {
+		t.Fatal(err)
+	}
+	m.edited(p, true, nil)
+	if m.message != "Updated peb-aaaa." {
+		t.Errorf("message = %q", m.message)
+	}
+	if m.selected().Title != "Renamed bug" {
+		t.Errorf("title = %q", m.selected().Title)
+	}
+}
@@ -3,8 +3,6 @@ package commands
 import (
 	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 
 	"github.com/urfave/cli/v2"
@@ -73,15 +71,16 @@ Examples:
 				return fmt.Errorf("failed to update plugin: %w", err)
 			}
 
-			s := store.New(cfg.PebblesDir(), cfg.Prefix)
-			if err := s.Load(); err != nil {
+			s, err := openStore(cfg)
+			if err != nil {
 				return err
 			}
 
-			p, ok := s.Get(pebID)
-			if !ok {
-				return fmt.Errorf("peb %s not found", pebID)
+			p, err := getPeb(s, pebID)
+			if err != nil {
+				return err
 			}
+			pebID = p.ID
 
 			var input UpdateInput
 
@@ -94,83 +93,43 @@ Examples:
 						return fmt.Errorf("failed to parse JSON input: %w", err)
 					}
 				} else {
-					decoder := json.NewDecoder(os.Stdin)
+					decoder := json.NewDecoder(c.App.Reader)
 					if err := decoder.Decode(&input); err != nil {
 						return fmt.Errorf("failed to parse JSON input: %w", err)
 					}
 				}
 			} else {
-				decoder := json.NewDecoder(os.Stdin)
+				decoder := json.NewDecoder(c.App.Reader)
 				if err := decoder.Decode(&input); err != nil {
 					return fmt.Errorf("failed to parse JSON input: %w", err)
 				}
 			}
 
-			oldFilename := peb.Filename(p)
 			oldTitle := p.Title
 			oldType := p.Type
 			oldStatus := p.Status
 
-			if input.Title != nil {
-				p.Title = *input.Title
-			}
-			if input.Content != nil {
-				p.Content = *input.Content
-			}
-			if input.Type != nil {
-				p.Type = peb.Type(*input.Type)
-			}
-			if input.Status != nil {
-				p.Status = peb.Status(*input.Status)
-			}
-			if input.BlockedBy != nil {
-				p.BlockedBy = *input.BlockedBy
-			}
-
-			if input.BlockedBy != nil && len(*input.BlockedBy) > 0 {
-				if err := peb.ValidateBlockedBy(s, p, *input.BlockedBy); err != nil {
-					if peb.IsInvalidReference(err) {
-						return fmt.Errorf("Referenced peb(s) not found: %s", extractInvalidID(err))
-					}
-					return err
-				}
-				if err := peb.CheckCycle(s, p.ID, *input.BlockedBy); err != nil {
-					return err
-				}
-			}
-
-			p.UpdateTimestamp()
-
-			if input.Title != nil {
-				oldPath := filepath.Join(cfg.PebblesDir(), oldFilename)
-				newFilename := peb.Filename(p)
-				newPath := filepath.Join(cfg.PebblesDir(), newFilename)
-				if err := os.Rename(oldPath, newPath); err != nil {
-					return fmt.Errorf("failed to rename file: %w", err)
-				}
-			}
-
-			if err := s.Save(p); err != nil {
-				return fmt.Errorf("failed to save peb: %w", err)
+			if err := applyUpdate(s, cfg, p, input); err != nil {
+				return err
 			}
 
 			if input.Status != nil && oldStatus != p.Status {
-				fmt.Printf("Updated status of %s to %s.\n", pebID, p.Status)
+				fmt.Fprintf(c.App.Writer, "Updated status of %s to %s.\n", pebID, p.Status)
 			}
 			if input.Title != nil && oldTitle != p.Title {
-				fmt.Printf("Updated title of %s to %q.\n", pebID, truncate(p.Title))
+				fmt.Fprintf(c.App.Writer, "Updated title of %s to %q.\n", pebID, truncate(p.Title))
 			}
 			if input.Content != nil && p.Content != "" {
-				fmt.Printf("Updated content of %s to %q.\n", pebID, truncate(p.Content))
+				fmt.Fprintf(c.App.Writer, "Updated content of %s to %q.\n", pebID, truncate(p.Content))
 			}
 			if input.Type != nil && oldType != p.Type {
-				fmt.Printf("Updated type of %s to %s.\n", pebID, p.Type)
+				fmt.Fprintf(c.App.Writer, "Updated type of %s to %s.\n", pebID, p.Type)
 			}
 			if input.BlockedBy != nil {
 				if len(*input.BlockedBy) > 0 {
-					fmt.Printf("Updated blocked-by list of %s to %v.\n", pebID, p.BlockedBy)
+					fmt.Fprintf(c.App.Writer, "Updated blocked-by list of %s to %v.\n", pebID, p.BlockedBy)
 				} else {
-					fmt.Printf("Cleared blocked-by list of %s.\n", pebID)
+					fmt.Fprintf(c.App.Writer, "Cleared blocked-by list of %s.\n", pebID)
 				}
 			}
 
@@ -178,3 +137,41 @@ Examples:
 		},
 	}
 }
+
+func applyUpdate(s *store.Store, cfg *config.Config, p *peb.Peb, input UpdateInput) error {
+	if input.Title != nil {
+		p.Title = *input.Title
+	}
+	if input.Content != nil {
+		p.Content = *input.Content
+	}
+	if input.Type != nil {
+		p.Type = peb.Type(*input.Type)
+	}
+	if input.Status != nil {
+		p.Status = peb.Status(*input.Status)
+	}
+	if input.BlockedBy != nil {
+		p.BlockedBy = *input.BlockedBy
+	}
+
+	if input.BlockedBy != nil && len(*input.BlockedBy) > 0 {
+		if err := peb.ValidateBlockedBy(s, p, *input.BlockedBy); err != nil {
+			if peb.IsInvalidReference(err) {
+				return fmt.Errorf("Referenced peb(s) not found: %s", extractInvalidID(err))
+			}
+			return err
+		}
+		if err := peb.CheckCycle(s, p.ID, *input.BlockedBy); err != nil {
+			return err
+		}
+	}
+
+	p.UpdateTimestamp()
+
+	if err := s.Save(p); err != nil {
+		return fmt.Errorf("failed to save peb: %w", err)
+	}
+
+	return nil
+}
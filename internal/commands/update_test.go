@@ -612,6 +612,7 @@ func runCommand(args []string) string {
 			NewCommand(),
 			ReadCommand(),
 			UpdateCommand(),
+			StatsCommand(),
 		},
 	}
 
@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/urfave/cli/v2"
+	"go.yozora.eu/pebbles/internal/config"
+	"go.yozora.eu/pebbles/internal/watch"
+)
+
+func WatchCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "watch",
+		Usage: "Stream changes to pebs as JSON lines",
+		Description: `Watch .pebbles/ and print one JSON object per change.
+
+Each event has the fields:
+  event         One of: created, updated, deleted, renamed
+  id            The peb ID
+  filename      The current file name (the last one for deleted pebs)
+  old-filename  The previous file name if the file was renamed
+  changes       For updates, the old and new value of every changed field
+  peb           The id, type, status, title and blocked-by of the peb
+
+Title changes rename the peb file; they are reported as a single update.
+A rename without any field change is reported as "renamed".
+
+Accepts the same filters as "peb query". An event is reported if the peb
+matches the filters before or after the change, so "status:fixed" reports
+pebs as they are marked fixed.
+
+Uses inotify on Linux and polls the directory elsewhere.
+
+Examples:
+  peb watch                     Stream all changes
+  peb watch status:fixed        Stream pebs being marked fixed
+  peb watch --poll              Force polling`,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "poll",
+				Usage: "Poll the directory instead of using file system notifications",
+			},
+			&cli.DurationFlag{
+				Name:  "interval",
+				Usage: "Poll interval",
+				Value: watch.DefaultPollInterval,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			if err := config.MaybeUpdatePlugin(cfg); err != nil {
+				return fmt.Errorf("failed to update plugin: %w", err)
+			}
+
+			args, err := expandSavedQueries(c.Args().Slice(), cfg.Queries)
+			if err != nil {
+				return err
+			}
+
+			filters, err := parseFilters(args, nil)
+			if err != nil {
+				return err
+			}
+
+			w, err := watch.New(cfg.PebblesDir(), cfg.Prefix, watch.Options{
+				Poll:         c.Bool("poll"),
+				PollInterval: c.Duration("interval"),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to watch pebbles directory: %w", err)
+			}
+
+			ctx, stop := signal.NotifyContext(c.Context, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			return runWatch(ctx, w, filters, json.NewEncoder(c.App.Writer))
+		},
+	}
+}
+
+func runWatch(ctx context.Context, w *watch.Watcher, filters []filterFunc, encoder *json.Encoder) error {
+	return w.Run(ctx, func(e watch.Event) error {
+		matches := (e.Old != nil && applyFilters(e.Old, filters)) ||
+			(e.New != nil && applyFilters(e.New, filters))
+		if !matches {
+			return nil
+		}
+		if err := encoder.Encode(e); err != nil {
+			return fmt.Errorf("failed to encode event: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"go.yozora.eu/pebbles/internal/peb"
+	"go.yozora.eu/pebbles/internal/watch"
+)
+
+func TestWatchCommandTitleRenameIsOneUpdate(t *testing.T) {
+	pebblesDir, s, cleanup := setupTestStore(t)
+	defer cleanup()
+	t.Chdir(pebblesDir)
+
+	if err := s.Save(peb.New("peb-aaaa", "Old title", peb.TypeTask, peb.StatusNew, "Content")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save(peb.New("peb-bbbb", "Other", peb.TypeTask, peb.StatusNew, "Content")); err != nil {
+		t.Fatal(err)
+	}
+
+	filters, err := parseFilters([]string{"status:fixed"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := watch.New(pebblesDir, "peb", watch.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, pw := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- runWatch(ctx, w, filters, json.NewEncoder(pw))
+		pw.Close()
+	}()
+
+	// Not matching the filter before or after the change: no event.
+	runCommand([]string{"update", "peb-bbbb", `{"content":"Changed"}`})
+	time.Sleep(200 * time.Millisecond)
+
+	output := runCommand([]string{"update", "peb-aaaa", `{"title":"New title","status":"fixed"}`})
+	if output == "" {
+		t.Fatal("expected update output")
+	}
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	select {
+	case line := <-lines:
+		var e struct {
+			Event       string                       `json:"event"`
+			ID          string                       `json:"id"`
+			Filename    string                       `json:"filename"`
+			OldFilename string                       `json:"old-filename"`
+			Changes     map[string]watch.FieldChange `json:"changes"`
+		}
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("invalid event %s: %v", line, err)
+		}
+		if e.Event != watch.EventUpdated || e.ID != "peb-aaaa" {
+			t.Errorf("expected update of peb-aaaa, got %s", line)
+		}
+		if e.OldFilename != "peb-aaaa--old-title.md" || e.Filename != "peb-aaaa--new-title.md" {
+			t.Errorf("unexpected filenames in %s", line)
+		}
+		if e.Changes["status"].New != "fixed" || e.Changes["title"].New != "New title" {
+			t.Errorf("expected status and title changes in %s", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("watch failed: %v", err)
+	}
+	for line := range lines {
+		t.Errorf("unexpected extra event %s", line)
+	}
+}
@@ -3,21 +3,60 @@ package config
 import (
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
+	"go.yozora.eu/pebbles/internal/peb"
+	"go.yozora.eu/pebbles/internal/vcs"
 )
 
 type Config struct {
-	Prefix     string `toml:"prefix"`
-	IDLength   int    `toml:"id_length"`
+	// Version is 0 for directories created before it was introduced.
+	Version    int               `toml:"version"`
+	Prefix     string            `toml:"prefix"`
+	IDLength   int               `toml:"id_length"`
+	IDScheme   peb.IDScheme      `toml:"id_scheme"`
+	IDStride   int               `toml:"id_stride"`
+	Queries    map[string]string `toml:"queries"`
+	Hooks      HooksConfig       `toml:"hooks"`
 	projectDir string
 	pebblesDir string
+	spread     *peb.Spread
 }
 
+// HooksConfig configures shell commands that run when pebs change. A failing
+// pre-hook vetoes the change; a failing post-hook only warns unless
+// fail_on_error is set.
+type HooksConfig struct {
+	Timeout        string `toml:"timeout"`
+	FailOnError    bool   `toml:"fail_on_error"`
+	PreCreate      string `toml:"pre_create"`
+	PreUpdate      string `toml:"pre_update"`
+	PreDelete      string `toml:"pre_delete"`
+	OnCreate       string `toml:"on_create"`
+	OnStatusChange string `toml:"on_status_change"`
+	OnClose        string `toml:"on_close"`
+	OnDelete       string `toml:"on_delete"`
+}
+
+const DefaultHookTimeout = 30 * time.Second
+
+// FormatVersion is the format version of .pebbles directories written by
+// this version of pebbles. Newer ones are refused, since this version could
+// corrupt them.
+const FormatVersion = 1
+
 const DefaultPrefix = "peb"
 const DefaultIDLength = 4
+const DefaultIDStride = 10
+
+var ErrFormatTooNew = errors.New("unsupported format version")
 
 var ErrNoPebblesDir = errors.New("no .pebbles directory found (did you run 'peb init'?)")
 
@@ -66,6 +105,15 @@ func loadConfig(pebblesDir string) (*Config, error) {
 	if err := toml.Unmarshal(data, cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
+	if cfg.Version > FormatVersion {
+		return nil, fmt.Errorf("%w: .pebbles/ has format version %d, but this peb only supports up to %d; please upgrade peb", ErrFormatTooNew, cfg.Version, FormatVersion)
+	}
+	if cfg.IDScheme, err = peb.ParseIDScheme(string(cfg.IDScheme)); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if cfg.IDStride < 0 {
+		return nil, fmt.Errorf("failed to parse config: id_stride must not be negative")
+	}
 	return cfg, nil
 }
 
@@ -73,9 +121,99 @@ func (c *Config) PebblesDir() string {
 	return c.pebblesDir
 }
 
+func (c *Config) ProjectDir() string {
+	return c.projectDir
+}
+
+// IDSpread derives the slot from the user and the branch. Outside of a
+// repository, numbers are dense.
+func (c *Config) IDSpread() peb.Spread {
+	if c.spread != nil {
+		return *c.spread
+	}
+	c.spread = &peb.Spread{}
+	stride := c.IDStride
+	if stride == 0 {
+		stride = DefaultIDStride
+	}
+	if c.IDScheme == peb.IDSchemeRandom || stride == 1 {
+		return *c.spread
+	}
+	repo, err := vcs.Detect(c.projectDir)
+	if err != nil {
+		return *c.spread
+	}
+	h := fnv.New32a()
+	h.Write([]byte(repo.Workspace()))
+	c.spread = &peb.Spread{Stride: stride, Slot: int(h.Sum32() % uint32(stride))}
+	return *c.spread
+}
+
+func (c *Config) HookTimeout() (time.Duration, error) {
+	if c.Hooks.Timeout == "" {
+		return DefaultHookTimeout, nil
+	}
+	timeout, err := time.ParseDuration(c.Hooks.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid hook timeout %q: %w", c.Hooks.Timeout, err)
+	}
+	return timeout, nil
+}
+
 func DefaultConfigContent() string {
 	return fmt.Sprintf(`# Pebbles configuration
+# Format version of .pebbles/, upgraded by "peb migrate".
+version = %d
 prefix = "%s"
 id_length = %d
-`, DefaultPrefix, DefaultIDLength)
+# IDs of new pebs: "random" (peb-ab12), "sequential" (peb-42) or
+# "hierarchical" (peb-ab12.1 for the children of peb-ab12).
+id_scheme = "random"
+# Sequential and hierarchical numbers are spread over branches in steps of
+# id_stride, so that branches don't take the same numbers; 1 numbers densely.
+# id_stride = 10
+
+# Named queries, usable as "peb query @name".
+# [queries]
+# triage = "status:open type:(bug|feature)"
+
+# Shell commands run on changes; they receive {"hook","old","new"} as JSON on
+# stdin. Pre-hooks veto the change by exiting with a non-zero status.
+# [hooks]
+# timeout = "30s"
+# fail_on_error = false
+# pre_update = "..."
+# on_close = "..."
+`, FormatVersion, DefaultPrefix, DefaultIDLength)
+}
+
+// SetValue sets the top-level key to value, which must be valid TOML, keeping
+// the rest of config.toml as it is. A new key is added before the first
+// setting, outside of any table.
+func SetValue(data []byte, key, value string) []byte {
+	line := key + " = " + value
+	pattern := regexp.MustCompile(`(?m)^` + regexp.QuoteMeta(key) + `\s*=.*$`)
+	lines := strings.SplitAfter(string(data), "\n")
+	for i, l := range lines {
+		trimmed := strings.TrimSpace(l)
+		if strings.HasPrefix(trimmed, "[") {
+			break
+		}
+		if pattern.MatchString(trimmed) {
+			lines[i] = pattern.ReplaceAllString(l, line)
+			return []byte(strings.Join(lines, ""))
+		}
+	}
+	for i, l := range lines {
+		trimmed := strings.TrimSpace(l)
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			lines = slices.Insert(lines, i, line+"\n")
+			return []byte(strings.Join(lines, ""))
+		}
+	}
+	text := string(data)
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return []byte(text + line + "\n")
 }
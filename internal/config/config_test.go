@@ -0,0 +1,50 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.yozora.eu/pebbles/internal/peb"
+)
+
+func TestLoadConfigIDScheme(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, "config.toml"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("prefix = \"peb\"\n")
+	cfg, err := loadConfig(dir)
+	if err != nil || cfg.IDScheme != peb.IDSchemeRandom {
+		t.Fatalf("expected default scheme random, got %v, %v", cfg, err)
+	}
+
+	write("id_scheme = \"sequential\"\n")
+	if cfg, err = loadConfig(dir); err != nil || cfg.IDScheme != peb.IDSchemeSequential {
+		t.Fatalf("expected sequential scheme, got %v, %v", cfg, err)
+	}
+
+	write("id_scheme = \"uuid\"\n")
+	if _, err := loadConfig(dir); err == nil {
+		t.Fatal("expected error for unknown scheme")
+	}
+}
+
+func TestSetValue(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"", "version = 2\n"},
+		{"# comment\n", "# comment\nversion = 2\n"},
+		{"# comment\n\n[queries]\na = \"b\"\n", "# comment\n\nversion = 2\n[queries]\na = \"b\"\n"},
+		{"prefix = \"x\"\nversion = 1\n", "prefix = \"x\"\nversion = 2\n"},
+		{"prefix = \"x\"\n[hooks]\nversion = 1\n", "version = 2\nprefix = \"x\"\n[hooks]\nversion = 1\n"},
+	}
+	for _, tt := range tests {
+		if got := string(SetValue([]byte(tt.in), "version", "2")); got != tt.want {
+			t.Errorf("SetValue(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -581,6 +581,7 @@ export default async function (pi: ExtensionAPI) {
 	let pebbleIDPattern = "peb-xxxx";
 	let pebbleIDPattern2 = "peb-yyyy";
 	let pebbleIDPattern3 = "peb-zzzz";
+	let savedQueries: Record<string, string> = {};
 
 	try {
 		const { stdout } = runPeb(["prime", "--mcp"]);
@@ -594,9 +595,13 @@ export default async function (pi: ExtensionAPI) {
 		const config = JSON.parse(stdout);
 		const prefix: string = config.prefix;
 		const idLength: number = config.id_length;
-		pebbleIDPattern = `${prefix}-${"x".repeat(idLength)}`;
-		pebbleIDPattern2 = `${prefix}-${"y".repeat(idLength)}`;
-		pebbleIDPattern3 = `${prefix}-${"z".repeat(idLength)}`;
+		const examples: string[] = config.id_examples ?? [
+			`${prefix}-${"x".repeat(idLength)}`,
+			`${prefix}-${"y".repeat(idLength)}`,
+			`${prefix}-${"z".repeat(idLength)}`,
+		];
+		[pebbleIDPattern, pebbleIDPattern2, pebbleIDPattern3] = examples;
+		savedQueries = config.queries ?? {};
 	} catch {
 		// keep defaults
 	}
@@ -632,13 +637,13 @@ export default async function (pi: ExtensionAPI) {
 		name: "peb_new",
 		label: "Peb New",
 		description:
-			"Create a new peb (task/bug/feature/epic). Required: title, content. Optional: type (bug|feature|epic|task, default: bug), blocked_by (array of peb IDs)",
+			"Create a new peb (task/bug/feature/epic). Required: title, content. Optional: type (bug|feature|epic|task, default: bug), blocked_by (array of peb IDs), children (subtasks created atomically with this peb, which is then blocked by them; returns the ID tree)",
 		parameters: Type.Object({
 			title: Type.String({ description: "Short description of the peb" }),
 			content: Type.String({ description: "Markdown description of the peb" }),
 			type: Type.Optional(
 				StringEnum(["bug", "feature", "epic", "task"], {
-					description: "Type: bug, feature, epic, or task (default: bug)",
+					description: "Type: bug, feature, epic, or task (default: bug, or epic with children)",
 				}),
 			),
 			blocked_by: Type.Optional(
@@ -646,6 +651,20 @@ export default async function (pi: ExtensionAPI) {
 					description: "Array of peb IDs that block this peb",
 				}),
 			),
+			children: Type.Optional(
+				Type.Array(
+					Type.Object({
+						title: Type.String({ description: "Short description of the subtask" }),
+						content: Type.String({ description: "Markdown description of the subtask" }),
+						type: Type.Optional(
+							StringEnum(["bug", "feature", "epic", "task"], {
+								description: "Type: bug, feature, epic, or task (default: task)",
+							}),
+						),
+					}),
+					{ description: "Subtasks to create together with this peb" },
+				),
+			),
 		}),
 		async execute(_toolCallId, params) {
 			const json: Record<string, unknown> = {
@@ -654,6 +673,7 @@ export default async function (pi: ExtensionAPI) {
 			};
 			if (params.type) json.type = params.type;
 			if (params.blocked_by) json["blocked-by"] = params.blocked_by;
+			if (params.children) json.children = params.children;
 			const text = pebOutput(["new"], JSON.stringify(json));
 			return { content: [{ type: "text", text }], details: undefined };
 		},
@@ -714,11 +734,22 @@ export default async function (pi: ExtensionAPI) {
 		renderResult: renderPebResult,
 	});
 
+	const presetNames = Object.keys(savedQueries).sort();
+	const presetDescription =
+		presetNames.length > 0
+			? ` Saved query presets: ${presetNames.map((name) => `${name} (${savedQueries[name]})`).join(", ")}.`
+			: "";
+
 	pi.registerTool({
 		name: "peb_query",
 		label: "Peb Query",
-		description: `Query pebs with optional filters (id:${pebbleIDPattern}|id:(${pebbleIDPattern}|${pebbleIDPattern2}), status:new|in-progress|fixed|wont-fix|open|closed, type:bug|feature|epic|task, blocked-by:${pebbleIDPattern}, --fields:id,title). Returns list of pebs.`,
+		description: `Query pebs with optional filters (id:${pebbleIDPattern}|id:(${pebbleIDPattern}|${pebbleIDPattern2}), status:new|in-progress|fixed|wont-fix|open|closed, type:bug|feature|epic|task, blocked-by:${pebbleIDPattern}, --fields:id,title). Returns list of pebs.${presetDescription}`,
 		parameters: Type.Object({
+			preset: Type.Optional(
+				presetNames.length > 0
+					? StringEnum(presetNames, { description: "Name of a saved query to apply before the filters" })
+					: Type.String({ description: "Name of a saved query to apply before the filters" }),
+			),
 			filters: Type.Optional(
 				Type.Array(Type.String(), {
 					description: "Array of filters (e.g., ['status:new', 'type:bug'])",
@@ -726,13 +757,14 @@ export default async function (pi: ExtensionAPI) {
 			),
 			fields: Type.Optional(
 				Type.Array(Type.String(), {
-					description: "Array of fields to output (e.g., ['id', 'title'])",
+					description: "Array of fields to output (e.g., ['id', 'title']). Available: id, type, status, title, created, changed, blocked-by, content, content:N (truncated), content-summary, blocks, ready, filename, age",
 				}),
 			),
 		}),
 		async execute(_toolCallId, params) {
 			const args: string[] = ["query"];
 			if (params.fields) args.push("--fields", params.fields.join(","));
+			if (params.preset) args.push(`@${params.preset}`);
 			if (params.filters) args.push(...params.filters);
 			const text = pebOutput(args);
 			return { content: [{ type: "text", text }], details: undefined };
@@ -1060,6 +1092,12 @@ export default async function (pi: ExtensionAPI) {
 									.map((l) => l.trim())
 									.filter(Boolean);
 								logFix("captured", { pebId, anchorIds, changeIds: job.changeIds });
+								// Record the commits on the peb, so the link outlives the session.
+								if (job.changeIds.length) {
+									const ids = job.changeIds.map((c) => c.split(/\s+/)[0]);
+									const linkRes = runPeb(["link-commit", pebId, ...ids]);
+									if (linkRes.status !== 0) logFix("link_failed", { pebId, error: linkRes.stderr.trim() });
+								}
 							} catch (e) {
 								logFix("capture_failed", { pebId, anchorIds, error: String(e) });
 							}
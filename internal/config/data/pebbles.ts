@@ -33,9 +33,16 @@ export const PebblesPlugin: Plugin = async ({ $ }) => {
   const config = JSON.parse(configText);
   const prefix = config.prefix;
   const idLength = config.id_length;
-  const pebbleIDPattern = `${prefix}-${"x".repeat(idLength)}`;
-  const pebbleIDPattern2 = `${prefix}-${"y".repeat(idLength)}`;
-  const pebbleIDPattern3 = `${prefix}-${"z".repeat(idLength)}`;
+  const [pebbleIDPattern, pebbleIDPattern2, pebbleIDPattern3]: string[] = config.id_examples ?? [
+    `${prefix}-${"x".repeat(idLength)}`,
+    `${prefix}-${"y".repeat(idLength)}`,
+    `${prefix}-${"z".repeat(idLength)}`,
+  ];
+  const savedQueries: Record<string, string> = config.queries ?? {};
+  const presetNames = Object.keys(savedQueries).sort();
+  const presetDescription = presetNames.length > 0
+    ? ` Saved query presets: ${presetNames.map((name) => `${name} (${savedQueries[name]})`).join(", ")}.`
+    : "";
 
   return {
     "experimental.chat.system.transform": async (_, output) => {
@@ -46,17 +53,23 @@ export const PebblesPlugin: Plugin = async ({ $ }) => {
     },
     tool: {
       peb_new: tool({
-        description: "Create a new peb (task/bug/feature/epic). Required: title, content. Optional: type (bug|feature|epic|task, default: bug), blocked-by (array of peb IDs)",
+        description: "Create a new peb (task/bug/feature/epic). Required: title, content. Optional: type (bug|feature|epic|task, default: bug), blocked-by (array of peb IDs), children (subtasks created atomically with this peb, which is then blocked by them; returns the ID tree)",
         args: {
           title: tool.schema.string().describe("Short description of the peb"),
           content: tool.schema.string().describe("Markdown description of the peb"),
-          type: tool.schema.string().optional().describe("Type: bug, feature, epic, or task (default: bug)"),
+          type: tool.schema.string().optional().describe("Type: bug, feature, epic, or task (default: bug, or epic with children)"),
           blocked_by: tool.schema.array(tool.schema.string()).optional().describe("Array of peb IDs that block this peb"),
+          children: tool.schema.array(tool.schema.object({
+            title: tool.schema.string().describe("Short description of the subtask"),
+            content: tool.schema.string().describe("Markdown description of the subtask"),
+            type: tool.schema.string().optional().describe("Type: bug, feature, epic, or task (default: task)"),
+          })).optional().describe("Subtasks to create together with this peb"),
         },
         async execute(args) {
           const json: Record<string, unknown> = { title: args.title, content: args.content };
           if (args.type) json.type = args.type;
           if (args.blocked_by) json["blocked-by"] = args.blocked_by;
+          if (args.children) json.children = args.children;
 
           const jsonString = JSON.stringify(json);
           const proc = spawn(['peb', 'new'], {
@@ -131,16 +144,20 @@ export const PebblesPlugin: Plugin = async ({ $ }) => {
         },
       }),
       peb_query: tool({
-        description: `Query pebs with optional filters (id:${pebbleIDPattern}|id:(${pebbleIDPattern}|${pebbleIDPattern2}), status:new|in-progress|fixed|wont-fix|open|closed, type:bug|feature|epic|task, blocked-by:${pebbleIDPattern}, --fields:id,title). Returns list of pebs.`,
+        description: `Query pebs with optional filters (id:${pebbleIDPattern}|id:(${pebbleIDPattern}|${pebbleIDPattern2}), status:new|in-progress|fixed|wont-fix|open|closed, type:bug|feature|epic|task, blocked-by:${pebbleIDPattern}, --fields:id,title). Returns list of pebs.${presetDescription}`,
         args: {
+          preset: tool.schema.string().optional().describe("Name of a saved query to apply before the filters"),
           filters: tool.schema.array(tool.schema.string()).optional().describe("Array of filters (e.g., ['status:new', 'type:bug'])"),
-          fields: tool.schema.array(tool.schema.string()).optional().describe("Array of fields to output (e.g., ['id', 'title'])"),
+          fields: tool.schema.array(tool.schema.string()).optional().describe("Array of fields to output (e.g., ['id', 'title']). Available: id, type, status, title, created, changed, blocked-by, content, content:N (truncated), content-summary, blocks, ready, filename, age"),
         },
         async execute(args) {
           const cmdArgs: string[] = ['peb', 'query'];
           if (args.fields) {
             cmdArgs.push('--fields', args.fields.join(','));
           }
+          if (args.preset) {
+            cmdArgs.push(`@${args.preset}`);
+          }
           if (args.filters) {
             cmdArgs.push(...args.filters);
           }
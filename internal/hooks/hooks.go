@@ -0,0 +1,166 @@
+// Package hooks runs the shell commands configured in the [hooks] table of
+// config.toml when pebs are created, updated or deleted.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"go.yozora.eu/pebbles/internal/config"
+	"go.yozora.eu/pebbles/internal/peb"
+)
+
+const (
+	PreCreate      = "pre_create"
+	PreUpdate      = "pre_update"
+	PreDelete      = "pre_delete"
+	OnCreate       = "on_create"
+	OnStatusChange = "on_status_change"
+	OnClose        = "on_close"
+	OnDelete       = "on_delete"
+)
+
+// Input is the JSON document a hook receives on stdin.
+type Input struct {
+	Hook string   `json:"hook"`
+	Old  *peb.Peb `json:"old"`
+	New  *peb.Peb `json:"new"`
+}
+
+// Runner implements store.Hooks.
+type Runner struct {
+	cfg     config.HooksConfig
+	dir     string
+	timeout time.Duration
+	// Output defaults to os.Stderr, so that hooks never mix with the JSON
+	// output of a command.
+	Output io.Writer
+}
+
+// New returns nil if no hooks are configured.
+func New(cfg *config.Config) (*Runner, error) {
+	h := cfg.Hooks
+	if h.PreCreate == "" && h.PreUpdate == "" && h.PreDelete == "" &&
+		h.OnCreate == "" && h.OnStatusChange == "" && h.OnClose == "" && h.OnDelete == "" {
+		return nil, nil
+	}
+	timeout, err := cfg.HookTimeout()
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{cfg: cfg.Hooks, dir: cfg.ProjectDir(), timeout: timeout, Output: os.Stderr}, nil
+}
+
+// Before returns the output of a failing pre-hook as the error.
+func (r *Runner) Before(old, new *peb.Peb) error {
+	var name, command string
+	switch {
+	case old == nil:
+		name, command = PreCreate, r.cfg.PreCreate
+	case new == nil:
+		name, command = PreDelete, r.cfg.PreDelete
+	default:
+		name, command = PreUpdate, r.cfg.PreUpdate
+	}
+	if command == "" {
+		return nil
+	}
+
+	var output bytes.Buffer
+	if err := r.run(name, command, old, new, &output); err != nil {
+		msg := strings.TrimSpace(output.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return fmt.Errorf("%s hook rejected the change: %s", name, msg)
+	}
+	return nil
+}
+
+func (r *Runner) After(old, new *peb.Peb) error {
+	var names []string
+	switch {
+	case old == nil:
+		names = append(names, OnCreate)
+	case new == nil:
+		names = append(names, OnDelete)
+	default:
+		if old.Status != new.Status {
+			names = append(names, OnStatusChange)
+		}
+		if !peb.IsClosed(old.Status) && peb.IsClosed(new.Status) {
+			names = append(names, OnClose)
+		}
+	}
+
+	var errs []error
+	for _, name := range names {
+		command := r.command(name)
+		if command == "" {
+			continue
+		}
+		if err := r.run(name, command, old, new, r.Output); err != nil {
+			err = fmt.Errorf("%s hook failed: %w", name, err)
+			if !r.cfg.FailOnError {
+				fmt.Fprintf(r.Output, "Warning: %v\n", err)
+				continue
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Runner) command(name string) string {
+	switch name {
+	case OnCreate:
+		return r.cfg.OnCreate
+	case OnStatusChange:
+		return r.cfg.OnStatusChange
+	case OnClose:
+		return r.cfg.OnClose
+	case OnDelete:
+		return r.cfg.OnDelete
+	}
+	return ""
+}
+
+func (r *Runner) run(name, command string, old, new *peb.Peb, output io.Writer) error {
+	input, err := json.Marshal(Input{Hook: name, Old: old, New: new})
+	if err != nil {
+		return fmt.Errorf("failed to encode hook input: %w", err)
+	}
+
+	id := ""
+	if new != nil {
+		id = new.ID
+	} else if old != nil {
+		id = old.ID
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = r.dir
+	cmd.Env = append(os.Environ(), "PEB_HOOK="+name, "PEB_ID="+id)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	// Don't wait for background processes holding the output open.
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s", r.timeout)
+	}
+	return err
+}
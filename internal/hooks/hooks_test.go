@@ -0,0 +1,122 @@
+package hooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.yozora.eu/pebbles/internal/config"
+	"go.yozora.eu/pebbles/internal/peb"
+)
+
+func newRunner(t *testing.T, cfg config.HooksConfig) (*Runner, *bytes.Buffer) {
+	t.Helper()
+	var output bytes.Buffer
+	return &Runner{cfg: cfg, dir: t.TempDir(), timeout: 5 * time.Second, Output: &output}, &output
+}
+
+func TestAfterReceivesOldAndNew(t *testing.T) {
+	r, _ := newRunner(t, config.HooksConfig{
+		OnStatusChange: `cat > "$PEB_HOOK.json"`,
+		OnClose:        `cat > "$PEB_HOOK.json"`,
+	})
+
+	old := peb.New("peb-aaaa", "Peb", peb.TypeTask, peb.StatusInProgress, "Content")
+	cur := *old
+	cur.Status = peb.StatusFixed
+
+	if err := r.After(old, &cur); err != nil {
+		t.Fatalf("After() failed: %v", err)
+	}
+
+	for _, name := range []string{OnStatusChange, OnClose} {
+		data, err := os.ReadFile(filepath.Join(r.dir, name+".json"))
+		if err != nil {
+			t.Fatalf("expected %s to run: %v", name, err)
+		}
+		var input Input
+		if err := json.Unmarshal(data, &input); err != nil {
+			t.Fatal(err)
+		}
+		if input.Hook != name || input.Old.Status != peb.StatusInProgress || input.New.Status != peb.StatusFixed {
+			t.Errorf("unexpected %s input %s", name, data)
+		}
+	}
+}
+
+func TestAfterSkipsUnrelatedHooks(t *testing.T) {
+	r, output := newRunner(t, config.HooksConfig{
+		OnCreate: "echo created",
+		OnClose:  "echo closed",
+	})
+
+	old := peb.New("peb-aaaa", "Peb", peb.TypeTask, peb.StatusFixed, "Content")
+	cur := *old
+	cur.Status = peb.StatusWontFix
+
+	if err := r.After(old, &cur); err != nil {
+		t.Fatal(err)
+	}
+	if output.Len() != 0 {
+		t.Errorf("expected no hooks to run for a closed peb changing status, got %q", output)
+	}
+
+	if err := r.After(nil, &cur); err != nil {
+		t.Fatal(err)
+	}
+	if output.String() != "created\n" {
+		t.Errorf("expected on_create output, got %q", output)
+	}
+}
+
+func TestAfterFailurePolicy(t *testing.T) {
+	p := peb.New("peb-aaaa", "Peb", peb.TypeTask, peb.StatusNew, "Content")
+
+	r, output := newRunner(t, config.HooksConfig{OnDelete: "exit 3"})
+	if err := r.After(p, nil); err != nil {
+		t.Errorf("expected failing post-hook to only warn, got %v", err)
+	}
+	if !strings.Contains(output.String(), "Warning: on_delete hook failed") {
+		t.Errorf("expected warning, got %q", output)
+	}
+
+	r, _ = newRunner(t, config.HooksConfig{OnDelete: "exit 3", FailOnError: true})
+	if err := r.After(p, nil); err == nil || !strings.Contains(err.Error(), "on_delete hook failed") {
+		t.Errorf("expected error with fail_on_error, got %v", err)
+	}
+}
+
+func TestBeforeVeto(t *testing.T) {
+	r, _ := newRunner(t, config.HooksConfig{
+		PreUpdate: `echo "needs a review" >&2; exit 1`,
+		PreDelete: "true",
+	})
+
+	p := peb.New("peb-aaaa", "Peb", peb.TypeTask, peb.StatusNew, "Content")
+
+	err := r.Before(p, p)
+	if err == nil || err.Error() != "pre_update hook rejected the change: needs a review" {
+		t.Errorf("expected veto with hook output, got %v", err)
+	}
+	if err := r.Before(p, nil); err != nil {
+		t.Errorf("expected passing pre-hook to allow the change, got %v", err)
+	}
+	if err := r.Before(nil, p); err != nil {
+		t.Errorf("expected unconfigured pre-hook to allow the change, got %v", err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	r, _ := newRunner(t, config.HooksConfig{PreCreate: "sleep 10"})
+	r.timeout = 50 * time.Millisecond
+
+	p := peb.New("peb-aaaa", "Peb", peb.TypeTask, peb.StatusNew, "Content")
+	err := r.Before(nil, p)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected timeout, got %v", err)
+	}
+}
@@ -0,0 +1,227 @@
+package interop
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go.yozora.eu/pebbles/internal/peb"
+)
+
+type BeadsIssue struct {
+	ID                 string            `json:"id"`
+	Title              string            `json:"title"`
+	Description        string            `json:"description,omitempty"`
+	Design             string            `json:"design,omitempty"`
+	AcceptanceCriteria string            `json:"acceptance_criteria,omitempty"`
+	Notes              string            `json:"notes,omitempty"`
+	Status             string            `json:"status"`
+	Priority           int               `json:"priority"`
+	IssueType          string            `json:"issue_type"`
+	Assignee           string            `json:"assignee,omitempty"`
+	Labels             []string          `json:"labels,omitempty"`
+	CreatedAt          string            `json:"created_at,omitempty"`
+	UpdatedAt          string            `json:"updated_at,omitempty"`
+	ClosedAt           string            `json:"closed_at,omitempty"`
+	Dependencies       []BeadsDependency `json:"dependencies,omitempty"`
+}
+
+// BeadsDependency is an edge between two beads issues. For "parent-child"
+// edges, IssueID is the child.
+type BeadsDependency struct {
+	IssueID     string `json:"issue_id"`
+	DependsOnID string `json:"depends_on_id"`
+	Type        string `json:"type"`
+}
+
+const beadsDefaultPriority = 2
+
+var beadsStatuses = map[string]peb.Status{
+	"open":        peb.StatusNew,
+	"blocked":     peb.StatusNew,
+	"in_progress": peb.StatusInProgress,
+	"closed":      peb.StatusFixed,
+}
+
+var beadsTypes = map[string]peb.Type{
+	"bug":     peb.TypeBug,
+	"feature": peb.TypeFeature,
+	"task":    peb.TypeTask,
+	"epic":    peb.TypeEpic,
+	"chore":   peb.TypeTask,
+}
+
+func BeadsSource(id string) string {
+	return "beads:" + id
+}
+
+func ReadBeads(r io.Reader) ([]BeadsIssue, error) {
+	var issues []BeadsIssue
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var issue BeadsIssue
+		if err := json.Unmarshal(data, &issue); err != nil {
+			return nil, fmt.Errorf("failed to parse beads issue on line %d: %w", line, err)
+		}
+		issues = append(issues, issue)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read beads issues: %w", err)
+	}
+	return issues, nil
+}
+
+// FromBeads appends design, acceptance criteria and notes to the content.
+// Parents are blocked by their children, like epics in pebbles.
+func FromBeads(issues []BeadsIssue, existing Existing, newID IDGenerator) (*Result, error) {
+	result := &Result{Mapping: make(map[string]string)}
+
+	records := make([]record, 0, len(issues))
+	index := make(map[string]int, len(issues))
+	for _, issue := range issues {
+		if issue.ID == "" {
+			return nil, fmt.Errorf("beads issue %q has no id", issue.Title)
+		}
+
+		p := &peb.Peb{
+			Title:   issue.Title,
+			Type:    peb.TypeTask,
+			Status:  peb.StatusNew,
+			Content: beadsContent(issue),
+		}
+
+		if t, ok := beadsTypes[issue.IssueType]; ok {
+			p.Type = t
+		} else if issue.IssueType != "" {
+			result.warnf("%s: type %q not mapped", issue.ID, issue.IssueType)
+		}
+		if s, ok := beadsStatuses[issue.Status]; ok {
+			p.Status = s
+		} else if issue.Status != "" {
+			result.warnf("%s: status %q not mapped", issue.ID, issue.Status)
+		}
+		if issue.Priority != beadsDefaultPriority {
+			result.warnf("%s: priority %d not mapped", issue.ID, issue.Priority)
+		}
+		if issue.Assignee != "" {
+			result.warnf("%s: assignee %q not mapped", issue.ID, issue.Assignee)
+		}
+		for _, label := range issue.Labels {
+			result.warnf("%s: label %q not mapped", issue.ID, label)
+		}
+
+		now := time.Now()
+		p.Created = formatForeignTimestamp(issue.CreatedAt, now)
+		p.Changed = formatForeignTimestamp(issue.UpdatedAt, now)
+
+		index[issue.ID] = len(records)
+		records = append(records, record{ref: issue.ID, source: BeadsSource(issue.ID), peb: p})
+	}
+
+	for _, issue := range issues {
+		for _, dep := range issue.Dependencies {
+			from := dep.IssueID
+			if from == "" {
+				from = issue.ID
+			}
+			i, ok := index[from]
+			if !ok {
+				result.warnf("%s: dependency of unknown issue %s not mapped", issue.ID, from)
+				continue
+			}
+			switch dep.Type {
+			case "blocks", "":
+				records[i].blockedBy = append(records[i].blockedBy, dep.DependsOnID)
+			case "parent-child":
+				records[i].blocks = append(records[i].blocks, dep.DependsOnID)
+			default:
+				result.warnf("%s: %s dependency on %s not mapped", from, dep.Type, dep.DependsOnID)
+			}
+		}
+	}
+
+	c := converter{sourceOf: BeadsSource, rewriteMentions: true}
+	if err := c.convert(records, existing, newID, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func beadsContent(issue BeadsIssue) string {
+	sections := []string{strings.TrimSpace(issue.Description)}
+	for _, s := range []struct{ title, text string }{
+		{"Design", issue.Design},
+		{"Acceptance Criteria", issue.AcceptanceCriteria},
+		{"Notes", issue.Notes},
+	} {
+		if text := strings.TrimSpace(s.text); text != "" {
+			sections = append(sections, "## "+s.title+"\n\n"+text)
+		}
+	}
+	content := strings.TrimSpace(strings.Join(sections, "\n\n"))
+	if content == "" {
+		return ""
+	}
+	return content + "\n"
+}
+
+func ToBeads(pebs []*peb.Peb) []BeadsIssue {
+	issues := make([]BeadsIssue, 0, len(pebs))
+	for _, p := range pebs {
+		issue := BeadsIssue{
+			ID:          p.ID,
+			Title:       p.Title,
+			Description: p.Content,
+			Status:      "open",
+			Priority:    beadsDefaultPriority,
+			IssueType:   string(p.Type),
+		}
+		switch p.Status {
+		case peb.StatusInProgress:
+			issue.Status = "in_progress"
+		case peb.StatusFixed, peb.StatusWontFix:
+			issue.Status = "closed"
+		}
+
+		if t, err := peb.ParseTimestamp(p.Created); err == nil {
+			issue.CreatedAt = t.UTC().Format(time.RFC3339)
+		}
+		if t, err := peb.ParseTimestamp(p.Changed); err == nil {
+			issue.UpdatedAt = t.UTC().Format(time.RFC3339)
+			if issue.Status == "closed" {
+				issue.ClosedAt = issue.UpdatedAt
+			}
+		}
+
+		for _, id := range p.BlockedBy {
+			issue.Dependencies = append(issue.Dependencies, BeadsDependency{
+				IssueID:     p.ID,
+				DependsOnID: id,
+				Type:        "blocks",
+			})
+		}
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+func WriteBeads(w io.Writer, issues []BeadsIssue) error {
+	encoder := json.NewEncoder(w)
+	for _, issue := range issues {
+		if err := encoder.Encode(issue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package interop
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.yozora.eu/pebbles/internal/peb"
+)
+
+func TestFromBeads(t *testing.T) {
+	input := `{"id":"bd-1","title":"Epic","description":"Tracks bd-2 and bd-10","status":"open","priority":2,"issue_type":"epic"}
+{"id":"bd-2","title":"Child","description":"Part of it","design":"Use a map","status":"in_progress","priority":1,"issue_type":"chore","labels":["backend"],"dependencies":[{"issue_id":"bd-2","depends_on_id":"bd-1","type":"parent-child"},{"issue_id":"bd-2","depends_on_id":"bd-10","type":"blocks"}]}
+
+{"id":"bd-10","title":"Blocker","status":"closed","priority":2,"issue_type":"bug","created_at":"2024-05-06T07:08:09Z","dependencies":[{"issue_id":"bd-10","depends_on_id":"bd-1","type":"related"}]}
+`
+	issues, err := ReadBeads(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := FromBeads(issues, Existing{}, sequentialIDs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Pebs) != 3 {
+		t.Fatalf("expected 3 pebs, got %d", len(result.Pebs))
+	}
+	epic, child, blocker := result.Pebs[0], result.Pebs[1], result.Pebs[2]
+
+	if epic.Type != peb.TypeEpic || epic.Source != "beads:bd-1" {
+		t.Errorf("unexpected epic %+v", epic)
+	}
+	if epic.Content != "Tracks peb-0002 and peb-0003\n" {
+		t.Errorf("expected mentions to be rewritten, got %q", epic.Content)
+	}
+	if !slices.Equal(epic.BlockedBy, []string{"peb-0002"}) {
+		t.Errorf("expected epic to be blocked by its child, got %v", epic.BlockedBy)
+	}
+
+	if child.Type != peb.TypeTask || child.Status != peb.StatusInProgress {
+		t.Errorf("unexpected child %+v", child)
+	}
+	if child.Content != "Part of it\n\n## Design\n\nUse a map\n" {
+		t.Errorf("unexpected child content %q", child.Content)
+	}
+	if !slices.Equal(child.BlockedBy, []string{"peb-0003"}) {
+		t.Errorf("expected child to be blocked by bd-10, got %v", child.BlockedBy)
+	}
+
+	if blocker.Status != peb.StatusFixed || blocker.Type != peb.TypeBug {
+		t.Errorf("unexpected blocker %+v", blocker)
+	}
+
+	want := []string{
+		"bd-2: priority 1 not mapped",
+		`bd-2: label "backend" not mapped`,
+		"bd-10: related dependency on bd-1 not mapped",
+	}
+	if !slices.Equal(result.Warnings, want) {
+		t.Errorf("unexpected warnings %q", result.Warnings)
+	}
+}
+
+func TestBeadsRoundTrip(t *testing.T) {
+	blocker := peb.New("peb-aaaa", "Blocker", peb.TypeTask, peb.StatusFixed, "Content\n")
+	p := peb.New("peb-bbbb", "Blocked", peb.TypeBug, peb.StatusInProgress, "Content\n")
+	p.BlockedBy = []string{"peb-aaaa"}
+
+	var buf bytes.Buffer
+	if err := WriteBeads(&buf, ToBeads([]*peb.Peb{blocker, p})); err != nil {
+		t.Fatal(err)
+	}
+
+	issues, err := ReadBeads(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := FromBeads(issues, Existing{}, sequentialIDs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Warnings) != 0 {
+		t.Errorf("unexpected warnings %q", result.Warnings)
+	}
+
+	got := result.Pebs[1]
+	if got.Title != p.Title || got.Type != p.Type || got.Status != p.Status || got.Content != p.Content {
+		t.Errorf("round trip changed peb: %+v", got)
+	}
+	if !slices.Equal(got.BlockedBy, []string{result.Mapping["beads:peb-aaaa"]}) {
+		t.Errorf("expected blocked-by to survive the round trip, got %v", got.BlockedBy)
+	}
+}
@@ -0,0 +1,158 @@
+package interop
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.yozora.eu/pebbles/internal/peb"
+	"gopkg.in/yaml.v3"
+)
+
+type Bean struct {
+	ID        string              `yaml:"id"`
+	Title     string              `yaml:"title"`
+	Status    string              `yaml:"status"`
+	Type      string              `yaml:"type"`
+	Priority  string              `yaml:"priority"`
+	Tags      []string            `yaml:"tags"`
+	CreatedAt string              `yaml:"created_at"`
+	UpdatedAt string              `yaml:"updated_at"`
+	Parent    string              `yaml:"parent"`
+	Blocking  []string            `yaml:"blocking"`
+	Links     []map[string]string `yaml:"links"`
+	Body      string              `yaml:"-"`
+	Filename  string              `yaml:"-"`
+}
+
+var beansStatuses = map[string]peb.Status{
+	"draft":       peb.StatusNew,
+	"todo":        peb.StatusNew,
+	"in-progress": peb.StatusInProgress,
+	"completed":   peb.StatusFixed,
+	"scrapped":    peb.StatusWontFix,
+}
+
+var beansTypes = map[string]peb.Type{
+	"bug":       peb.TypeBug,
+	"feature":   peb.TypeFeature,
+	"task":      peb.TypeTask,
+	"epic":      peb.TypeEpic,
+	"milestone": peb.TypeEpic,
+}
+
+const beansDefaultPriority = "normal"
+
+func BeansSource(id string) string {
+	return "beans:" + id
+}
+
+func ReadBeans(dir string) ([]Bean, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read beans directory: %w", err)
+	}
+
+	var beans []Bean
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".md") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bean: %w", err)
+		}
+		bean, err := parseBean(name, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		beans = append(beans, bean)
+	}
+	return beans, nil
+}
+
+func parseBean(filename string, data []byte) (Bean, error) {
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	rest, ok := bytes.CutPrefix(data, []byte("---\n"))
+	if !ok {
+		return Bean{}, fmt.Errorf("missing frontmatter")
+	}
+	frontmatter, body, ok := bytes.Cut(rest, []byte("\n---\n"))
+	if !ok {
+		return Bean{}, fmt.Errorf("unterminated frontmatter")
+	}
+
+	var bean Bean
+	if err := yaml.Unmarshal(frontmatter, &bean); err != nil {
+		return Bean{}, fmt.Errorf("failed to parse frontmatter: %w", err)
+	}
+	bean.Filename = filename
+	bean.Body = strings.TrimLeft(string(body), "\n")
+	if bean.ID == "" {
+		bean.ID, _, _ = strings.Cut(strings.TrimSuffix(filename, ".md"), "--")
+	}
+	return bean, nil
+}
+
+// FromBeans blocks parents by their children, like epics in pebbles.
+func FromBeans(beans []Bean, existing Existing, newID IDGenerator) (*Result, error) {
+	result := &Result{Mapping: make(map[string]string)}
+
+	records := make([]record, 0, len(beans))
+	for _, bean := range beans {
+		p := &peb.Peb{
+			Title:   bean.Title,
+			Type:    peb.TypeTask,
+			Status:  peb.StatusNew,
+			Content: bean.Body,
+		}
+
+		if t, ok := beansTypes[bean.Type]; ok {
+			p.Type = t
+		} else if bean.Type != "" {
+			result.warnf("%s: type %q not mapped", bean.ID, bean.Type)
+		}
+		if s, ok := beansStatuses[bean.Status]; ok {
+			p.Status = s
+		} else if bean.Status != "" {
+			result.warnf("%s: status %q not mapped", bean.ID, bean.Status)
+		}
+		if bean.Priority != "" && bean.Priority != beansDefaultPriority {
+			result.warnf("%s: priority %q not mapped", bean.ID, bean.Priority)
+		}
+		for _, tag := range bean.Tags {
+			result.warnf("%s: tag %q not mapped", bean.ID, tag)
+		}
+
+		now := time.Now()
+		p.Created = formatForeignTimestamp(bean.CreatedAt, now)
+		p.Changed = formatForeignTimestamp(bean.UpdatedAt, now)
+
+		r := record{ref: bean.ID, source: BeansSource(bean.ID), peb: p, blocks: bean.Blocking}
+		if bean.Parent != "" {
+			r.blocks = append(r.blocks, bean.Parent)
+		}
+		// Older versions of beans store relationships as a list of links.
+		for _, link := range bean.Links {
+			for kind, target := range link {
+				switch kind {
+				case "blocks", "parent":
+					r.blocks = append(r.blocks, target)
+				default:
+					result.warnf("%s: %s link to %s not mapped", bean.ID, kind, target)
+				}
+			}
+		}
+		records = append(records, r)
+	}
+
+	c := converter{sourceOf: BeansSource, rewriteMentions: true}
+	if err := c.convert(records, existing, newID, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,69 @@
+package interop
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"go.yozora.eu/pebbles/internal/peb"
+)
+
+func TestFromBeans(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"beans-ep01--launch.md": "---\n# beans-ep01\ntitle: Launch\nstatus: todo\ntype: milestone\n---\n\nShip it.\n",
+		"beans-t001--write-docs.md": "---\n# beans-t001\ntitle: Write docs\nstatus: in-progress\ntype: task\npriority: high\n" +
+			"parent: beans-ep01\nblocking:\n  - beans-t002\n---\n\nSee beans-t002.\r\n",
+		"beans-t002--publish.md": "---\ntitle: Publish\nstatus: scrapped\ntype: feature\ntags: [web]\n" +
+			"links:\n  - related: beans-ep01\n---\n",
+		"notes.txt": "not a bean",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	beans, err := ReadBeans(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(beans) != 3 {
+		t.Fatalf("expected 3 beans, got %d", len(beans))
+	}
+
+	result, err := FromBeans(beans, Existing{}, sequentialIDs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := result.Mapping
+	launch, docs, publish := result.Pebs[0], result.Pebs[1], result.Pebs[2]
+
+	if launch.Type != peb.TypeEpic || launch.Status != peb.StatusNew || launch.Content != "Ship it.\n" {
+		t.Errorf("unexpected milestone %+v", launch)
+	}
+	if !slices.Equal(launch.BlockedBy, []string{ids["beans:beans-t001"]}) {
+		t.Errorf("expected milestone to be blocked by its child, got %v", launch.BlockedBy)
+	}
+
+	if docs.Status != peb.StatusInProgress || docs.Content != "See "+ids["beans:beans-t002"]+".\n" {
+		t.Errorf("unexpected docs peb %+v", docs)
+	}
+
+	if publish.Status != peb.StatusWontFix || publish.Type != peb.TypeFeature {
+		t.Errorf("unexpected publish peb %+v", publish)
+	}
+	if !slices.Equal(publish.BlockedBy, []string{ids["beans:beans-t001"]}) {
+		t.Errorf("expected publish to be blocked by docs, got %v", publish.BlockedBy)
+	}
+
+	want := []string{
+		`beans-t001: priority "high" not mapped`,
+		`beans-t002: tag "web" not mapped`,
+		"beans-t002: related link to beans-ep01 not mapped",
+	}
+	if !slices.Equal(result.Warnings, want) {
+		t.Errorf("unexpected warnings %q", result.Warnings)
+	}
+}
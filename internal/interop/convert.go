@@ -0,0 +1,108 @@
+package interop
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"go.yozora.eu/pebbles/internal/peb"
+)
+
+// record is a converted peb whose ID and references are not assigned yet.
+type record struct {
+	ref       string
+	source    string
+	peb       *peb.Peb
+	blockedBy []string
+	blocks    []string
+}
+
+type converter struct {
+	sourceOf        func(ref string) string
+	rewriteMentions bool
+}
+
+func (c converter) convert(records []record, existing Existing, newID IDGenerator, result *Result) error {
+	ids := make(map[string]string)
+	var imported []*record
+
+	for i := range records {
+		r := &records[i]
+		if id, ok := existing[r.source]; ok {
+			ids[r.ref] = id
+			result.Mapping[r.source] = id
+			result.warnf("%s: already imported as %s, skipped", r.ref, id)
+			continue
+		}
+
+		id, err := newID()
+		if err != nil {
+			return err
+		}
+		r.peb.ID = id
+		r.peb.Source = r.source
+		ids[r.ref] = id
+		result.Mapping[r.source] = id
+		imported = append(imported, r)
+	}
+
+	resolve := func(from, ref string) (string, bool) {
+		if id, ok := ids[ref]; ok {
+			return id, true
+		}
+		if id, ok := existing[c.sourceOf(ref)]; ok {
+			return id, true
+		}
+		result.warnf("%s: reference to %s not found", from, ref)
+		return "", false
+	}
+
+	byID := make(map[string]*peb.Peb, len(imported))
+	for _, r := range imported {
+		byID[r.peb.ID] = r.peb
+	}
+	addBlocker := func(p *peb.Peb, id string) {
+		if id != p.ID && !slices.Contains(p.BlockedBy, id) {
+			p.BlockedBy = append(p.BlockedBy, id)
+		}
+	}
+
+	for _, r := range imported {
+		for _, ref := range r.blockedBy {
+			if id, ok := resolve(r.ref, ref); ok {
+				addBlocker(r.peb, id)
+			}
+		}
+		for _, ref := range r.blocks {
+			id, ok := resolve(r.ref, ref)
+			if !ok {
+				continue
+			}
+			if blocked, ok := byID[id]; ok {
+				addBlocker(blocked, r.peb.ID)
+			} else {
+				result.warnf("%s: blocks %s, which was imported before; add the reference with peb update", r.ref, id)
+			}
+		}
+	}
+
+	if c.rewriteMentions && len(ids) > 0 {
+		refs := make([]string, 0, len(ids))
+		for ref := range ids {
+			refs = append(refs, regexp.QuoteMeta(ref))
+		}
+		// A ref that is a prefix of another one must not win.
+		slices.SortFunc(refs, func(a, b string) int { return len(b) - len(a) })
+		pattern := regexp.MustCompile(`\b(` + strings.Join(refs, "|") + `)\b`)
+		for _, r := range imported {
+			r.peb.Content = pattern.ReplaceAllStringFunc(r.peb.Content, func(ref string) string {
+				return ids[ref]
+			})
+		}
+	}
+
+	for _, r := range imported {
+		result.Pebs = append(result.Pebs, r.peb)
+	}
+	return nil
+}
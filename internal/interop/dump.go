@@ -0,0 +1,186 @@
+package interop
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"go.yozora.eu/pebbles/internal/peb"
+)
+
+const DumpVersion = 1
+
+// Dump is a full-fidelity copy of a set of pebs, optionally with the
+// config.toml it belongs to.
+type Dump struct {
+	Version int        `json:"version"`
+	Config  string     `json:"config,omitempty"`
+	Pebs    []*peb.Peb `json:"pebs"`
+}
+
+type dumpHeader struct {
+	Version int    `json:"version"`
+	Config  string `json:"config,omitempty"`
+}
+
+func WriteDump(w io.Writer, d *Dump) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(d)
+}
+
+// WriteDumpLines writes a header with the version and the config, followed
+// by one line per peb.
+func WriteDumpLines(w io.Writer, d *Dump) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(dumpHeader{Version: d.Version, Config: d.Config}); err != nil {
+		return err
+	}
+	for _, p := range d.Pebs {
+		if err := encoder.Encode(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadDump accepts JSON lines without a header, so that fixtures can be
+// written by hand.
+func ReadDump(r io.Reader) (*Dump, error) {
+	decoder := json.NewDecoder(r)
+	d := &Dump{Version: DumpVersion}
+
+	for n := 1; ; n++ {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to parse dump: %w", err)
+		}
+
+		var keys map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &keys); err != nil {
+			return nil, fmt.Errorf("failed to parse dump entry %d: %w", n, err)
+		}
+
+		_, isPeb := keys["id"]
+		switch {
+		case isPeb:
+			var p peb.Peb
+			if err := json.Unmarshal(raw, &p); err != nil {
+				return nil, fmt.Errorf("failed to parse dump entry %d: %w", n, err)
+			}
+			d.Pebs = append(d.Pebs, &p)
+		case n == 1:
+			if err := json.Unmarshal(raw, d); err != nil {
+				return nil, fmt.Errorf("failed to parse dump: %w", err)
+			}
+		default:
+			return nil, fmt.Errorf("dump entry %d is not a peb", n)
+		}
+	}
+
+	if d.Version > DumpVersion {
+		return nil, fmt.Errorf("unsupported dump version %d (this version of peb supports up to %d)", d.Version, DumpVersion)
+	}
+
+	seen := make(map[string]bool, len(d.Pebs))
+	for i, p := range d.Pebs {
+		if p.ID == "" {
+			return nil, fmt.Errorf("peb %d in dump has no id", i+1)
+		}
+		if seen[p.ID] {
+			return nil, fmt.Errorf("peb %s appears more than once in dump", p.ID)
+		}
+		seen[p.ID] = true
+	}
+	return d, nil
+}
+
+type ConflictPolicy string
+
+const (
+	ConflictError     ConflictPolicy = "error"
+	ConflictSkip      ConflictPolicy = "skip"
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	// ConflictReID also rewrites the references in the other pebs of the
+	// dump.
+	ConflictReID ConflictPolicy = "reid"
+)
+
+var ConflictPolicies = []ConflictPolicy{ConflictError, ConflictSkip, ConflictOverwrite, ConflictReID}
+
+// FromDump restores pebs unchanged, except for references to pebs moved to a
+// new ID with ConflictReID.
+func FromDump(d *Dump, exists func(id string) bool, policy ConflictPolicy, newID IDGenerator) (*Result, error) {
+	if !slices.Contains(ConflictPolicies, policy) {
+		return nil, fmt.Errorf("unknown conflict policy %q", policy)
+	}
+
+	result := &Result{Mapping: make(map[string]string), Config: d.Config}
+
+	inDump := make(map[string]bool, len(d.Pebs))
+	for _, p := range d.Pebs {
+		inDump[p.ID] = true
+	}
+
+	var conflicts []string
+	renamed := make(map[string]string)
+	for _, p := range d.Pebs {
+		p := *p
+		if !exists(p.ID) {
+			result.Mapping[p.ID] = p.ID
+			result.Pebs = append(result.Pebs, &p)
+			continue
+		}
+
+		switch policy {
+		case ConflictError:
+			conflicts = append(conflicts, p.ID)
+		case ConflictSkip:
+			result.Mapping[p.ID] = p.ID
+			result.warnf("%s: already exists, skipped", p.ID)
+		case ConflictOverwrite:
+			result.Mapping[p.ID] = p.ID
+			result.Pebs = append(result.Pebs, &p)
+		case ConflictReID:
+			id, err := newID()
+			for err == nil && inDump[id] {
+				id, err = newID()
+			}
+			if err != nil {
+				return nil, err
+			}
+			renamed[p.ID] = id
+			result.Mapping[p.ID] = id
+			p.ID = id
+			result.Pebs = append(result.Pebs, &p)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("pebs already exist: %s", strings.Join(conflicts, ", "))
+	}
+
+	if len(renamed) > 0 {
+		for _, p := range result.Pebs {
+			if len(p.BlockedBy) > 0 {
+				blockedBy := make([]string, len(p.BlockedBy))
+				for i, id := range p.BlockedBy {
+					if to, ok := renamed[id]; ok {
+						id = to
+					}
+					blockedBy[i] = id
+				}
+				p.BlockedBy = blockedBy
+			}
+			p.Content = peb.ReplaceIDs(p.Content, renamed)
+		}
+	}
+	return result, nil
+}
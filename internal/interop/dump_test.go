@@ -0,0 +1,70 @@
+package interop
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadDump(t *testing.T) {
+	// Hand-written fixtures may leave out the header.
+	input := `{"id": "peb-aaaa", "title": "One", "type": "bug", "status": "new", "created": "", "changed": "", "content": ""}
+{"id": "peb-bbbb", "title": "Two", "type": "task", "status": "fixed", "created": "", "changed": "", "blocked-by": ["peb-aaaa"], "content": "Text\n"}
+`
+	d, err := ReadDump(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Version != DumpVersion || len(d.Pebs) != 2 || d.Pebs[1].Content != "Text\n" {
+		t.Errorf("unexpected dump %+v", d)
+	}
+
+	for input, want := range map[string]string{
+		`{"version": 2, "pebs": []}`:                        "unsupported dump version 2",
+		`{"version": 1}` + "\n" + `{"version": 1}`:          "dump entry 2 is not a peb",
+		`{"id": "peb-aaaa"}` + "\n" + `{"id": "peb-aaaa"}`:  "peb-aaaa appears more than once",
+		`{"version": 1, "pebs": [{"id": ""}]}`:              "peb 1 in dump has no id",
+		`{"version": 1, "pebs": [{"id": "peb-aaaa"}]} junk`: "failed to parse dump",
+	} {
+		if _, err := ReadDump(strings.NewReader(input)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ReadDump(%s) = %v, want error containing %q", input, err, want)
+		}
+	}
+}
+
+func TestFromDumpReID(t *testing.T) {
+	d, err := ReadDump(strings.NewReader(`{"version": 1, "pebs": [
+  {"id": "peb-0001", "title": "Blocker", "content": "See peb-0001x"},
+  {"id": "peb-0002", "title": "Blocked", "blocked-by": ["peb-0001", "peb-9999"], "content": "After peb-0001."}
+]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exists := func(id string) bool { return id == "peb-0001" }
+	// The first generated ID is in the dump and must not be used.
+	ids := sequentialIDs()
+	ids()
+
+	result, err := FromDump(d, exists, ConflictReID, ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Mapping["peb-0001"] != "peb-0003" || result.Mapping["peb-0002"] != "peb-0002" {
+		t.Errorf("unexpected mapping %v", result.Mapping)
+	}
+	blocker, blocked := result.Pebs[0], result.Pebs[1]
+	if blocker.ID != "peb-0003" || blocker.Content != "See peb-0001x" {
+		t.Errorf("unexpected peb %+v", blocker)
+	}
+	if !slices.Equal(blocked.BlockedBy, []string{"peb-0003", "peb-9999"}) || blocked.Content != "After peb-0003." {
+		t.Errorf("expected references to be rewritten, got %+v", blocked)
+	}
+	if d.Pebs[0].ID != "peb-0001" {
+		t.Error("FromDump must not modify the dump")
+	}
+
+	if _, err := FromDump(d, exists, ConflictError, ids); err == nil || !strings.Contains(err.Error(), "peb-0001") {
+		t.Errorf("expected conflict error, got %v", err)
+	}
+}
@@ -0,0 +1,260 @@
+package interop
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.yozora.eu/pebbles/internal/peb"
+)
+
+// GitHubIssue also accepts the GitLab field names (iid, description,
+// "opened") on import.
+type GitHubIssue struct {
+	Number      int          `json:"number,omitempty"`
+	IID         int          `json:"iid,omitempty"`
+	Title       string       `json:"title"`
+	Body        string       `json:"body"`
+	Description string       `json:"description,omitempty"`
+	State       string       `json:"state"`
+	StateReason string       `json:"stateReason,omitempty"`
+	Labels      GitHubLabels `json:"labels"`
+	CreatedAt   string       `json:"createdAt,omitempty"`
+	UpdatedAt   string       `json:"updatedAt,omitempty"`
+	ClosedAt    string       `json:"closedAt,omitempty"`
+}
+
+// GitHubLabels unmarshals from both label objects and plain strings.
+type GitHubLabels []string
+
+func (l *GitHubLabels) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*l = nil
+	for _, r := range raw {
+		var name string
+		if err := json.Unmarshal(r, &name); err == nil {
+			*l = append(*l, name)
+			continue
+		}
+		var label struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(r, &label); err != nil {
+			return err
+		}
+		*l = append(*l, label.Name)
+	}
+	return nil
+}
+
+func (l GitHubLabels) MarshalJSON() ([]byte, error) {
+	labels := make([]struct {
+		Name string `json:"name"`
+	}, len(l))
+	for i, name := range l {
+		labels[i].Name = name
+	}
+	return json.Marshal(labels)
+}
+
+func GitHubSource(n int) string {
+	return "github#" + strconv.Itoa(n)
+}
+
+func githubNumber(source string) (int, bool) {
+	n, ok := strings.CutPrefix(source, "github#")
+	if !ok {
+		return 0, false
+	}
+	number, err := strconv.Atoi(n)
+	return number, err == nil
+}
+
+var githubTypes = map[string]peb.Type{
+	"bug":         peb.TypeBug,
+	"feature":     peb.TypeFeature,
+	"enhancement": peb.TypeFeature,
+	"epic":        peb.TypeEpic,
+	"task":        peb.TypeTask,
+}
+
+var githubInProgress = []string{"in-progress", "in progress"}
+
+// blockedByPattern matches "blocked by #12" and lists like
+// "Blocked by: #12, #13 and #14".
+var blockedByPattern = regexp.MustCompile(`(?i)blocked by:?\s*(#\d+(?:(?:\s*,\s*|\s+and\s+|\s+)#\d+)*)`)
+var issueRefPattern = regexp.MustCompile(`#(\d+)`)
+
+func parseBlockedBy(body string) []int {
+	var numbers []int
+	for _, m := range blockedByPattern.FindAllStringSubmatch(body, -1) {
+		for _, ref := range issueRefPattern.FindAllStringSubmatch(m[1], -1) {
+			n, _ := strconv.Atoi(ref[1])
+			if !slices.Contains(numbers, n) {
+				numbers = append(numbers, n)
+			}
+		}
+	}
+	return numbers
+}
+
+func ReadGitHub(r io.Reader) ([]GitHubIssue, error) {
+	var issues []GitHubIssue
+	if err := json.NewDecoder(r).Decode(&issues); err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub issues: %w", err)
+	}
+	return issues, nil
+}
+
+// FromGitHub skips issues whose source is in existing, but resolves
+// references to them.
+func FromGitHub(issues []GitHubIssue, existing Existing, newID IDGenerator) (*Result, error) {
+	result := &Result{Mapping: make(map[string]string)}
+
+	records := make([]record, 0, len(issues))
+	for _, issue := range issues {
+		number := issue.Number
+		if number == 0 {
+			number = issue.IID
+		}
+		if number == 0 {
+			return nil, fmt.Errorf("issue %q has no number", issue.Title)
+		}
+		ref := "#" + strconv.Itoa(number)
+
+		body := issue.Body
+		if body == "" {
+			body = issue.Description
+		}
+		body = strings.ReplaceAll(body, "\r\n", "\n")
+
+		p := &peb.Peb{
+			Title:   issue.Title,
+			Type:    peb.TypeBug,
+			Status:  peb.StatusNew,
+			Content: body,
+		}
+
+		typed := false
+		var labels []string
+		for _, label := range issue.Labels {
+			name := strings.ToLower(label)
+			switch {
+			case githubTypes[name] != "" && !typed:
+				p.Type = githubTypes[name]
+				typed = true
+			case slices.Contains(githubInProgress, name):
+				p.Status = peb.StatusInProgress
+			default:
+				labels = append(labels, label)
+			}
+		}
+		p.Extra.SetStrings(peb.LabelsKey, labels)
+
+		switch strings.ToLower(issue.State) {
+		case "open", "opened", "":
+		case "closed":
+			p.Status = peb.StatusFixed
+			if strings.EqualFold(issue.StateReason, "not_planned") {
+				p.Status = peb.StatusWontFix
+			}
+		default:
+			result.warnf("%s: state %q not mapped", ref, issue.State)
+		}
+
+		now := time.Now()
+		p.Created = formatForeignTimestamp(issue.CreatedAt, now)
+		changed := issue.UpdatedAt
+		if changed == "" {
+			changed = issue.ClosedAt
+		}
+		p.Changed = formatForeignTimestamp(changed, now)
+
+		var blockedBy []string
+		for _, n := range parseBlockedBy(body) {
+			blockedBy = append(blockedBy, "#"+strconv.Itoa(n))
+		}
+		records = append(records, record{ref: ref, source: GitHubSource(number), peb: p, blockedBy: blockedBy})
+	}
+
+	c := converter{sourceOf: func(ref string) string {
+		return "github" + ref
+	}}
+	if err := c.convert(records, existing, newID, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func formatForeignTimestamp(s string, fallback time.Time) string {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t = fallback
+	}
+	return peb.FormatTimestamp(t)
+}
+
+// ToGitHub adds blockers that have an issue number to the body as "Blocked
+// by #N" unless it already mentions them.
+func ToGitHub(pebs []*peb.Peb, lookup func(id string) (*peb.Peb, bool)) []GitHubIssue {
+	issues := make([]GitHubIssue, 0, len(pebs))
+	for _, p := range pebs {
+		issue := GitHubIssue{
+			Title:  p.Title,
+			Body:   p.Content,
+			State:  "OPEN",
+			Labels: GitHubLabels{string(p.Type)},
+		}
+		issue.Number, _ = githubNumber(p.Source)
+
+		for _, label := range p.Extra.Strings(peb.LabelsKey) {
+			if !slices.Contains(issue.Labels, label) {
+				issue.Labels = append(issue.Labels, label)
+			}
+		}
+
+		switch p.Status {
+		case peb.StatusInProgress:
+			issue.Labels = append(issue.Labels, "in-progress")
+		case peb.StatusFixed:
+			issue.State = "CLOSED"
+			issue.StateReason = "COMPLETED"
+		case peb.StatusWontFix:
+			issue.State = "CLOSED"
+			issue.StateReason = "NOT_PLANNED"
+		}
+
+		if t, err := peb.ParseTimestamp(p.Created); err == nil {
+			issue.CreatedAt = t.UTC().Format(time.RFC3339)
+		}
+		if t, err := peb.ParseTimestamp(p.Changed); err == nil {
+			issue.UpdatedAt = t.UTC().Format(time.RFC3339)
+		}
+
+		mentioned := parseBlockedBy(p.Content)
+		var refs []string
+		for _, id := range p.BlockedBy {
+			blocker, ok := lookup(id)
+			if !ok {
+				continue
+			}
+			if n, ok := githubNumber(blocker.Source); ok && !slices.Contains(mentioned, n) {
+				refs = append(refs, "#"+strconv.Itoa(n))
+			}
+		}
+		if len(refs) > 0 {
+			issue.Body = strings.TrimRight(issue.Body, "\n") + "\n\nBlocked by " + strings.Join(refs, ", ") + "\n"
+		}
+
+		issues = append(issues, issue)
+	}
+	return issues
+}
@@ -0,0 +1,147 @@
+package interop
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.yozora.eu/pebbles/internal/peb"
+)
+
+func sequentialIDs() IDGenerator {
+	n := 0
+	return func() (string, error) {
+		n++
+		return fmt.Sprintf("peb-%04d", n), nil
+	}
+}
+
+func TestParseBlockedBy(t *testing.T) {
+	tests := map[string][]int{
+		"Blocked by #12":                    {12},
+		"blocked by: #1, #2 and #3":         {1, 2, 3},
+		"Blocked by #4\nAlso blocked by #4": {4},
+		"Related to #5":                     nil,
+		"blocked by someone":                nil,
+	}
+	for body, want := range tests {
+		if got := parseBlockedBy(body); !slices.Equal(got, want) {
+			t.Errorf("parseBlockedBy(%q) = %v, want %v", body, got, want)
+		}
+	}
+}
+
+func TestFromGitHub(t *testing.T) {
+	input := `[
+  {"number": 1, "title": "Crash", "body": "Blocked by #2 and #9\r\n", "state": "OPEN",
+   "labels": [{"name": "bug"}, {"name": "in-progress"}, {"name": "help wanted"}],
+   "createdAt": "2024-01-02T03:04:05Z", "updatedAt": "2024-01-03T03:04:05Z"},
+  {"number": 2, "title": "Feature", "body": "", "state": "CLOSED", "stateReason": "COMPLETED",
+   "labels": [{"name": "enhancement"}]},
+  {"iid": 3, "title": "GitLab issue", "description": "Dropped", "state": "closed",
+   "labels": ["task"]},
+  {"number": 4, "title": "Not planned", "body": "blocked by #5", "state": "CLOSED", "stateReason": "NOT_PLANNED",
+   "labels": []}
+]`
+	issues, err := ReadGitHub(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := FromGitHub(issues, Existing{"github#5": "peb-old1"}, sequentialIDs())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Pebs) != 4 {
+		t.Fatalf("expected 4 pebs, got %d", len(result.Pebs))
+	}
+
+	crash := result.Pebs[0]
+	if crash.Type != peb.TypeBug || crash.Status != peb.StatusInProgress || crash.Source != "github#1" {
+		t.Errorf("unexpected peb %+v", crash)
+	}
+	if crash.Content != "Blocked by #2 and #9\n" {
+		t.Errorf("expected normalized line endings, got %q", crash.Content)
+	}
+	if !slices.Equal(crash.BlockedBy, []string{"peb-0002"}) {
+		t.Errorf("expected blocked by peb-0002, got %v", crash.BlockedBy)
+	}
+	created, _ := peb.ParseTimestamp(crash.Created)
+	if created.UTC().Format("2006-01-02T15:04:05") != "2024-01-02T03:04:05" {
+		t.Errorf("unexpected created timestamp %s", crash.Created)
+	}
+
+	if p := result.Pebs[1]; p.Type != peb.TypeFeature || p.Status != peb.StatusFixed {
+		t.Errorf("unexpected peb %+v", p)
+	}
+	if p := result.Pebs[2]; p.Type != peb.TypeTask || p.Status != peb.StatusFixed || p.Content != "Dropped" || p.Source != "github#3" {
+		t.Errorf("unexpected GitLab peb %+v", p)
+	}
+	if p := result.Pebs[3]; p.Status != peb.StatusWontFix || !slices.Equal(p.BlockedBy, []string{"peb-old1"}) {
+		t.Errorf("unexpected peb %+v", p)
+	}
+
+	if labels := crash.Extra.Strings(peb.LabelsKey); !slices.Equal(labels, []string{"help wanted"}) {
+		t.Errorf("expected label help wanted, got %v", labels)
+	}
+	if _, value := result.Pebs[1].Extra.Lookup(peb.LabelsKey); value != nil {
+		t.Errorf("expected no labels, got %v", value)
+	}
+
+	wantWarnings := []string{
+		"#1: reference to #9 not found",
+	}
+	if !slices.Equal(result.Warnings, wantWarnings) {
+		t.Errorf("unexpected warnings %q", result.Warnings)
+	}
+}
+
+func TestFromGitHubSkipsExisting(t *testing.T) {
+	issues := []GitHubIssue{{Number: 1, Title: "Existing", State: "OPEN"}}
+	result, err := FromGitHub(issues, Existing{"github#1": "peb-aaaa"}, sequentialIDs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Pebs) != 0 || result.Mapping["github#1"] != "peb-aaaa" {
+		t.Errorf("expected existing issue to be skipped, got %+v", result)
+	}
+}
+
+func TestToGitHub(t *testing.T) {
+	blocker := peb.New("peb-aaaa", "Blocker", peb.TypeTask, peb.StatusWontFix, "Content")
+	blocker.Source = "github#7"
+	p := peb.New("peb-bbbb", "Blocked", peb.TypeBug, peb.StatusInProgress, "Content\n")
+	p.BlockedBy = []string{"peb-aaaa", "peb-cccc"}
+
+	pebs := map[string]*peb.Peb{blocker.ID: blocker, p.ID: p}
+	lookup := func(id string) (*peb.Peb, bool) {
+		p, ok := pebs[id]
+		return p, ok
+	}
+
+	issues := ToGitHub([]*peb.Peb{blocker, p}, lookup)
+
+	if issues[0].Number != 7 || issues[0].State != "CLOSED" || issues[0].StateReason != "NOT_PLANNED" {
+		t.Errorf("unexpected issue %+v", issues[0])
+	}
+	if issues[1].Number != 0 || issues[1].State != "OPEN" || !slices.Equal(issues[1].Labels, GitHubLabels{"bug", "in-progress"}) {
+		t.Errorf("unexpected issue %+v", issues[1])
+	}
+	if issues[1].Body != "Content\n\nBlocked by #7\n" {
+		t.Errorf("unexpected body %q", issues[1].Body)
+	}
+
+	// Other labels are exported after the type.
+	p.Extra.SetStrings(peb.LabelsKey, []string{"help wanted", "bug"})
+	if issues := ToGitHub([]*peb.Peb{p}, lookup); !slices.Equal(issues[0].Labels, GitHubLabels{"bug", "help wanted", "in-progress"}) {
+		t.Errorf("unexpected labels %v", issues[0].Labels)
+	}
+
+	// Exporting an imported issue must not repeat its blocked-by line.
+	p.Content = issues[1].Body
+	if issues := ToGitHub([]*peb.Peb{p}, lookup); issues[0].Body != p.Content {
+		t.Errorf("expected body to be unchanged, got %q", issues[0].Body)
+	}
+}
@@ -0,0 +1,36 @@
+// Package interop converts pebs from and to the data formats of other issue
+// trackers.
+package interop
+
+import (
+	"fmt"
+
+	"go.yozora.eu/pebbles/internal/peb"
+)
+
+type Result struct {
+	Pebs []*peb.Peb
+	// Mapping maps source references, e.g. "github#12", to peb IDs.
+	Mapping  map[string]string
+	Warnings []string
+	Config   string
+}
+
+func (r *Result) warnf(format string, args ...any) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+type IDGenerator func() (string, error)
+
+// Existing maps the source of pebs that were imported before to their IDs.
+type Existing map[string]string
+
+func SourceIndex(pebs []*peb.Peb) Existing {
+	index := make(Existing)
+	for _, p := range pebs {
+		if p.Source != "" {
+			index[p.Source] = p.ID
+		}
+	}
+	return index
+}
@@ -0,0 +1,360 @@
+// Package mcp implements a minimal Model Context Protocol server speaking
+// JSON-RPC 2.0 over newline-delimited stdio.
+package mcp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const ProtocolVersion = "2025-06-18"
+
+var supportedVersions = []string{"2024-11-05", "2025-03-26", ProtocolVersion}
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// Tool is a callable tool. An error of Handler is reported as a tool error
+// result, not as a protocol error.
+type Tool struct {
+	Name        string
+	Description string
+	InputSchema json.RawMessage
+	Handler     func(args json.RawMessage) (string, error)
+}
+
+type Prompt struct {
+	Name        string
+	Description string
+	Handler     func() (string, error)
+}
+
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text"`
+}
+
+type Server struct {
+	Name         string
+	Version      string
+	Instructions string
+	Tools        []Tool
+	Prompts      []Prompt
+
+	// ListResources and ReadResource may be nil.
+	ListResources func() ([]Resource, error)
+	ReadResource  func(uri string) (*ResourceContents, error)
+
+	// Handlers need not be safe for concurrent use.
+	mu sync.Mutex
+}
+
+var ErrUnknownResource = errors.New("unknown resource")
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+type textContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		out := s.Handle(line)
+		if out == nil {
+			continue
+		}
+		if _, err := w.Write(append(out, '\n')); err != nil {
+			return fmt.Errorf("failed to write response: %w", err)
+		}
+	}
+	return scanner.Err()
+}
+
+// Handle returns the encoded response to msg, or nil for a notification.
+func (s *Server) Handle(msg []byte) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var req request
+	if err := json.Unmarshal(msg, &req); err != nil {
+		return encode(response{
+			JSONRPC: "2.0",
+			ID:      json.RawMessage("null"),
+			Error:   &rpcError{Code: codeParseError, Message: err.Error()},
+		})
+	}
+
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		if len(req.ID) == 0 {
+			return nil
+		}
+		return encode(response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   &rpcError{Code: codeInvalidRequest, Message: "invalid JSON-RPC 2.0 request"},
+		})
+	}
+
+	// Notifications never get a response, not even an error.
+	if len(req.ID) == 0 {
+		if !strings.HasPrefix(req.Method, "notifications/") {
+			s.dispatch(req.Method, req.Params)
+		}
+		return nil
+	}
+
+	result, err := s.dispatch(req.Method, req.Params)
+
+	resp := response{JSONRPC: "2.0", ID: req.ID, Result: result}
+	if err != nil {
+		var rerr *rpcError
+		if !errors.As(err, &rerr) {
+			rerr = &rpcError{Code: codeInternalError, Message: err.Error()}
+		}
+		resp.Result = nil
+		resp.Error = rerr
+	}
+	return encode(resp)
+}
+
+func encode(resp response) []byte {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		data, _ = json.Marshal(response{
+			JSONRPC: "2.0",
+			ID:      resp.ID,
+			Error:   &rpcError{Code: codeInternalError, Message: err.Error()},
+		})
+	}
+	return data
+}
+
+func (s *Server) dispatch(method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "initialize":
+		return s.initialize(params)
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		return s.listTools(), nil
+	case "tools/call":
+		return s.callTool(params)
+	case "prompts/list":
+		return s.listPrompts(), nil
+	case "prompts/get":
+		return s.getPrompt(params)
+	case "resources/list":
+		return s.listResources()
+	case "resources/read":
+		return s.readResource(params)
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: "method not found: " + method}
+}
+
+func (s *Server) initialize(params json.RawMessage) (any, error) {
+	var p struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+	}
+
+	version := ProtocolVersion
+	if slices.Contains(supportedVersions, p.ProtocolVersion) {
+		version = p.ProtocolVersion
+	}
+
+	capabilities := map[string]any{
+		"tools":   map[string]any{},
+		"prompts": map[string]any{},
+	}
+	if s.ListResources != nil {
+		capabilities["resources"] = map[string]any{}
+	}
+
+	return map[string]any{
+		"protocolVersion": version,
+		"capabilities":    capabilities,
+		"serverInfo": map[string]string{
+			"name":    s.Name,
+			"version": s.Version,
+		},
+		"instructions": s.Instructions,
+	}, nil
+}
+
+func (s *Server) listTools() any {
+	type toolInfo struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description"`
+		InputSchema json.RawMessage `json:"inputSchema"`
+	}
+	tools := make([]toolInfo, 0, len(s.Tools))
+	for _, t := range s.Tools {
+		schema := t.InputSchema
+		if schema == nil {
+			schema = json.RawMessage(`{"type":"object"}`)
+		}
+		tools = append(tools, toolInfo{Name: t.Name, Description: t.Description, InputSchema: schema})
+	}
+	return map[string]any{"tools": tools}
+}
+
+func (s *Server) callTool(params json.RawMessage) (any, error) {
+	var p struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+	}
+
+	for _, t := range s.Tools {
+		if t.Name != p.Name {
+			continue
+		}
+		args := p.Arguments
+		if len(args) == 0 || string(args) == "null" {
+			args = json.RawMessage("{}")
+		}
+		text, err := t.Handler(args)
+		if err != nil {
+			if text != "" {
+				text += "\n"
+			}
+			return map[string]any{
+				"content": []textContent{{Type: "text", Text: text + "Error: " + err.Error()}},
+				"isError": true,
+			}, nil
+		}
+		return map[string]any{
+			"content": []textContent{{Type: "text", Text: text}},
+			"isError": false,
+		}, nil
+	}
+	return nil, &rpcError{Code: codeInvalidParams, Message: "unknown tool: " + p.Name}
+}
+
+func (s *Server) listPrompts() any {
+	type promptInfo struct {
+		Name        string `json:"name"`
+		Description string `json:"description,omitempty"`
+	}
+	prompts := make([]promptInfo, 0, len(s.Prompts))
+	for _, p := range s.Prompts {
+		prompts = append(prompts, promptInfo{Name: p.Name, Description: p.Description})
+	}
+	return map[string]any{"prompts": prompts}
+}
+
+func (s *Server) getPrompt(params json.RawMessage) (any, error) {
+	var p struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+	}
+
+	for _, prompt := range s.Prompts {
+		if prompt.Name != p.Name {
+			continue
+		}
+		text, err := prompt.Handler()
+		if err != nil {
+			return nil, err
+		}
+		type message struct {
+			Role    string      `json:"role"`
+			Content textContent `json:"content"`
+		}
+		return map[string]any{
+			"description": prompt.Description,
+			"messages":    []message{{Role: "user", Content: textContent{Type: "text", Text: text}}},
+		}, nil
+	}
+	return nil, &rpcError{Code: codeInvalidParams, Message: "unknown prompt: " + p.Name}
+}
+
+func (s *Server) listResources() (any, error) {
+	resources := []Resource{}
+	if s.ListResources != nil {
+		list, err := s.ListResources()
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, list...)
+	}
+	return map[string]any{"resources": resources}, nil
+}
+
+func (s *Server) readResource(params json.RawMessage) (any, error) {
+	var p struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	if s.ReadResource == nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "unknown resource: " + p.URI}
+	}
+
+	contents, err := s.ReadResource(p.URI)
+	if errors.Is(err, ErrUnknownResource) {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "unknown resource: " + p.URI}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"contents": []*ResourceContents{contents}}, nil
+}
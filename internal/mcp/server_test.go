@@ -0,0 +1,218 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testServer() *Server {
+	return &Server{
+		Name:    "test",
+		Version: "1.0",
+		Tools: []Tool{
+			{
+				Name:        "echo",
+				Description: "Echo the text argument",
+				InputSchema: json.RawMessage(`{"type":"object","properties":{"text":{"type":"string"}}}`),
+				Handler: func(args json.RawMessage) (string, error) {
+					var p struct {
+						Text string `json:"text"`
+					}
+					if err := json.Unmarshal(args, &p); err != nil {
+						return "", err
+					}
+					if p.Text == "" {
+						return "partial", errors.New("text is required")
+					}
+					return p.Text, nil
+				},
+			},
+		},
+		Prompts: []Prompt{
+			{
+				Name:        "hello",
+				Description: "Say hello",
+				Handler: func() (string, error) {
+					return "Hello!", nil
+				},
+			},
+		},
+		ListResources: func() ([]Resource, error) {
+			return []Resource{{URI: "test://a", Name: "a"}}, nil
+		},
+		ReadResource: func(uri string) (*ResourceContents, error) {
+			if uri != "test://a" {
+				return nil, ErrUnknownResource
+			}
+			return &ResourceContents{URI: uri, Text: "contents of a"}, nil
+		},
+	}
+}
+
+type testResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+func call(t *testing.T, s *Server, msg string) testResponse {
+	t.Helper()
+	out := s.Handle([]byte(msg))
+	if out == nil {
+		t.Fatalf("expected a response to %s", msg)
+	}
+	var resp testResponse
+	if err := json.Unmarshal(out, &resp); err != nil {
+		t.Fatalf("failed to parse response %s: %v", out, err)
+	}
+	return resp
+}
+
+func TestInitialize(t *testing.T) {
+	s := testServer()
+	resp := call(t, s, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`)
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %v", resp.Error)
+	}
+
+	var result struct {
+		ProtocolVersion string                     `json:"protocolVersion"`
+		Capabilities    map[string]json.RawMessage `json:"capabilities"`
+		ServerInfo      struct {
+			Name string `json:"name"`
+		} `json:"serverInfo"`
+	}
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.ProtocolVersion != "2025-03-26" {
+		t.Errorf("expected negotiated version 2025-03-26, got %s", result.ProtocolVersion)
+	}
+	for _, capability := range []string{"tools", "prompts", "resources"} {
+		if _, ok := result.Capabilities[capability]; !ok {
+			t.Errorf("expected capability %s", capability)
+		}
+	}
+	if result.ServerInfo.Name != "test" {
+		t.Errorf("expected server name test, got %s", result.ServerInfo.Name)
+	}
+
+	resp = call(t, s, `{"jsonrpc":"2.0","id":2,"method":"initialize","params":{"protocolVersion":"1999-01-01"}}`)
+	if !strings.Contains(string(resp.Result), ProtocolVersion) {
+		t.Errorf("expected fallback to %s, got %s", ProtocolVersion, resp.Result)
+	}
+}
+
+func TestNotificationsHaveNoResponse(t *testing.T) {
+	s := testServer()
+	if out := s.Handle([]byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)); out != nil {
+		t.Errorf("expected no response, got %s", out)
+	}
+	if out := s.Handle([]byte(`{"jsonrpc":"2.0","method":"tools/list"}`)); out != nil {
+		t.Errorf("expected no response to a request without id, got %s", out)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	s := testServer()
+
+	tests := []struct {
+		name string
+		msg  string
+		code int
+	}{
+		{name: "parse error", msg: `{not json`, code: codeParseError},
+		{name: "invalid request", msg: `{"id":1,"method":"ping"}`, code: codeInvalidRequest},
+		{name: "unknown method", msg: `{"jsonrpc":"2.0","id":1,"method":"bogus"}`, code: codeMethodNotFound},
+		{name: "notification with id", msg: `{"jsonrpc":"2.0","id":1,"method":"notifications/initialized"}`, code: codeMethodNotFound},
+		{name: "unknown tool", msg: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"bogus"}}`, code: codeInvalidParams},
+		{name: "unknown prompt", msg: `{"jsonrpc":"2.0","id":1,"method":"prompts/get","params":{"name":"bogus"}}`, code: codeInvalidParams},
+		{name: "unknown resource", msg: `{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":"test://b"}}`, code: codeInvalidParams},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := call(t, s, tt.msg)
+			if resp.Error == nil {
+				t.Fatalf("expected error, got result %s", resp.Result)
+			}
+			if resp.Error.Code != tt.code {
+				t.Errorf("expected error code %d, got %d (%s)", tt.code, resp.Error.Code, resp.Error.Message)
+			}
+		})
+	}
+}
+
+func TestToolsCall(t *testing.T) {
+	s := testServer()
+
+	resp := call(t, s, `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	if !strings.Contains(string(resp.Result), `"name":"echo"`) || !strings.Contains(string(resp.Result), `"inputSchema"`) {
+		t.Errorf("unexpected tools/list result %s", resp.Result)
+	}
+
+	resp = call(t, s, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"echo","arguments":{"text":"hi"}}}`)
+	var result struct {
+		Content []textContent `json:"content"`
+		IsError bool          `json:"isError"`
+	}
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.IsError || len(result.Content) != 1 || result.Content[0].Text != "hi" {
+		t.Errorf("unexpected tools/call result %s", resp.Result)
+	}
+
+	resp = call(t, s, `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo"}}`)
+	result.IsError = false
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	if !result.IsError || result.Content[0].Text != "partial\nError: text is required" {
+		t.Errorf("expected tool error result, got %s", resp.Result)
+	}
+}
+
+func TestPromptsAndResources(t *testing.T) {
+	s := testServer()
+
+	resp := call(t, s, `{"jsonrpc":"2.0","id":1,"method":"prompts/get","params":{"name":"hello"}}`)
+	if !strings.Contains(string(resp.Result), `"text":"Hello!"`) {
+		t.Errorf("unexpected prompts/get result %s", resp.Result)
+	}
+
+	resp = call(t, s, `{"jsonrpc":"2.0","id":2,"method":"resources/list"}`)
+	if !strings.Contains(string(resp.Result), `"uri":"test://a"`) {
+		t.Errorf("unexpected resources/list result %s", resp.Result)
+	}
+
+	resp = call(t, s, `{"jsonrpc":"2.0","id":3,"method":"resources/read","params":{"uri":"test://a"}}`)
+	if !strings.Contains(string(resp.Result), `"text":"contents of a"`) {
+		t.Errorf("unexpected resources/read result %s", resp.Result)
+	}
+}
+
+func TestServe(t *testing.T) {
+	s := testServer()
+	input := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		``,
+		`{"jsonrpc":"2.0","id":2,"method":"ping"}`,
+	}, "\n")
+
+	var out strings.Builder
+	if err := s.Serve(strings.NewReader(input), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 responses, got %d: %s", len(lines), out.String())
+	}
+	if lines[1] != `{"jsonrpc":"2.0","id":2,"result":{}}` {
+		t.Errorf("unexpected ping response %s", lines[1])
+	}
+}
@@ -0,0 +1,185 @@
+// Package merge merges two versions of a peb that were changed concurrently,
+// e.g. on two git branches.
+package merge
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"go.yozora.eu/pebbles/internal/peb"
+	"gopkg.in/yaml.v3"
+)
+
+// TextMerger reports whether there are conflicts, which are marked in the
+// result.
+type TextMerger func(base, ours, theirs string) (string, bool, error)
+
+// Merge merges ours and theirs field by field. base is nil if the peb was
+// added on both sides; Merge fails if the two were created at different
+// times, since they are different pebs that got the same ID.
+//
+// Fields changed on both sides take the value of the side that was changed
+// last (ours on a tie), except for blocked-by, commits and labels, which
+// combine the additions and removals of both sides.
+func Merge(base, ours, theirs *peb.Peb, mergeText TextMerger) (*peb.Peb, bool, error) {
+	if ours.ID != theirs.ID {
+		return nil, false, fmt.Errorf("cannot merge different pebs %s and %s", ours.ID, theirs.ID)
+	}
+	if base == nil {
+		if ours.Created != theirs.Created {
+			return nil, false, fmt.Errorf("different pebs %s were created on both sides", ours.ID)
+		}
+		base = &peb.Peb{ID: ours.ID}
+	}
+
+	oursLast := !timestamp(ours.Changed).Before(timestamp(theirs.Changed))
+	merged := &peb.Peb{
+		ID:          ours.ID,
+		Title:       pick(base.Title, ours.Title, theirs.Title, oursLast),
+		Type:        pick(base.Type, ours.Type, theirs.Type, oursLast),
+		Status:      pick(base.Status, ours.Status, theirs.Status, oursLast),
+		Created:     pick(base.Created, ours.Created, theirs.Created, timestamp(ours.Created).Before(timestamp(theirs.Created))),
+		Changed:     pick(base.Changed, ours.Changed, theirs.Changed, oursLast),
+		BlockedBy:   mergeSet(base.BlockedBy, ours.BlockedBy, theirs.BlockedBy),
+		Source:      pick(base.Source, ours.Source, theirs.Source, oursLast),
+		Commits:     mergeSet(base.Commits, ours.Commits, theirs.Commits),
+		Extra:       mergeExtra(base.Extra, ours.Extra, theirs.Extra, oursLast),
+		Format:      ours.Format,
+		Frontmatter: ours.Frontmatter,
+	}
+	oursLabels, theirsLabels := ours.Extra.Strings(peb.LabelsKey), theirs.Extra.Strings(peb.LabelsKey)
+	if oursLabels != nil || theirsLabels != nil {
+		labels := mergeSet(base.Extra.Strings(peb.LabelsKey), oursLabels, theirsLabels)
+		if !slices.Equal(labels, merged.Extra.Strings(peb.LabelsKey)) {
+			merged.Extra.SetStrings(peb.LabelsKey, labels)
+		}
+	}
+
+	switch {
+	case ours.Content == theirs.Content || theirs.Content == base.Content:
+		merged.Content = ours.Content
+	case ours.Content == base.Content:
+		merged.Content = theirs.Content
+	default:
+		content, conflict, err := mergeText(base.Content, ours.Content, theirs.Content)
+		if err != nil {
+			return nil, false, err
+		}
+		merged.Content = content
+		return merged, conflict, nil
+	}
+	return merged, false, nil
+}
+
+func timestamp(s string) time.Time {
+	t, _ := peb.ParseTimestamp(s)
+	return t
+}
+
+func pick[T comparable](base, ours, theirs T, oursWins bool) T {
+	switch {
+	case ours == theirs || theirs == base:
+		return ours
+	case ours == base:
+		return theirs
+	case oursWins:
+		return ours
+	default:
+		return theirs
+	}
+}
+
+// mergeSet returns the items of base that neither side removed, followed by
+// the items either side added, in the order of ours and then theirs.
+func mergeSet(base, ours, theirs []string) []string {
+	var merged []string
+	for _, items := range [][]string{ours, theirs} {
+		for _, item := range items {
+			if slices.Contains(merged, item) {
+				continue
+			}
+			removed := slices.Contains(base, item) && (!slices.Contains(ours, item) || !slices.Contains(theirs, item))
+			if !removed {
+				merged = append(merged, item)
+			}
+		}
+	}
+	return merged
+}
+
+// mergeExtra compares values by their YAML encoding. Keys added by theirs
+// follow those of ours.
+func mergeExtra(base, ours, theirs peb.Extra, oursWins bool) peb.Extra {
+	keys := ours.Keys()
+	for _, key := range theirs.Keys() {
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	var merged peb.Extra
+	for _, key := range keys {
+		_, baseValue := base.Lookup(key)
+		oursKey, oursValue := ours.Lookup(key)
+		theirsKey, theirsValue := theirs.Lookup(key)
+
+		value := pick(encodeNode(baseValue), encodeNode(oursValue), encodeNode(theirsValue), oursWins)
+		switch {
+		case value == "":
+			// Removed.
+		case value == encodeNode(oursValue):
+			merged = append(merged, oursKey, oursValue)
+		default:
+			merged = append(merged, theirsKey, theirsValue)
+		}
+	}
+	return merged
+}
+
+func encodeNode(node *yaml.Node) string {
+	if node == nil {
+		return ""
+	}
+	data, err := yaml.Marshal(node)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func GitMergeFile(base, ours, theirs string) (string, bool, error) {
+	dir, err := os.MkdirTemp("", "peb-merge-")
+	if err != nil {
+		return "", false, err
+	}
+	defer os.RemoveAll(dir)
+
+	var paths []string
+	for _, f := range []struct{ name, text string }{{"ours", ours}, {"base", base}, {"theirs", theirs}} {
+		path := filepath.Join(dir, f.name)
+		if err := os.WriteFile(path, []byte(f.text), 0644); err != nil {
+			return "", false, err
+		}
+		paths = append(paths, path)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", "merge-file", "-p", "-L", "ours", "-L", "base", "-L", "theirs", paths[0], paths[1], paths[2])
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	// git merge-file exits with the number of conflicts, or a negative
+	// status on errors.
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() > 0 && exitErr.ExitCode() < 128 {
+		return stdout.String(), true, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("git merge-file: %v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return stdout.String(), false, nil
+}
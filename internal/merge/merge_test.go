@@ -0,0 +1,158 @@
+package merge
+
+import (
+	"encoding/json"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.yozora.eu/pebbles/internal/peb"
+)
+
+func noTextMerge(t *testing.T) TextMerger {
+	return func(base, ours, theirs string) (string, bool, error) {
+		t.Fatalf("unexpected text merge of %q and %q", ours, theirs)
+		return "", false, nil
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := &peb.Peb{
+		ID: "peb-aaaa", Title: "Title", Type: peb.TypeBug, Status: peb.StatusNew,
+		Created: "2024-01-01T00:00:00+00:00", Changed: "2024-01-01T00:00:00+00:00",
+		BlockedBy: []string{"peb-1111", "peb-2222"}, Content: "Content\n",
+	}
+
+	ours := *base
+	ours.Status = peb.StatusInProgress
+	ours.Type = peb.TypeFeature
+	ours.Changed = "2024-01-03T00:00:00+00:00"
+	ours.BlockedBy = []string{"peb-1111", "peb-3333"}
+
+	theirs := *base
+	theirs.Status = peb.StatusFixed
+	theirs.Title = "New title"
+	theirs.Changed = "2024-01-02T00:00:00+01:00"
+	theirs.BlockedBy = []string{"peb-2222", "peb-1111", "peb-4444"}
+	theirs.Commits = []string{"abc"}
+	theirs.Content = "New content\n"
+
+	merged, conflict, err := Merge(base, &ours, &theirs, noTextMerge(t))
+	if err != nil || conflict {
+		t.Fatalf("Merge() = %v, %v", conflict, err)
+	}
+	want := &peb.Peb{
+		ID: "peb-aaaa", Title: "New title", Type: peb.TypeFeature, Status: peb.StatusInProgress,
+		Created: base.Created, Changed: ours.Changed,
+		BlockedBy: []string{"peb-1111", "peb-3333", "peb-4444"}, Commits: []string{"abc"},
+		Content: "New content\n",
+	}
+	if !pebsEqual(merged, want) {
+		t.Errorf("Merge() = %+v\nwant %+v", merged, want)
+	}
+
+	// Without a base, both sides count as changed.
+	merged, _, err = Merge(nil, &ours, &theirs, func(base, ours, theirs string) (string, bool, error) {
+		return "<<<<<<< ours\n" + ours + "=======\n" + theirs + ">>>>>>> theirs\n", true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.Title != ours.Title || merged.Status != ours.Status || !strings.HasPrefix(merged.Content, "<<<<<<<") {
+		t.Errorf("expected ours to win without a base, got %+v", merged)
+	}
+
+	other := *base
+	other.ID = "peb-bbbb"
+	if _, _, err := Merge(base, &ours, &other, noTextMerge(t)); err == nil {
+		t.Error("expected an error when merging different pebs")
+	}
+}
+
+func pebsEqual(a, b *peb.Peb) bool {
+	return a.ID == b.ID && a.Title == b.Title && a.Type == b.Type && a.Status == b.Status &&
+		a.Created == b.Created && a.Changed == b.Changed && a.Source == b.Source &&
+		slices.Equal(a.BlockedBy, b.BlockedBy) && slices.Equal(a.Commits, b.Commits) &&
+		a.Content == b.Content
+}
+
+func TestGitMergeFile(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	text, conflict, err := GitMergeFile("a\nb\nc\n", "A\nb\nc\n", "a\nb\nC\n")
+	if err != nil || conflict || text != "A\nb\nC\n" {
+		t.Errorf("GitMergeFile() = %q, %v, %v", text, conflict, err)
+	}
+
+	text, conflict, err = GitMergeFile("a\n", "ours\n", "theirs\n")
+	if err != nil || !conflict || !strings.Contains(text, "<<<<<<< ours\nours\n=======\ntheirs\n>>>>>>> theirs\n") {
+		t.Errorf("GitMergeFile() = %q, %v, %v", text, conflict, err)
+	}
+}
+
+func TestMergeExtra(t *testing.T) {
+	extra := func(s string) peb.Extra {
+		var e peb.Extra
+		if err := json.Unmarshal([]byte(s), &e); err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+	base := &peb.Peb{ID: "peb-aaaa", Extra: extra(`{"owner":"alice","estimate":3,"labels":["ui"]}`)}
+	ours := *base
+	ours.Changed = "2024-01-02T00:00:00+00:00"
+	ours.Extra = extra(`{"owner":"bob","labels":["ui"],"sprint":4}`)
+	theirs := *base
+	theirs.Changed = "2024-01-01T00:00:00+00:00"
+	theirs.Extra = extra(`{"owner":"carol","estimate":3,"labels":["ui","p1"],"team":"core"}`)
+
+	merged, _, err := Merge(base, &ours, &theirs, noTextMerge(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(merged.Extra)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"owner":"bob","labels":["ui","p1"],"sprint":4,"team":"core"}`
+	if string(data) != want {
+		t.Errorf("merged extra = %s, want %s", data, want)
+	}
+}
+
+func TestMergeLabels(t *testing.T) {
+	read := func(labels string) *peb.Peb {
+		p, err := peb.Unmarshal([]byte("---\nid: peb-aaaa\nchanged: \"2024-01-01T00:00:00+00:00\"\nlabels:\n" + labels + "---\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	base := read("  - ui\n  - p2\n")
+	ours := read("  - ui\n  - p2\n  - docs\n")
+	theirs := read("  - ui\n  - p1\n")
+
+	merged, _, err := Merge(base, ours, theirs, noTextMerge(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labels := merged.Extra.Strings(peb.LabelsKey); !slices.Equal(labels, []string{"ui", "docs", "p1"}) {
+		t.Errorf("merged labels = %v", labels)
+	}
+
+	// Labels changed on one side only keep their style.
+	merged, _, err = Merge(base, ours, base, noTextMerge(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := peb.Marshal(merged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "labels:\n    - ui\n    - p2\n    - docs\n") {
+		t.Errorf("merged file = %s", data)
+	}
+}
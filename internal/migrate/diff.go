@@ -0,0 +1,163 @@
+package migrate
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const diffContext = 3
+
+// WriteDiff shows a file deleted and created with the same content as a
+// rename.
+func (m *Migration) WriteDiff(w io.Writer) error {
+	renamedTo := make(map[string]string)
+	renamedFrom := make(map[string]bool)
+	for _, deleted := range m.Changes {
+		if deleted.New != nil {
+			continue
+		}
+		for _, created := range m.Changes {
+			if created.Old == nil && !renamedFrom[created.Name] && bytes.Equal(created.New, deleted.Old) {
+				renamedTo[deleted.Name] = created.Name
+				renamedFrom[created.Name] = true
+				break
+			}
+		}
+	}
+
+	for _, c := range m.Changes {
+		switch {
+		case renamedFrom[c.Name]:
+			continue
+		case renamedTo[c.Name] != "":
+			if _, err := fmt.Fprintf(w, "rename %s => %s\n", c.Name, renamedTo[c.Name]); err != nil {
+				return err
+			}
+			continue
+		}
+
+		oldName, newName := "a/"+c.Name, "b/"+c.Name
+		if c.Old == nil {
+			oldName = "/dev/null"
+		}
+		if c.New == nil {
+			newName = "/dev/null"
+		}
+		if _, err := fmt.Fprintf(w, "--- %s\n+++ %s\n", oldName, newName); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, unifiedDiff(splitLines(c.Old), splitLines(c.New))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func unifiedDiff(a, b []string) string {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type edit struct {
+		op   byte
+		line string
+	}
+	var edits []edit
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, edit{'-', a[i]})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j]})
+			j++
+		}
+	}
+
+	var out strings.Builder
+	for start := 0; start < len(edits); {
+		// A hunk extends until there are more than 2*diffContext unchanged
+		// lines.
+		first := start
+		for first < len(edits) && edits[first].op == ' ' {
+			first++
+		}
+		if first == len(edits) {
+			break
+		}
+		end, unchanged := first, 0
+		for k := first; k < len(edits) && unchanged <= 2*diffContext; k++ {
+			if edits[k].op == ' ' {
+				unchanged++
+			} else {
+				unchanged, end = 0, k+1
+			}
+		}
+		from, to := max(first-diffContext, start), min(end+diffContext, len(edits))
+
+		oldStart, newStart := 1, 1
+		for _, e := range edits[:from] {
+			if e.op != '+' {
+				oldStart++
+			}
+			if e.op != '-' {
+				newStart++
+			}
+		}
+		oldCount, newCount := 0, 0
+		for _, e := range edits[from:to] {
+			if e.op != '+' {
+				oldCount++
+			}
+			if e.op != '-' {
+				newCount++
+			}
+		}
+		if oldCount == 0 {
+			oldStart--
+		}
+		if newCount == 0 {
+			newStart--
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+		for _, e := range edits[from:to] {
+			out.WriteByte(e.op)
+			out.WriteString(e.line)
+			if !strings.HasSuffix(e.line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		start = to
+	}
+	return out.String()
+}
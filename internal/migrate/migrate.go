@@ -0,0 +1,154 @@
+// Package migrate upgrades .pebbles directories written by older versions of
+// pebbles to the current format version.
+//
+// Every change to the format that needs existing data to be rewritten bumps
+// config.FormatVersion and adds a Step to Steps.
+package migrate
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"go.yozora.eu/pebbles/internal/config"
+)
+
+const ConfigFile = "config.toml"
+
+type Files map[string][]byte
+
+// Step upgrades from format version Version-1 to Version. Plan updates the
+// version in config.toml, not the step.
+type Step struct {
+	Version     int
+	Description string
+	Apply       func(files Files, cfg *config.Config) error
+}
+
+var Steps = []Step{
+	{
+		Version:     1,
+		Description: "Record the format version and rename peb files to match their titles",
+		Apply:       renameFiles,
+	},
+}
+
+func Pending(version int) []Step {
+	var steps []Step
+	for _, step := range Steps {
+		if step.Version > version {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+type Change struct {
+	Name string
+	Old  []byte
+	New  []byte
+}
+
+type Migration struct {
+	From    int
+	To      int
+	Steps   []Step
+	Changes []Change
+	dir     string
+}
+
+// Plan runs the pending steps in memory, so the result can be shown as a
+// diff before anything is written.
+func Plan(cfg *config.Config) (*Migration, error) {
+	dir := cfg.PebblesDir()
+	files, err := readFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Migration{From: cfg.Version, To: cfg.Version, Steps: Pending(cfg.Version), dir: dir}
+	migrated := maps.Clone(files)
+	for _, step := range m.Steps {
+		if err := step.Apply(migrated, cfg); err != nil {
+			return nil, fmt.Errorf("migration to format version %d failed: %w", step.Version, err)
+		}
+		m.To = step.Version
+	}
+	if m.To != m.From {
+		data, ok := migrated[ConfigFile]
+		if !ok {
+			return nil, fmt.Errorf("migration removed %s", ConfigFile)
+		}
+		migrated[ConfigFile] = config.SetValue(data, "version", strconv.Itoa(m.To))
+	}
+
+	// Files are written before the ones they replace are removed, so that
+	// an interrupted migration doesn't lose pebs.
+	for _, name := range slices.Sorted(maps.Keys(migrated)) {
+		if old, ok := files[name]; !ok || !bytes.Equal(migrated[name], old) {
+			m.Changes = append(m.Changes, Change{Name: name, Old: old, New: migrated[name]})
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		if _, ok := migrated[name]; !ok {
+			m.Changes = append(m.Changes, Change{Name: name, Old: files[name]})
+		}
+	}
+	return m, nil
+}
+
+// Apply writes config.toml last, so that a failed migration leaves the old
+// format version in place and can be run again.
+func (m *Migration) Apply() error {
+	var configChange *Change
+	for i, c := range m.Changes {
+		if c.Name == ConfigFile {
+			configChange = &m.Changes[i]
+			continue
+		}
+		if err := writeChange(m.dir, c); err != nil {
+			return err
+		}
+	}
+	if configChange != nil {
+		return writeChange(m.dir, *configChange)
+	}
+	return nil
+}
+
+func writeChange(dir string, c Change) error {
+	path := filepath.Join(dir, c.Name)
+	if c.New == nil {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", c.Name, err)
+		}
+		return nil
+	}
+	if err := os.WriteFile(path, c.New, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", c.Name, err)
+	}
+	return nil
+}
+
+func readFiles(dir string) (Files, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pebbles directory: %w", err)
+	}
+	files := make(Files)
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.Name(), err)
+		}
+		files[entry.Name()] = data
+	}
+	return files, nil
+}
@@ -0,0 +1,167 @@
+package migrate
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"go.yozora.eu/pebbles/internal/config"
+)
+
+func TestSteps(t *testing.T) {
+	for i, step := range Steps {
+		if step.Version != i+1 {
+			t.Errorf("step %d migrates to version %d, want %d", i, step.Version, i+1)
+		}
+		if step.Description == "" || step.Apply == nil {
+			t.Errorf("step %d is incomplete", i)
+		}
+	}
+	if last := Steps[len(Steps)-1].Version; last != config.FormatVersion {
+		t.Errorf("last step migrates to version %d, but the format version is %d", last, config.FormatVersion)
+	}
+	if len(Pending(config.FormatVersion)) != 0 {
+		t.Error("expected no pending steps at the current format version")
+	}
+}
+
+const oldPeb = "---\nid: peb-aaaa\ntitle: Edited title\ntype: bug\nstatus: new\ncreated: \"2024-01-01T00:00:00Z\"\nchanged: \"2024-01-01T00:00:00Z\"\n---\nContent\n"
+
+func setupOldStore(t *testing.T) string {
+	t.Helper()
+	projectDir := t.TempDir()
+	pebblesDir := filepath.Join(projectDir, ".pebbles")
+	files := map[string]string{
+		"config.toml":             "# Pebbles configuration\nprefix = \"peb\"\nid_length = 4\n",
+		"peb-aaaa--old-title.md":  oldPeb,
+		"peb-bbbb--broken.md":     "not a peb\n",
+		"notes.txt":               "kept\n",
+		"other-cccc--whatever.md": oldPeb,
+	}
+	if err := os.MkdirAll(pebblesDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(pebblesDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(projectDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return pebblesDir
+}
+
+func TestMigrate(t *testing.T) {
+	pebblesDir := setupOldStore(t)
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Plan(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.From != 0 || m.To != config.FormatVersion || len(m.Steps) != len(Steps) {
+		t.Errorf("Plan() = %d -> %d with %d steps", m.From, m.To, len(m.Steps))
+	}
+
+	var diff bytes.Buffer
+	if err := m.WriteDiff(&diff); err != nil {
+		t.Fatal(err)
+	}
+	want := `--- a/config.toml
++++ b/config.toml
+@@ -1,3 +1,4 @@
+ # Pebbles configuration
++version = 1
+ prefix = "peb"
+ id_length = 4
+rename peb-aaaa--old-title.md => peb-aaaa--edited-title.md
+`
+	if diff.String() != want {
+		t.Errorf("WriteDiff() =\n%s\nwant\n%s", diff.String(), want)
+	}
+
+	// The renamed file is written before the old one is removed.
+	var names []string
+	for _, c := range m.Changes {
+		names = append(names, c.Name)
+	}
+	if want := []string{"config.toml", "peb-aaaa--edited-title.md", "peb-aaaa--old-title.md"}; !slices.Equal(names, want) {
+		t.Errorf("changes = %v, want %v", names, want)
+	}
+
+	// Planning doesn't write anything.
+	if _, err := os.Stat(filepath.Join(pebblesDir, "peb-aaaa--old-title.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"peb-aaaa--edited-title.md", "peb-bbbb--broken.md", "notes.txt", "other-cccc--whatever.md"} {
+		if _, err := os.Stat(filepath.Join(pebblesDir, name)); err != nil {
+			t.Errorf("expected %s to exist: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(pebblesDir, "peb-aaaa--old-title.md")); !os.IsNotExist(err) {
+		t.Error("expected the old file name to be gone")
+	}
+
+	cfg, err = config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Version != config.FormatVersion {
+		t.Errorf("version after migration = %d", cfg.Version)
+	}
+	m, err = Plan(cfg)
+	if err != nil || len(m.Steps) != 0 || len(m.Changes) != 0 {
+		t.Errorf("Plan() after migration = %+v, %v", m, err)
+	}
+}
+
+func TestNewerVersion(t *testing.T) {
+	pebblesDir := setupOldStore(t)
+	if err := os.WriteFile(filepath.Join(pebblesDir, "config.toml"), []byte("version = 999\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.Load(); err == nil {
+		t.Error("expected config.Load to refuse a newer format version")
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	a := splitLines([]byte("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n17\n18"))
+	b := splitLines([]byte("1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n17\n18\n19\n"))
+	want := `@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+@@ -15,4 +15,5 @@
+ 15
+ 16
+ 17
+-18
+\ No newline at end of file
++18
++19
+`
+	if got := unifiedDiff(a, b); got != want {
+		t.Errorf("unifiedDiff() =\n%s\nwant\n%s", got, want)
+	}
+}
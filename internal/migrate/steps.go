@@ -0,0 +1,32 @@
+package migrate
+
+import (
+	"strings"
+
+	"go.yozora.eu/pebbles/internal/config"
+	"go.yozora.eu/pebbles/internal/peb"
+)
+
+// renameFiles leaves files that cannot be read alone.
+func renameFiles(files Files, cfg *config.Config) error {
+	for name, data := range files {
+		if !strings.HasSuffix(name, ".md") {
+			continue
+		}
+		id, err := peb.ParseID(name, cfg.Prefix)
+		if err != nil {
+			continue
+		}
+		p, err := peb.Unmarshal(data)
+		if err != nil || p.ID != id {
+			continue
+		}
+		want := peb.Filename(p)
+		if _, taken := files[want]; taken || want == name {
+			continue
+		}
+		delete(files, name)
+		files[want] = data
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+package peb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Extra holds the unknown frontmatter keys as alternating key and value
+// nodes, so that they are written back unchanged, including their comments.
+type Extra []*yaml.Node
+
+// Extra keys written by importers.
+const (
+	LabelsKey   = "labels"
+	PriorityKey = "priority"
+)
+
+var knownKeys = []string{"id", "title", "type", "status", "created", "changed", "blocked-by", "source", "commits"}
+
+func (e Extra) Lookup(key string) (*yaml.Node, *yaml.Node) {
+	for i := 0; i+1 < len(e); i += 2 {
+		if e[i].Value == key {
+			return e[i], e[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// Strings also accepts a single string. It returns nil if key is missing or
+// not a list of scalars.
+func (e Extra) Strings(key string) []string {
+	_, value := e.Lookup(key)
+	for value != nil && value.Kind == yaml.AliasNode {
+		value = value.Alias
+	}
+	if value == nil {
+		return nil
+	}
+	var values []string
+	if value.Kind == yaml.ScalarNode {
+		if value.Tag == "!!null" {
+			return nil
+		}
+		return []string{value.Value}
+	}
+	if value.Decode(&values) != nil {
+		return nil
+	}
+	return values
+}
+
+// SetStrings keeps the place of key if it exists. An empty list removes it.
+func (e *Extra) SetStrings(key string, values []string) {
+	for i := 0; i+1 < len(*e); i += 2 {
+		if (*e)[i].Value != key {
+			continue
+		}
+		if len(values) == 0 {
+			*e = slices.Delete(*e, i, i+2)
+		} else {
+			(*e)[i+1] = stringsNode(values)
+		}
+		return
+	}
+	if len(values) > 0 {
+		*e = append(*e, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, stringsNode(values))
+	}
+}
+
+func stringsNode(values []string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle}
+	for _, v := range values {
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v})
+	}
+	return node
+}
+
+func (e Extra) Keys() []string {
+	keys := make([]string, 0, len(e)/2)
+	for i := 0; i+1 < len(e); i += 2 {
+		keys = append(keys, e[i].Value)
+	}
+	return keys
+}
+
+func extraFromMapping(mapping *yaml.Node) Extra {
+	var extra Extra
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if !slices.Contains(knownKeys, mapping.Content[i].Value) {
+			extra = append(extra, mapping.Content[i], mapping.Content[i+1])
+		}
+	}
+
+	// Known fields are written without their anchors, so aliases of them
+	// are replaced by copies. Some values are not written back as they were
+	// read: empty nulls in flow collections, e.g. {a}, and as keys become
+	// empty strings, so they are spelled out; block scalars of whitespace
+	// only lose it, so they are quoted instead.
+	inside := make(map[*yaml.Node]bool)
+	var mark func(node *yaml.Node)
+	mark = func(node *yaml.Node) {
+		inside[node] = true
+		for _, child := range node.Content {
+			mark(child)
+		}
+	}
+	for _, node := range extra {
+		mark(node)
+	}
+	var resolve func(nodes []*yaml.Node, flow, mapping bool)
+	resolve = func(nodes []*yaml.Node, flow, mapping bool) {
+		for i, node := range nodes {
+			if node.Kind == yaml.AliasNode && node.Alias != nil && !inside[node.Alias] {
+				target := *node.Alias
+				target.Anchor = ""
+				nodes[i] = &target
+			}
+			if nodes[i].Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 && strings.TrimSpace(nodes[i].Value) == "" {
+				nodes[i].Style = yaml.DoubleQuotedStyle
+			}
+			isKey := mapping && i%2 == 0
+			if (flow || isKey) && nodes[i].Kind == yaml.ScalarNode && nodes[i].Tag == "!!null" && nodes[i].Value == "" {
+				nodes[i].Value = "null"
+			}
+			resolve(nodes[i].Content, flow || nodes[i].Style&yaml.FlowStyle != 0, nodes[i].Kind == yaml.MappingNode)
+		}
+	}
+	resolve(extra, false, true)
+	return extra
+}
+
+func (e Extra) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i := 0; i+1 < len(e); i += 2 {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(e[i].Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		if err := writeNodeJSON(&buf, e[i+1]); err != nil {
+			return nil, fmt.Errorf("extra key %s: %w", e[i].Value, err)
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func writeNodeJSON(buf *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return writeNodeJSON(buf, node.Alias)
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, err := json.Marshal(node.Content[i].Value)
+			if err != nil {
+				return err
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := writeNodeJSON(buf, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeNodeJSON(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		var value any
+		if err := node.Decode(&value); err != nil {
+			return err
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+	}
+	return nil
+}
+
+func (e *Extra) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	node, err := readNodeJSON(decoder)
+	if err != nil {
+		return err
+	}
+	if node.Kind != yaml.MappingNode {
+		return errors.New("extra must be an object")
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		if slices.Contains(knownKeys, node.Content[i].Value) {
+			return fmt.Errorf("extra must not contain the field %s", node.Content[i].Value)
+		}
+	}
+	*e = node.Content
+	return nil
+}
+
+func readNodeJSON(decoder *json.Decoder) (*yaml.Node, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	switch token := token.(type) {
+	case json.Delim:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if token == '{' {
+			node.Kind, node.Tag = yaml.MappingNode, "!!map"
+		}
+		for decoder.More() {
+			if node.Kind == yaml.MappingNode {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.(string)})
+			}
+			value, err := readNodeJSON(decoder)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, value)
+		}
+
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case json.Number:
+		tag := "!!float"
+		if _, err := strconv.ParseInt(token.String(), 10, 64); err == nil {
+			tag = "!!int"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: token.String()}, nil
+	case string:
+		node := &yaml.Node{}
+		if err := node.Encode(token); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(token)}, nil
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+}
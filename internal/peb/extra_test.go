@@ -0,0 +1,113 @@
+package peb
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const extraFile = `---
+id: peb-abcd
+# Who works on it.
+owner: alice
+title: Title
+type: bug
+status: new
+created: "2024-01-01T00:00:00Z"
+changed: "2024-01-01T00:00:00Z"
+labels: [ui, "p1"] # flow style
+estimate: 3
+---
+Content
+`
+
+func TestExtraRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peb.md")
+	if err := os.WriteFile(path, []byte(extraFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := strings.Join(p.Extra.Keys(), ","); keys != "owner,labels,estimate" {
+		t.Errorf("Extra.Keys() = %s", keys)
+	}
+
+	p.Status = StatusInProgress
+	data, err := Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"status: in-progress\n", "# Who works on it.\nowner: alice\n", "labels: [ui, \"p1\"] # flow style\nestimate: 3\n"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Marshal() = %s\nwant it to contain %q", data, want)
+		}
+	}
+}
+
+func TestExtraJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peb.md")
+	if err := os.WriteFile(path, []byte(extraFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(p.Extra)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"owner":"alice","labels":["ui","p1"],"estimate":3}`
+	if string(data) != want {
+		t.Errorf("MarshalJSON() = %s, want %s", data, want)
+	}
+
+	var extra Extra
+	if err := json.Unmarshal(data, &extra); err != nil {
+		t.Fatal(err)
+	}
+	again, err := json.Marshal(extra)
+	if err != nil || string(again) != want {
+		t.Errorf("round trip = %s, %v, want %s", again, err, want)
+	}
+
+	for _, invalid := range []string{`[]`, `{"status":"new"}`, `{"owner":`} {
+		if err := json.Unmarshal([]byte(invalid), &extra); err == nil {
+			t.Errorf("UnmarshalJSON(%s): expected an error", invalid)
+		}
+	}
+}
+
+func TestExtraStrings(t *testing.T) {
+	p, err := Unmarshal([]byte("---\nid: peb-abcd\nlabels: [ui, p1]\nowner: alice\n---\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labels := p.Extra.Strings(LabelsKey); !slices.Equal(labels, []string{"ui", "p1"}) {
+		t.Errorf("Strings(labels) = %v", labels)
+	}
+	if owner := p.Extra.Strings("owner"); !slices.Equal(owner, []string{"alice"}) {
+		t.Errorf("Strings(owner) = %v", owner)
+	}
+
+	p.Extra.SetStrings(LabelsKey, []string{"ui"})
+	p.Extra.SetStrings("topics", []string{"docs"})
+	p.Extra.SetStrings("owner", nil)
+	if keys := strings.Join(p.Extra.Keys(), ","); keys != "labels,topics" {
+		t.Errorf("Keys() = %s", keys)
+	}
+	data, err := Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "labels: [ui]\ntopics: [docs]\n") {
+		t.Errorf("Marshal() = %s", data)
+	}
+}
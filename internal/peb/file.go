@@ -6,6 +6,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -13,30 +17,85 @@ import (
 
 var ErrInvalidFormat = errors.New("invalid peb file format")
 
+const bom = "\ufeff"
+
+type ParseError struct {
+	Path string
+	// Line is 0 if it is unknown.
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	location := e.Path
+	if location == "" {
+		location = "peb"
+	}
+	if e.Line > 0 {
+		location += ":" + strconv.Itoa(e.Line)
+	}
+	return location + ": " + e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
 func WriteFile(pebblesDir string, peb *Peb) error {
 	filename := Filename(peb)
 	filepath := filepath.Join(pebblesDir, filename)
 
+	data, err := Marshal(peb)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write peb file: %w", err)
+	}
+
+	return nil
+}
+
+func Marshal(peb *Peb) ([]byte, error) {
 	var buf bytes.Buffer
 
 	buf.WriteString("---\n")
 
+	var node yaml.Node
+	if err := node.Encode(peb); err != nil {
+		return nil, fmt.Errorf("failed to encode peb: %w", err)
+	}
+	document := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{&node}}
+	if peb.Frontmatter != nil {
+		document.HeadComment = peb.Frontmatter.HeadComment
+		document.FootComment = peb.Frontmatter.FootComment
+		node.Content = arrangeKeys(node.Content, peb.Extra, peb.Frontmatter.Content[0])
+	} else {
+		// Unknown keys follow the known fields.
+		node.Content = append(node.Content, peb.Extra...)
+	}
+
 	encoder := yaml.NewEncoder(&buf)
-	if err := encoder.Encode(peb); err != nil {
-		return fmt.Errorf("failed to encode peb: %w", err)
+	if err := encoder.Encode(document); err != nil {
+		return nil, fmt.Errorf("failed to encode peb: %w", err)
 	}
 	if err := encoder.Close(); err != nil {
-		return fmt.Errorf("failed to close encoder: %w", err)
+		return nil, fmt.Errorf("failed to close encoder: %w", err)
 	}
 
 	buf.WriteString("---\n")
 	buf.WriteString(peb.Content)
 
-	if err := os.WriteFile(filepath, buf.Bytes(), 0644); err != nil {
-		return fmt.Errorf("failed to write peb file: %w", err)
+	data := buf.Bytes()
+	if peb.Format.CRLF {
+		data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+		data = bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n"))
 	}
-
-	return nil
+	if peb.Format.BOM {
+		data = append([]byte(bom), data...)
+	}
+	return data, nil
 }
 
 func ReadFile(path string) (*Peb, error) {
@@ -45,27 +104,197 @@ func ReadFile(path string) (*Peb, error) {
 		return nil, fmt.Errorf("failed to read peb file: %w", err)
 	}
 
-	content := string(data)
+	peb, err := Unmarshal(data)
+	if parseErr, ok := err.(*ParseError); ok {
+		parseErr.Path = path
+	}
+	return peb, err
+}
 
-	if !strings.HasPrefix(content, "---\n") {
-		return nil, ErrInvalidFormat
+// Unmarshal treats everything after the second "---" line as content, so
+// that it may contain "---" too. Errors are of type *ParseError.
+func Unmarshal(data []byte) (*Peb, error) {
+	text := string(data)
+	var format Format
+	if strings.HasPrefix(text, bom) {
+		format.BOM = true
+		text = text[len(bom):]
 	}
 
-	endMarker := strings.Index(content[len("---\n"):], "---\n")
-	if endMarker == -1 {
-		return nil, ErrInvalidFormat
+	var frontmatter strings.Builder
+	line, rest := 1, text
+	for {
+		current, next, found := strings.Cut(rest, "\n")
+		if line == 1 {
+			format.CRLF = found && strings.HasSuffix(current, "\r")
+		}
+		if isDelimiter(current) {
+			if line > 1 {
+				rest = next
+				if !found {
+					rest = ""
+				}
+				break
+			}
+		} else if line == 1 {
+			return nil, &ParseError{Line: 1, Err: fmt.Errorf("%w: expected --- on the first line", ErrInvalidFormat)}
+		} else {
+			frontmatter.WriteString(strings.TrimSuffix(current, "\r"))
+			frontmatter.WriteByte('\n')
+		}
+		if !found {
+			if current == "" {
+				// The last line ended with a line break.
+				line--
+			}
+			return nil, &ParseError{Line: line, Err: fmt.Errorf("%w: no closing --- after the frontmatter", ErrInvalidFormat)}
+		}
+		line, rest = line+1, next
 	}
 
-	frontmatterEnd := len("---\n") + endMarker
-	frontmatter := content[len("---\n"):frontmatterEnd]
-	bodyContent := content[frontmatterEnd+len("---\n"):]
+	peb, err := decodeFrontmatter(frontmatter.String())
+	if err != nil {
+		return nil, err
+	}
+	peb.Format = format
 
-	peb := &Peb{}
-	if err := yaml.Unmarshal([]byte(frontmatter), peb); err != nil {
-		return nil, fmt.Errorf("failed to parse frontmatter: %w", err)
+	if format.CRLF {
+		rest = strings.ReplaceAll(rest, "\r\n", "\n")
 	}
+	peb.Content = rest
 
-	peb.Content = bodyContent
+	return peb, nil
+}
 
+func decodeFrontmatter(frontmatter string) (peb *Peb, err error) {
+	// The YAML decoder panics on some unusual input, e.g. merge keys with
+	// non-scalar keys.
+	defer func() {
+		if r := recover(); r != nil {
+			err = &ParseError{Err: fmt.Errorf("failed to parse frontmatter: %v", r)}
+		}
+	}()
+
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(frontmatter), &node); err != nil {
+		return nil, yamlError(err)
+	}
+	peb = &Peb{}
+	if len(node.Content) > 0 {
+		mapping := node.Content[0]
+		if err := mapping.Decode(peb); err != nil {
+			return nil, yamlError(err)
+		}
+		// Decoding the unknown keys too rejects recursive and excessive
+		// aliases in them.
+		var all any
+		if err := mapping.Decode(&all); err != nil {
+			return nil, yamlError(err)
+		}
+		if mapping.Kind == yaml.MappingNode {
+			peb.Extra = extraFromMapping(mapping)
+			peb.Frontmatter = &node
+		}
+	}
 	return peb, nil
 }
+
+// arrangeKeys orders the pairs of the encoded known fields and of extra like
+// the keys of original. New fields follow the known field before them. The
+// original nodes are kept for unchanged values, so that their comments and
+// style are written back.
+func arrangeKeys(fields []*yaml.Node, extra Extra, original *yaml.Node) []*yaml.Node {
+	values := make(map[string]*yaml.Node)
+	for i := 0; i+1 < len(fields); i += 2 {
+		values[fields[i].Value] = fields[i+1]
+	}
+
+	var arranged []*yaml.Node
+	written := make(map[string]bool)
+	for i := 0; i+1 < len(original.Content); i += 2 {
+		key, value := original.Content[i], original.Content[i+1]
+		if !slices.Contains(knownKeys, key.Value) {
+			if extraKey, extraValue := extra.Lookup(key.Value); extraKey != nil {
+				arranged = append(arranged, extraKey, extraValue)
+				written[key.Value] = true
+			}
+			continue
+		}
+		field, ok := values[key.Value]
+		if !ok || written[key.Value] {
+			continue
+		}
+		if !sameValue(value, field) {
+			field.HeadComment = value.HeadComment
+			field.LineComment = value.LineComment
+			field.FootComment = value.FootComment
+			value = field
+		}
+		arranged = append(arranged, key, value)
+		written[key.Value] = true
+	}
+
+	for i := 0; i+1 < len(fields); i += 2 {
+		key := fields[i].Value
+		if written[key] {
+			continue
+		}
+
+		at := 0
+		for j := 0; j+1 < len(arranged); j += 2 {
+			if index := slices.Index(knownKeys, arranged[j].Value); index >= 0 && index < slices.Index(knownKeys, key) {
+				at = j + 2
+			}
+		}
+		arranged = slices.Insert(arranged, at, fields[i], fields[i+1])
+		written[key] = true
+	}
+
+	for i := 0; i+1 < len(extra); i += 2 {
+		if !written[extra[i].Value] {
+			arranged = append(arranged, extra[i], extra[i+1])
+		}
+	}
+	return arranged
+}
+
+// sameValue never reuses nodes with anchors or aliases, as what they refer to
+// may have changed.
+func sameValue(original, encoded *yaml.Node) bool {
+	if hasReferences(original) {
+		return false
+	}
+	var a, b any
+	if original.Decode(&a) != nil || encoded.Decode(&b) != nil {
+		return false
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func hasReferences(node *yaml.Node) bool {
+	if node.Anchor != "" || node.Kind == yaml.AliasNode {
+		return true
+	}
+	return slices.ContainsFunc(node.Content, hasReferences)
+}
+
+func isDelimiter(line string) bool {
+	return strings.TrimRight(line, " \t\r") == "---"
+}
+
+var yamlLine = regexp.MustCompile(`line (\d+): `)
+
+// yamlError adds one to the line of the error for the opening delimiter.
+func yamlError(err error) error {
+	message := strings.TrimPrefix(err.Error(), "yaml: ")
+	if typeErr, ok := err.(*yaml.TypeError); ok && len(typeErr.Errors) > 0 {
+		message = typeErr.Errors[0]
+	}
+	parseErr := &ParseError{Err: fmt.Errorf("failed to parse frontmatter: %s", message)}
+	if m := yamlLine.FindStringSubmatchIndex(message); m != nil && m[0] == 0 {
+		n, _ := strconv.Atoi(message[m[2]:m[3]])
+		parseErr.Line = n + 1
+		parseErr.Err = fmt.Errorf("failed to parse frontmatter: %s", message[m[1]:])
+	}
+	return parseErr
+}
@@ -0,0 +1,195 @@
+package peb
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const lfFile = "---\nid: peb-abcd\ntitle: Title\ntype: bug\nstatus: new\ncreated: \"2024-01-01T00:00:00Z\"\nchanged: \"2024-01-01T00:00:00Z\"\n---\nContent\n---\nMore content\n"
+
+func TestReadFileFormats(t *testing.T) {
+	crlf := strings.ReplaceAll(lfFile, "\n", "\r\n")
+	tests := []struct {
+		name   string
+		data   string
+		format Format
+	}{
+		{"lf", lfFile, Format{}},
+		{"crlf", crlf, Format{CRLF: true}},
+		{"bom", "\ufeff" + lfFile, Format{BOM: true}},
+		{"bom crlf", "\ufeff" + crlf, Format{CRLF: true, BOM: true}},
+		{"trailing spaces", strings.Replace(lfFile, "---\n", "--- \n", 2), Format{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "peb.md")
+			if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			p, err := ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.ID != "peb-abcd" || p.Title != "Title" || p.Format != tt.format {
+				t.Errorf("ReadFile() = %+v", p)
+			}
+			if p.Content != "Content\n---\nMore content\n" {
+				t.Errorf("Content = %q", p.Content)
+			}
+
+			data, err := Marshal(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := strings.Replace(tt.data, "--- \n", "---\n", 2)
+			if string(data) != want {
+				t.Errorf("Marshal() = %q, want %q", data, want)
+			}
+		})
+	}
+}
+
+func TestReadFileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		line int
+	}{
+		{"empty", "", 1},
+		{"no opening delimiter", "id: peb-abcd\n---\n", 1},
+		{"no closing delimiter", "---\nid: peb-abcd\ntitle: Title\n", 3},
+		{"invalid yaml", "---\nid: peb-abcd\ntitle: a: b\n---\n", 3},
+		{"wrong type", "---\nid: peb-abcd\ntitle: Title\nblocked-by: peb-1111\n---\n", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "peb.md")
+			if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := ReadFile(path)
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("ReadFile() error = %v, want a ParseError", err)
+			}
+			if parseErr.Path != path || parseErr.Line != tt.line {
+				t.Errorf("ReadFile() error = %v, want line %d", err, tt.line)
+			}
+		})
+	}
+}
+
+func TestMarshalKeepsFrontmatterLayout(t *testing.T) {
+	data := `---
+# Tracked since the kickoff.
+
+title: 'Title'
+id: peb-abcd # do not change
+owner: alice
+status: new # until triaged
+type: bug
+created: "2024-01-01T00:00:00Z"
+changed: "2024-01-01T00:00:00Z"
+---
+Content
+`
+	p, err := Unmarshal([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Status = StatusInProgress
+	p.BlockedBy = []string{"peb-efgh"}
+	p.Extra = append(p.Extra, &yaml.Node{Kind: yaml.ScalarNode, Value: "estimate"}, &yaml.Node{Kind: yaml.ScalarNode, Value: "3"})
+
+	written, err := Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `---
+# Tracked since the kickoff.
+
+title: 'Title'
+id: peb-abcd # do not change
+owner: alice
+status: in-progress # until triaged
+type: bug
+created: "2024-01-01T00:00:00Z"
+changed: "2024-01-01T00:00:00Z"
+blocked-by:
+    - peb-efgh
+estimate: 3
+---
+Content
+`
+	if string(written) != want {
+		t.Errorf("Marshal() =\n%s\nwant\n%s", written, want)
+	}
+}
+
+func FuzzReadWriteFile(f *testing.F) {
+	f.Add([]byte(lfFile))
+	f.Add([]byte(strings.ReplaceAll(lfFile, "\n", "\r\n")))
+	f.Add([]byte("\ufeff" + lfFile))
+	f.Add([]byte("---\r\nid: peb-abcd\r\nowner: alice # comment\r\nlabels: [a, b]\r\n---"))
+	f.Add([]byte("---\n---\n---\n"))
+	f.Add([]byte("---\ntitle: &t Title\nalias: *t\nbase: &b {x: 1}\nmerged: {<<: *b, y: 2}\n---\n"))
+	f.Add([]byte("---\n<<: {title: Merged}\n? [complex, key]\n: value\n---\n"))
+
+	dir := f.TempDir()
+	f.Fuzz(func(t *testing.T, data []byte) {
+		path := filepath.Join(dir, "input.md")
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		p, err := ReadFile(path)
+		if err != nil {
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("ReadFile() error %v is not a ParseError", err)
+			}
+			return
+		}
+
+		// Filename depends on the fuzzed title, so write to a directory of
+		// its own.
+		out := t.TempDir()
+		if err := WriteFile(out, p); err != nil {
+			t.Skip("cannot be written:", err)
+		}
+		written := filepath.Join(out, Filename(p))
+		again, err := ReadFile(written)
+		if err != nil {
+			first, _ := os.ReadFile(written)
+			t.Fatalf("ReadFile() of written file: %v\n%q", err, first)
+		}
+		if !samePeb(p, again) {
+			t.Fatalf("round trip changed the peb:\n%+v\n%+v", p, again)
+		}
+
+		first, _ := os.ReadFile(written)
+		if err := WriteFile(out, again); err != nil {
+			t.Fatal(err)
+		}
+		second, _ := os.ReadFile(written)
+		if string(first) != string(second) {
+			t.Fatalf("rewriting is not stable:\n%q\n%q", first, second)
+		}
+	})
+}
+
+// samePeb compares the fields of two pebs and the JSON of their extras,
+// ignoring the frontmatter they were read from.
+func samePeb(a, b *Peb) bool {
+	extraA, errA := a.Extra.MarshalJSON()
+	extraB, errB := b.Extra.MarshalJSON()
+	x, y := *a, *b
+	x.Extra, y.Extra = nil, nil
+	x.Frontmatter, y.Frontmatter = nil, nil
+	return reflect.DeepEqual(x, y) && string(extraA) == string(extraB) && (errA == nil) == (errB == nil)
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"math/big"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 )
 
@@ -76,3 +78,18 @@ func ParseID(filename string, prefix string) (string, error) {
 	}
 	return fmt.Sprintf("%s-%s", prefix, parts[1]), nil
 }
+
+func ReplaceIDs(text string, renamed map[string]string) string {
+	if len(renamed) == 0 {
+		return text
+	}
+	ids := make([]string, 0, len(renamed))
+	for id := range renamed {
+		ids = append(ids, regexp.QuoteMeta(id))
+	}
+	slices.SortFunc(ids, func(a, b string) int { return len(b) - len(a) })
+	pattern := regexp.MustCompile(`\b(` + strings.Join(ids, "|") + `)\b`)
+	return pattern.ReplaceAllStringFunc(text, func(id string) string {
+		return renamed[id]
+	})
+}
@@ -67,6 +67,20 @@ func TestParseID(t *testing.T) {
 			want:     "task-xyz",
 			wantErr:  false,
 		},
+		{
+			name:     "sequential ID",
+			filename: "peb-42--fix-login-bug.md",
+			prefix:   "peb",
+			want:     "peb-42",
+			wantErr:  false,
+		},
+		{
+			name:     "hierarchical ID",
+			filename: "peb-abcd.1.2--fix-login-bug.md",
+			prefix:   "peb",
+			want:     "peb-abcd.1.2",
+			wantErr:  false,
+		},
 		{
 			name:     "wrong prefix",
 			filename: "peb-abcd--fix-login-bug.md",
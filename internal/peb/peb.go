@@ -1,6 +1,10 @@
 package peb
 
-import "time"
+import (
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
 
 const timestampFormat = "2006-01-02T15:04:05-07:00"
 
@@ -22,6 +26,9 @@ const (
 	StatusWontFix    Status = "wont-fix"
 )
 
+var Types = []Type{TypeBug, TypeFeature, TypeEpic, TypeTask}
+var Statuses = []Status{StatusNew, StatusInProgress, StatusFixed, StatusWontFix}
+
 var StatusOpen = []Status{StatusNew, StatusInProgress}
 var StatusClosed = []Status{StatusFixed, StatusWontFix}
 
@@ -33,7 +40,20 @@ type Peb struct {
 	Created   string   `yaml:"created" json:"created"`
 	Changed   string   `yaml:"changed" json:"changed"`
 	BlockedBy []string `yaml:"blocked-by,omitempty" json:"blocked-by,omitempty"`
+	Source    string   `yaml:"source,omitempty" json:"source,omitempty"`
+	Commits   []string `yaml:"commits,omitempty" json:"commits,omitempty"` // git commit hashes or jj change IDs
+	Extra     Extra    `yaml:"-" json:"extra,omitempty"`
 	Content   string   `yaml:"-" json:"content"`
+	// Frontmatter keeps the order of keys and the comments of the file.
+	Frontmatter *yaml.Node `yaml:"-" json:"-"`
+	Format      Format     `yaml:"-" json:"-"`
+}
+
+// Format is kept when a file is written back. Content always uses "\n" line
+// endings in memory.
+type Format struct {
+	CRLF bool
+	BOM  bool
 }
 
 type PebJSON struct {
@@ -43,7 +63,18 @@ type PebJSON struct {
 	Title     string   `json:"title,omitempty"`
 	Created   string   `json:"created,omitempty"`
 	Changed   string   `json:"changed,omitempty"`
+	Age       string   `json:"age,omitempty"`
+	Filename  string   `json:"filename,omitempty"`
+	Ready     *bool    `json:"ready,omitempty"`
+	Blocks    []string `json:"blocks,omitempty"`
 	BlockedBy []string `json:"blocked-by,omitempty"`
+	Source    string   `json:"source,omitempty"`
+	Commits   []string `json:"commits,omitempty"`
+	Extra     Extra    `json:"extra,omitempty"`
+	// ContentSummary and Content are pointers so that a requested but empty
+	// content is output as "".
+	ContentSummary *string `json:"content-summary,omitempty"`
+	Content        *string `json:"content,omitempty"`
 }
 
 func New(id, title string, pebType Type, status Status, content string) *Peb {
@@ -60,6 +91,14 @@ func New(id, title string, pebType Type, status Status, content string) *Peb {
 	}
 }
 
+func ParseTimestamp(s string) (time.Time, error) {
+	return time.Parse(timestampFormat, s)
+}
+
+func FormatTimestamp(t time.Time) string {
+	return t.Local().Format(timestampFormat)
+}
+
 func (p *Peb) UpdateTimestamp() {
 	p.Changed = time.Now().Local().Format(timestampFormat)
 }
@@ -0,0 +1,126 @@
+package peb
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// IDScheme determines the IDs given to new pebs. IDs of all schemes coexist
+// in a .pebbles directory.
+type IDScheme string
+
+const (
+	IDSchemeRandom     IDScheme = "random"
+	IDSchemeSequential IDScheme = "sequential"
+	// IDSchemeHierarchical numbers children after their parent, e.g.
+	// peb-ab12.1. Pebs without a parent get random IDs.
+	IDSchemeHierarchical IDScheme = "hierarchical"
+)
+
+var IDSchemes = []IDScheme{IDSchemeRandom, IDSchemeSequential, IDSchemeHierarchical}
+
+func ParseIDScheme(name string) (IDScheme, error) {
+	if name == "" {
+		return IDSchemeRandom, nil
+	}
+	for _, scheme := range IDSchemes {
+		if string(scheme) == name {
+			return scheme, nil
+		}
+	}
+	return "", fmt.Errorf("invalid ID scheme %q: must be one of random, sequential, hierarchical", name)
+}
+
+// Spread keeps branches from numbering new pebs alike: only numbers that
+// leave the remainder Slot when divided by Stride are taken. The zero Spread
+// numbers densely.
+type Spread struct {
+	Stride int
+	Slot   int
+}
+
+func (s Spread) next(highest int) int {
+	n := highest + 1
+	if s.Stride > 1 {
+		n += ((s.Slot-n)%s.Stride + s.Stride) % s.Stride
+	}
+	return n
+}
+
+// NextID returns an ID that is not in ids. Only the hierarchical scheme uses
+// parent.
+func (scheme IDScheme) NextID(prefix string, length int, parent string, ids []string, spread Spread) (string, error) {
+	switch scheme {
+	case IDSchemeSequential:
+		return fmt.Sprintf("%s-%d", prefix, spread.next(highestNumber(prefix+"-", ids))), nil
+	case IDSchemeHierarchical:
+		if parent != "" {
+			return fmt.Sprintf("%s.%d", parent, spread.next(highestNumber(parent+".", ids))), nil
+		}
+	case "", IDSchemeRandom:
+	default:
+		return "", fmt.Errorf("invalid ID scheme %q", scheme)
+	}
+
+	taken := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		taken[id] = true
+	}
+	const maxAttempts = 1000
+	for range maxAttempts {
+		id, err := GenerateID(prefix, length)
+		if err != nil {
+			return "", err
+		}
+		if !taken[id] {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("failed to generate unique ID after %d attempts", maxAttempts)
+}
+
+func highestNumber(base string, ids []string) int {
+	highest := 0
+	for _, id := range ids {
+		suffix, ok := strings.CutPrefix(id, base)
+		if !ok || suffix == "" || strings.Trim(suffix, "0123456789") != "" {
+			continue
+		}
+		if n, err := strconv.Atoi(suffix); err == nil && n > highest {
+			highest = n
+		}
+	}
+	return highest
+}
+
+// Examples returns three placeholder IDs for documentation.
+func (scheme IDScheme) Examples(prefix string, length int) [3]string {
+	switch scheme {
+	case IDSchemeSequential:
+		return [3]string{prefix + "-1", prefix + "-2", prefix + "-3"}
+	case IDSchemeHierarchical:
+		root := prefix + "-" + strings.Repeat("x", length)
+		return [3]string{root, root + ".1", root + ".2"}
+	}
+	return [3]string{
+		prefix + "-" + strings.Repeat("x", length),
+		prefix + "-" + strings.Repeat("y", length),
+		prefix + "-" + strings.Repeat("z", length),
+	}
+}
+
+// IDPattern matches the IDs of every scheme.
+func IDPattern(prefix string, length int) string {
+	return regexp.QuoteMeta(prefix) + `-(?:[0-9]+|[0-9a-z]{` + strconv.Itoa(length) + `})(?:\.[0-9]+)*`
+}
+
+// ParentID returns "" if id is not the hierarchical ID of a child.
+func ParentID(id string) string {
+	i := strings.LastIndexByte(id, '.')
+	if i < 0 || i == len(id)-1 || strings.Trim(id[i+1:], "0123456789") != "" {
+		return ""
+	}
+	return id[:i]
+}
@@ -0,0 +1,113 @@
+package peb
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestNextID(t *testing.T) {
+	ids := []string{"peb-ab12", "peb-3", "peb-10", "peb-ab12.1", "peb-ab12.4", "peb-ab12.4.1", "web-99"}
+
+	tests := []struct {
+		name   string
+		scheme IDScheme
+		parent string
+		spread Spread
+		want   string
+	}{
+		{"sequential", IDSchemeSequential, "", Spread{}, "peb-11"},
+		{"sequential ignores parent", IDSchemeSequential, "peb-ab12", Spread{}, "peb-11"},
+		{"sequential in a slot", IDSchemeSequential, "", Spread{Stride: 10, Slot: 7}, "peb-17"},
+		{"sequential in slot 0", IDSchemeSequential, "", Spread{Stride: 10, Slot: 0}, "peb-20"},
+		{"sequential in the next slot", IDSchemeSequential, "", Spread{Stride: 10, Slot: 1}, "peb-11"},
+		{"hierarchical child", IDSchemeHierarchical, "peb-ab12", Spread{}, "peb-ab12.5"},
+		{"hierarchical child in a slot", IDSchemeHierarchical, "peb-ab12", Spread{Stride: 10, Slot: 3}, "peb-ab12.13"},
+		{"hierarchical grandchild", IDSchemeHierarchical, "peb-ab12.4", Spread{}, "peb-ab12.4.2"},
+		{"hierarchical first child", IDSchemeHierarchical, "peb-10", Spread{}, "peb-10.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.scheme.NextID("peb", 4, tt.parent, ids, tt.spread)
+			if err != nil {
+				t.Fatalf("NextID() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("NextID() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	for _, scheme := range []IDScheme{IDSchemeRandom, IDSchemeHierarchical, ""} {
+		got, err := scheme.NextID("peb", 4, "", ids, Spread{})
+		if err != nil {
+			t.Fatalf("NextID() error = %v", err)
+		}
+		if !regexp.MustCompile(`^peb-[0-9a-z]{4}$`).MatchString(got) {
+			t.Errorf("%q scheme: NextID() = %s, want a random ID", scheme, got)
+		}
+	}
+
+	if got, err := IDSchemeSequential.NextID("peb", 4, "", nil, Spread{}); err != nil || got != "peb-1" {
+		t.Errorf("NextID() without pebs = %s, %v, want peb-1", got, err)
+	}
+	if _, err := IDScheme("counter").NextID("peb", 4, "", ids, Spread{}); err == nil {
+		t.Error("expected error for invalid scheme")
+	}
+}
+
+func TestParseIDScheme(t *testing.T) {
+	if scheme, err := ParseIDScheme(""); err != nil || scheme != IDSchemeRandom {
+		t.Errorf("ParseIDScheme(\"\") = %q, %v, want random", scheme, err)
+	}
+	if scheme, err := ParseIDScheme("hierarchical"); err != nil || scheme != IDSchemeHierarchical {
+		t.Errorf("ParseIDScheme(\"hierarchical\") = %q, %v", scheme, err)
+	}
+	if _, err := ParseIDScheme("uuid"); err == nil {
+		t.Error("expected error for unknown scheme")
+	}
+}
+
+func TestIDPattern(t *testing.T) {
+	pattern := regexp.MustCompile(`\b` + IDPattern("peb", 4) + `\b`)
+	for text, want := range map[string]string{
+		"fixes peb-ab12":       "peb-ab12",
+		"fixes peb-42.":        "peb-42",
+		"fixes peb-ab12.1.3":   "peb-ab12.1.3",
+		"fixes peb-ab123":      "",
+		"fixes peb-abc":        "",
+		"fixes web-ab12":       "",
+		"fixes peb-ab12.x":     "peb-ab12",
+		"see (peb-7) and more": "peb-7",
+	} {
+		if got := pattern.FindString(text); got != want {
+			t.Errorf("match in %q = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestExamples(t *testing.T) {
+	if got := IDSchemeRandom.Examples("peb", 3); got != [3]string{"peb-xxx", "peb-yyy", "peb-zzz"} {
+		t.Errorf("random examples = %v", got)
+	}
+	if got := IDSchemeSequential.Examples("peb", 3); got != [3]string{"peb-1", "peb-2", "peb-3"} {
+		t.Errorf("sequential examples = %v", got)
+	}
+	if got := IDSchemeHierarchical.Examples("peb", 3); got != [3]string{"peb-xxx", "peb-xxx.1", "peb-xxx.2"} {
+		t.Errorf("hierarchical examples = %v", got)
+	}
+}
+
+func TestParentID(t *testing.T) {
+	for id, want := range map[string]string{
+		"peb-ab12":     "",
+		"peb-42":       "",
+		"peb-ab12.1":   "peb-ab12",
+		"peb-ab12.1.2": "peb-ab12.1",
+		"peb-ab12.":    "",
+		"peb-ab12.x":   "",
+	} {
+		if got := ParentID(id); got != want {
+			t.Errorf("ParentID(%q) = %q, want %q", id, got, want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package peb
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 )
 
@@ -26,6 +27,20 @@ func ValidateBlockedBy(store Store, peb *Peb, blockedBy []string) error {
 	return nil
 }
 
+func ValidateType(t Type) error {
+	if !slices.Contains(Types, t) {
+		return fmt.Errorf("invalid type %q: must be one of bug, feature, epic, task", t)
+	}
+	return nil
+}
+
+func ValidateStatus(s Status) error {
+	if !slices.Contains(Statuses, s) {
+		return fmt.Errorf("invalid status %q: must be one of new, in-progress, fixed, wont-fix", s)
+	}
+	return nil
+}
+
 func HasInvalidReference(err error) bool {
 	return err != nil && strings.Contains(err.Error(), ErrInvalidReference.Error())
 }
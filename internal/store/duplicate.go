@@ -0,0 +1,79 @@
+package store
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"go.yozora.eu/pebbles/internal/peb"
+)
+
+// DuplicateError reports an ID that is used by several peb files. Get
+// refuses such IDs, since it cannot tell which peb is meant.
+type DuplicateError struct {
+	ID        string
+	Filenames []string
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("duplicate peb ID %s in %s", e.ID, strings.Join(e.Filenames, ", "))
+}
+
+// Duplicate is one of the files that share an ID. Peb is nil if it cannot be
+// read.
+type Duplicate struct {
+	Filename string
+	Peb      *peb.Peb
+	Err      error
+}
+
+func (s *Store) DuplicateIDs() []string {
+	ids := make([]string, 0, len(s.duplicates))
+	for id := range s.duplicates {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Duplicates are sorted by file name.
+func (s *Store) Duplicates(id string) []Duplicate {
+	var result []Duplicate
+	for _, filename := range s.duplicates[id] {
+		p, err := peb.ReadFile(filepath.Join(s.dir, filename))
+		result = append(result, Duplicate{Filename: filename, Peb: p, Err: err})
+	}
+	return result
+}
+
+// MoveDuplicate returns the changes for Apply that give the peb of d the new
+// ID of p.
+func (s *Store) MoveDuplicate(d Duplicate, p *peb.Peb) []Change {
+	return []Change{{New: p}, {Old: d.Peb, filename: d.Filename}}
+}
+
+// UpdateDuplicate returns the change for Apply that writes p, which has the
+// ID and title of d, over the file of d once the other duplicates have moved.
+func (s *Store) UpdateDuplicate(d Duplicate, p *peb.Peb) Change {
+	return Change{Old: d.Peb, New: p, filename: d.Filename}
+}
+
+// Once a single file is left, id refers to it.
+func (s *Store) removeDuplicate(id, filename string) {
+	filenames := slices.DeleteFunc(slices.Clone(s.duplicates[id]), func(f string) bool { return f == filename })
+	if len(filenames) > 1 {
+		s.duplicates[id] = filenames
+		s.filenames[id] = filenames[0]
+		return
+	}
+	delete(s.duplicates, id)
+	delete(s.invalid, id)
+	delete(s.cache, id)
+	if len(filenames) == 1 {
+		s.filenames[id] = filenames[0]
+	} else {
+		delete(s.filenames, id)
+	}
+}
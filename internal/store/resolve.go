@@ -0,0 +1,111 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrNotFound  = errors.New("not found")
+	ErrAmbiguous = errors.New("ambiguous")
+)
+
+// Resolve accepts the ID without the prefix (ab12 for peb-ab12) or a prefix
+// of either that matches a single peb, in any case. Otherwise the error lists
+// the matching pebs or suggests the closest one.
+func (s *Store) Resolve(input string) (string, error) {
+	if _, ok := s.filenames[input]; ok {
+		return input, nil
+	}
+
+	needle := strings.ToLower(input)
+	prefix := strings.ToLower(s.prefix) + "-"
+	var candidates []string
+	for id := range s.filenames {
+		lower := strings.ToLower(id)
+		if lower == needle || strings.TrimPrefix(lower, prefix) == needle {
+			return id, nil
+		}
+		if strings.HasPrefix(lower, needle) || strings.HasPrefix(strings.TrimPrefix(lower, prefix), needle) {
+			candidates = append(candidates, id)
+		}
+	}
+	sort.Strings(candidates)
+
+	switch {
+	case len(candidates) == 1 && needle != "":
+		return candidates[0], nil
+	case len(candidates) > 1 && needle != "":
+		described := make([]string, len(candidates))
+		for i, id := range candidates {
+			described[i] = s.describe(id)
+		}
+		return "", fmt.Errorf("peb ID %q is %w, it matches %s", input, ErrAmbiguous, strings.Join(described, ", "))
+	}
+
+	if suggestion := s.suggest(needle); suggestion != "" {
+		return "", fmt.Errorf("peb %s %w, did you mean %s?", input, ErrNotFound, s.describe(suggestion))
+	}
+	return "", fmt.Errorf("peb %s %w", input, ErrNotFound)
+}
+
+func (s *Store) describe(id string) string {
+	if p, ok := s.Get(id); ok {
+		return fmt.Sprintf("%s (%q)", id, p.Title)
+	}
+	return id
+}
+
+// suggest returns "" if no ID or title is close enough to be a likely typo.
+func (s *Store) suggest(needle string) string {
+	prefix := strings.ToLower(s.prefix) + "-"
+	suffix := strings.TrimPrefix(needle, prefix)
+	if suffix == "" {
+		return ""
+	}
+
+	ids := make([]string, 0, len(s.filenames))
+	for id := range s.filenames {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	// Allow about one typo per three characters.
+	best, bestScore := "", 1.0
+	consider := func(id, input, name string) {
+		score := float64(editDistance(input, name)) / float64(len(input)/3+1)
+		if score < bestScore || (score == bestScore && best == "") {
+			best, bestScore = id, score
+		}
+	}
+	for _, id := range ids {
+		consider(id, suffix, strings.TrimPrefix(strings.ToLower(id), prefix))
+		if p, ok := s.Get(id); ok {
+			consider(id, needle, strings.ToLower(p.Title))
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"go.yozora.eu/pebbles/internal/peb"
+)
+
+func TestResolve(t *testing.T) {
+	s := New(t.TempDir(), "peb")
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []*peb.Peb{
+		peb.New("peb-ab12", "Fix login bug", peb.TypeBug, peb.StatusNew, ""),
+		peb.New("peb-ab34", "Add dark mode", peb.TypeFeature, peb.StatusNew, ""),
+		peb.New("peb-cd56", "Write docs", peb.TypeTask, peb.StatusNew, ""),
+	} {
+		if err := s.Save(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		input string
+		want  string
+		err   error
+		hint  string
+	}{
+		{input: "peb-ab12", want: "peb-ab12"},
+		{input: "ab12", want: "peb-ab12"},
+		{input: "PEB-AB12", want: "peb-ab12"},
+		{input: "AB34", want: "peb-ab34"},
+		{input: "c", want: "peb-cd56"},
+		{input: "peb-cd", want: "peb-cd56"},
+		{input: "ab", err: ErrAmbiguous, hint: `peb-ab12 ("Fix login bug"), peb-ab34 ("Add dark mode")`},
+		{input: "", err: ErrNotFound},
+		{input: "ab19", err: ErrNotFound, hint: `did you mean peb-ab12 ("Fix login bug")?`},
+		{input: "peb-cd57", err: ErrNotFound, hint: "did you mean peb-cd56"},
+		{input: "fix logn bug", err: ErrNotFound, hint: "did you mean peb-ab12"},
+		{input: "zzzz", err: ErrNotFound},
+	}
+	for _, tt := range tests {
+		got, err := s.Resolve(tt.input)
+		if tt.err == nil {
+			if err != nil || got != tt.want {
+				t.Errorf("Resolve(%q) = %q, %v, want %q", tt.input, got, err, tt.want)
+			}
+			continue
+		}
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Resolve(%q) error = %v, want %v", tt.input, err, tt.err)
+			continue
+		}
+		if tt.hint != "" && !strings.Contains(err.Error(), tt.hint) {
+			t.Errorf("Resolve(%q) error = %v, want it to contain %q", tt.input, err, tt.hint)
+		}
+		if tt.hint == "" && strings.Contains(err.Error(), "did you mean") {
+			t.Errorf("Resolve(%q) error = %v, want no suggestion", tt.input, err)
+		}
+	}
+}
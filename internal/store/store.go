@@ -1,9 +1,11 @@
 package store
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"go.yozora.eu/pebbles/internal/peb"
@@ -12,14 +14,31 @@ import (
 type Store struct {
 	cache     map[string]*peb.Peb
 	filenames map[string]string
-	dir       string
-	prefix    string
+	invalid   map[string]error
+	foreign   []string
+	// duplicates maps the IDs used by several files to their names.
+	duplicates map[string][]string
+	dir        string
+	prefix     string
+	hooks      Hooks
+}
+
+// Hooks observes store mutations. old is nil when a peb is created and new is
+// nil when it is deleted. An error from Before aborts the mutation.
+type Hooks interface {
+	Before(old, new *peb.Peb) error
+	After(old, new *peb.Peb) error
+}
+
+func (s *Store) SetHooks(h Hooks) {
+	s.hooks = h
 }
 
 func New(dir string, prefix string) *Store {
 	return &Store{
 		cache:     make(map[string]*peb.Peb),
 		filenames: make(map[string]string),
+		invalid:   make(map[string]error),
 		dir:       dir,
 		prefix:    prefix,
 	}
@@ -27,6 +46,10 @@ func New(dir string, prefix string) *Store {
 
 func (s *Store) Load() error {
 	s.cache = make(map[string]*peb.Peb)
+	s.filenames = make(map[string]string)
+	s.invalid = make(map[string]error)
+	s.foreign = nil
+	s.duplicates = make(map[string][]string)
 	entries, err := os.ReadDir(s.dir)
 	if err != nil {
 		return fmt.Errorf("failed to read pebbles directory: %w", err)
@@ -44,6 +67,14 @@ func (s *Store) Load() error {
 
 		id, err := peb.ParseID(name, s.prefix)
 		if err != nil {
+			s.foreign = append(s.foreign, name)
+			continue
+		}
+		if first, ok := s.filenames[id]; ok {
+			if len(s.duplicates[id]) == 0 {
+				s.duplicates[id] = []string{first}
+			}
+			s.duplicates[id] = append(s.duplicates[id], name)
 			continue
 		}
 		s.filenames[id] = name
@@ -61,12 +92,18 @@ func (s *Store) Get(id string) (*peb.Peb, bool) {
 	if !ok {
 		return nil, false
 	}
+	if filenames, ok := s.duplicates[id]; ok {
+		s.invalid[id] = &DuplicateError{ID: id, Filenames: filenames}
+		return nil, false
+	}
 
 	path := filepath.Join(s.dir, filename)
 	p, err := peb.ReadFile(path)
 	if err != nil {
+		s.invalid[id] = err
 		return nil, false
 	}
+	delete(s.invalid, id)
 
 	filtered := make([]string, 0, len(p.BlockedBy))
 	for _, bid := range p.BlockedBy {
@@ -90,14 +127,138 @@ func (s *Store) Save(p *peb.Peb) error {
 	}
 	cleaned.BlockedBy = filtered
 
-	if err := peb.WriteFile(s.dir, &cleaned); err != nil {
-		return fmt.Errorf("failed to save peb: %w", err)
+	var old *peb.Peb
+	if s.hooks != nil {
+		// Read the previous state from disk, since callers usually modify the
+		// cached peb in place before saving it.
+		if filename, ok := s.filenames[cleaned.ID]; ok {
+			old, _ = peb.ReadFile(filepath.Join(s.dir, filename))
+		}
+	}
+
+	return s.Apply([]Change{{Old: old, New: &cleaned}})
+}
+
+// Change is a single mutation for Apply. Old is the state before the change
+// and nil if the peb is created; New is nil if the peb is deleted.
+type Change struct {
+	Old *peb.Peb
+	New *peb.Peb
+	// filename is the file of Old if its ID is used by several files, see
+	// MoveDuplicate and UpdateDuplicate.
+	filename string
+}
+
+// Apply writes changes as a unit: all pre-hooks run before anything is
+// written, and the changes already written are rolled back if a later one
+// fails.
+func (s *Store) Apply(changes []Change) error {
+	if s.hooks != nil {
+		for _, c := range changes {
+			if err := s.hooks.Before(c.Old, c.New); err != nil {
+				return err
+			}
+		}
+	}
+
+	var undo []func() error
+	for _, c := range changes {
+		u, err := s.write(c)
+		if err != nil {
+			for i := len(undo) - 1; i >= 0; i-- {
+				undo[i]()
+			}
+			return err
+		}
+		undo = append(undo, u)
+	}
+
+	for _, c := range changes {
+		if c.filename != "" && c.New == nil {
+			s.removeDuplicate(c.Old.ID, c.filename)
+			continue
+		}
+		if c.New == nil {
+			delete(s.cache, c.Old.ID)
+			delete(s.filenames, c.Old.ID)
+			continue
+		}
+		s.cache[c.New.ID] = c.New
+		s.filenames[c.New.ID] = peb.Filename(c.New)
+	}
+
+	if s.hooks == nil {
+		return nil
+	}
+	var errs []error
+	for _, c := range changes {
+		if err := s.hooks.After(c.Old, c.New); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return &AppliedError{Err: errors.Join(errs...)}
 	}
-	s.cache[cleaned.ID] = &cleaned
-	s.filenames[cleaned.ID] = peb.Filename(&cleaned)
 	return nil
 }
 
+// AppliedError is returned by Apply if post-hooks fail. Unlike its other
+// errors, the changes have been written and stay in effect.
+type AppliedError struct {
+	Err error
+}
+
+func (e *AppliedError) Error() string { return e.Err.Error() }
+func (e *AppliedError) Unwrap() error { return e.Err }
+
+func (s *Store) write(c Change) (func() error, error) {
+	if c.New == nil {
+		filename, ok := c.filename, c.filename != ""
+		if !ok {
+			filename, ok = s.filenames[c.Old.ID]
+		}
+		if !ok {
+			filename = peb.Filename(c.Old)
+		}
+		if err := os.Remove(filepath.Join(s.dir, filename)); err != nil {
+			return nil, fmt.Errorf("failed to delete peb file: %w", err)
+		}
+		return func() error {
+			return peb.WriteFile(s.dir, c.Old)
+		}, nil
+	}
+
+	oldFilename, exists := s.filenames[c.New.ID]
+	if c.filename != "" {
+		oldFilename, exists = c.filename, true
+	}
+	if err := peb.WriteFile(s.dir, c.New); err != nil {
+		return nil, fmt.Errorf("failed to save peb: %w", err)
+	}
+
+	newFilename := peb.Filename(c.New)
+	undo := func() error {
+		if !exists || oldFilename != newFilename {
+			if err := os.Remove(filepath.Join(s.dir, newFilename)); err != nil {
+				return err
+			}
+		}
+		if exists && c.Old != nil {
+			return peb.WriteFile(s.dir, c.Old)
+		}
+		return nil
+	}
+
+	// The file name contains the title slug, so a title change renames it.
+	if exists && oldFilename != newFilename {
+		if err := os.Remove(filepath.Join(s.dir, oldFilename)); err != nil && !os.IsNotExist(err) {
+			undo()
+			return nil, fmt.Errorf("failed to remove old peb file: %w", err)
+		}
+	}
+	return undo, nil
+}
+
 func (s *Store) All() []*peb.Peb {
 	result := make([]*peb.Peb, 0, len(s.filenames))
 	for id := range s.filenames {
@@ -108,20 +269,56 @@ func (s *Store) All() []*peb.Peb {
 	return result
 }
 
+func (s *Store) ReadError(id string) error {
+	return s.invalid[id]
+}
+
+// Invalid returns the errors sorted by file name.
+func (s *Store) Invalid() []error {
+	s.All()
+	ids := make([]string, 0, len(s.invalid))
+	for id := range s.invalid {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return s.filenames[ids[i]] < s.filenames[ids[j]] })
+	errs := make([]error, 0, len(ids))
+	for _, id := range ids {
+		errs = append(errs, s.invalid[id])
+	}
+	return errs
+}
+
+// Foreign returns the sorted names of the .md files without the prefix.
+func (s *Store) Foreign() []string {
+	return s.foreign
+}
+
+func (s *Store) Filename(id string) (string, bool) {
+	filename, ok := s.filenames[id]
+	return filename, ok
+}
+
+func (s *Store) Blocks(id string) []string {
+	var result []string
+	for _, p := range s.All() {
+		for _, bid := range p.BlockedBy {
+			if bid == id {
+				result = append(result, p.ID)
+				break
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
 func (s *Store) Exists(id string) bool {
 	_, ok := s.filenames[id]
 	return ok
 }
 
 func (s *Store) Delete(p *peb.Peb) error {
-	filename := peb.Filename(p)
-	path := filepath.Join(s.dir, filename)
-	if err := os.Remove(path); err != nil {
-		return fmt.Errorf("failed to delete peb file: %w", err)
-	}
-	delete(s.cache, p.ID)
-	delete(s.filenames, p.ID)
-	return nil
+	return s.Apply([]Change{{Old: p}})
 }
 
 func (s *Store) GenerateUniqueID(prefix string, length int) (string, error) {
@@ -137,3 +334,23 @@ func (s *Store) GenerateUniqueID(prefix string, length int) (string, error) {
 	}
 	return "", fmt.Errorf("failed to generate unique ID after %d attempts", maxAttempts)
 }
+
+func (s *Store) IDs() []string {
+	ids := make([]string, 0, len(s.filenames))
+	for id := range s.filenames {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// OpenBlockers returns the pebs in p's blocked-by list that are still open.
+func (s *Store) OpenBlockers(p *peb.Peb) []*peb.Peb {
+	var result []*peb.Peb
+	for _, id := range p.BlockedBy {
+		if blocker, ok := s.Get(id); ok && peb.IsOpen(blocker.Status) {
+			result = append(result, blocker)
+		}
+	}
+	return result
+}
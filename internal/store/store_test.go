@@ -1,8 +1,10 @@
 package store
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"go.yozora.eu/pebbles/internal/peb"
@@ -537,3 +539,269 @@ func TestGetCleansBlockedBy(t *testing.T) {
 		t.Errorf("expected blocked-by %s, got %s", blockingID2, gotPeb.BlockedBy[0])
 	}
 }
+
+func TestOpenBlockers(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := New(tmpDir, "peb")
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	open := peb.New("peb-aaaa", "Open blocker", peb.TypeTask, peb.StatusInProgress, "Content")
+	closed := peb.New("peb-bbbb", "Closed blocker", peb.TypeTask, peb.StatusFixed, "Content")
+	for _, p := range []*peb.Peb{open, closed} {
+		if err := s.Save(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := peb.New("peb-cccc", "Blocked peb", peb.TypeTask, peb.StatusNew, "Content")
+	p.BlockedBy = []string{"peb-aaaa", "peb-bbbb"}
+
+	blockers := s.OpenBlockers(p)
+	if len(blockers) != 1 || blockers[0].ID != "peb-aaaa" {
+		t.Errorf("expected only peb-aaaa to be an open blocker, got %v", blockers)
+	}
+
+	p.BlockedBy = []string{"peb-bbbb"}
+	if blockers := s.OpenBlockers(p); len(blockers) != 0 {
+		t.Errorf("expected no open blockers, got %v", blockers)
+	}
+}
+
+func TestBlocksAndFilename(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := New(tmpDir, "peb")
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	blocker := peb.New("peb-aaaa", "Blocker", peb.TypeTask, peb.StatusNew, "Content")
+	if err := s.Save(blocker); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"peb-cccc", "peb-bbbb"} {
+		p := peb.New(id, "Blocked", peb.TypeTask, peb.StatusNew, "Content")
+		p.BlockedBy = []string{"peb-aaaa"}
+		if err := s.Save(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	blocks := s.Blocks("peb-aaaa")
+	if len(blocks) != 2 || blocks[0] != "peb-bbbb" || blocks[1] != "peb-cccc" {
+		t.Errorf("expected [peb-bbbb peb-cccc], got %v", blocks)
+	}
+	if blocks := s.Blocks("peb-bbbb"); len(blocks) != 0 {
+		t.Errorf("expected no blocked pebs, got %v", blocks)
+	}
+
+	filename, ok := s.Filename("peb-aaaa")
+	if !ok || filename != "peb-aaaa--blocker.md" {
+		t.Errorf("unexpected filename %q (found: %v)", filename, ok)
+	}
+	if _, ok := s.Filename("peb-zzzz"); ok {
+		t.Error("expected no filename for unknown peb")
+	}
+}
+
+func TestSaveRenamesOnTitleChange(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := New(tmpDir, "peb")
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	p := peb.New("peb-aaaa", "Old title", peb.TypeTask, peb.StatusNew, "Content")
+	if err := s.Save(p); err != nil {
+		t.Fatal(err)
+	}
+	oldFilename := peb.Filename(p)
+
+	p.Title = "New title"
+	if err := s.Save(p); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, oldFilename)); !os.IsNotExist(err) {
+		t.Errorf("expected old file %s to be removed", oldFilename)
+	}
+	if filename, _ := s.Filename("peb-aaaa"); filename != "peb-aaaa--new-title.md" {
+		t.Errorf("expected new filename, got %s", filename)
+	}
+}
+
+type recordingHooks struct {
+	calls []string
+	veto  bool
+}
+
+func describe(phase string, old, new *peb.Peb) string {
+	switch {
+	case old == nil:
+		return phase + " create " + new.Title
+	case new == nil:
+		return phase + " delete " + old.Title
+	default:
+		return phase + " update " + old.Title + " -> " + new.Title
+	}
+}
+
+func (h *recordingHooks) Before(old, new *peb.Peb) error {
+	h.calls = append(h.calls, describe("before", old, new))
+	if h.veto {
+		return errors.New("vetoed")
+	}
+	return nil
+}
+
+func (h *recordingHooks) After(old, new *peb.Peb) error {
+	h.calls = append(h.calls, describe("after", old, new))
+	return nil
+}
+
+func TestHooks(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := New(tmpDir, "peb")
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	h := &recordingHooks{}
+	s.SetHooks(h)
+
+	p := peb.New("peb-aaaa", "One", peb.TypeTask, peb.StatusNew, "Content")
+	if err := s.Save(p); err != nil {
+		t.Fatal(err)
+	}
+	// Modify the cached peb in place, as the commands do.
+	cached, _ := s.Get("peb-aaaa")
+	cached.Title = "Two"
+	if err := s.Save(cached); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(cached); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"before create One", "after create One",
+		"before update One -> Two", "after update One -> Two",
+		"before delete Two", "after delete Two",
+	}
+	if strings.Join(h.calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected hook calls:\n%s", strings.Join(h.calls, "\n"))
+	}
+}
+
+func TestHooksVeto(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := New(tmpDir, "peb")
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	s.SetHooks(&recordingHooks{veto: true})
+
+	p := peb.New("peb-aaaa", "One", peb.TypeTask, peb.StatusNew, "Content")
+	if err := s.Save(p); err == nil {
+		t.Fatal("expected vetoed save to fail")
+	}
+	if s.Exists("peb-aaaa") {
+		t.Error("expected vetoed peb not to be stored")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, peb.Filename(p))); !os.IsNotExist(err) {
+		t.Error("expected vetoed peb not to be written")
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := New(tmpDir, "peb")
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save(peb.New("peb-aaaa", "Valid", peb.TypeTask, peb.StatusNew, "")); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(tmpDir, "peb-bbbb--broken.md")
+	if err := os.WriteFile(broken, []byte("---\nid: peb-bbbb\ntitle: [\n---\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	if all := s.All(); len(all) != 1 || all[0].ID != "peb-aaaa" {
+		t.Errorf("All() = %v, want only peb-aaaa", all)
+	}
+	invalid := s.Invalid()
+	var parseErr *peb.ParseError
+	if len(invalid) != 1 || !errors.As(invalid[0], &parseErr) || parseErr.Path != broken {
+		t.Fatalf("Invalid() = %v", invalid)
+	}
+	if err := s.ReadError("peb-bbbb"); err != invalid[0] {
+		t.Errorf("ReadError() = %v", err)
+	}
+	if err := s.ReadError("peb-aaaa"); err != nil {
+		t.Errorf("ReadError() of a valid peb = %v", err)
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, p := range []*peb.Peb{
+		peb.New("peb-aaaa", "Ours", peb.TypeTask, peb.StatusNew, ""),
+		peb.New("peb-aaaa", "Theirs", peb.TypeTask, peb.StatusNew, ""),
+		peb.New("peb-bbbb", "Other", peb.TypeTask, peb.StatusNew, ""),
+	} {
+		if err := peb.WriteFile(tmpDir, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := New(tmpDir, "peb")
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := s.DuplicateIDs(); len(ids) != 1 || ids[0] != "peb-aaaa" {
+		t.Fatalf("DuplicateIDs() = %v", ids)
+	}
+	if !s.Exists("peb-aaaa") {
+		t.Error("expected duplicate ID to exist")
+	}
+	if _, ok := s.Get("peb-aaaa"); ok {
+		t.Error("expected Get to refuse a duplicate ID")
+	}
+	var dup *DuplicateError
+	if err := s.ReadError("peb-aaaa"); !errors.As(err, &dup) || len(dup.Filenames) != 2 {
+		t.Fatalf("ReadError() = %v", err)
+	}
+	if invalid := s.Invalid(); len(invalid) != 1 || !errors.As(invalid[0], &dup) {
+		t.Errorf("Invalid() = %v", invalid)
+	}
+
+	duplicates := s.Duplicates("peb-aaaa")
+	if len(duplicates) != 2 || duplicates[0].Filename != "peb-aaaa--ours.md" || duplicates[1].Peb.Title != "Theirs" {
+		t.Fatalf("Duplicates() = %+v", duplicates)
+	}
+	moved := *duplicates[1].Peb
+	moved.ID = "peb-cccc"
+	if err := s.Apply(s.MoveDuplicate(duplicates[1], &moved)); err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := s.DuplicateIDs(); len(ids) != 0 {
+		t.Errorf("DuplicateIDs() after move = %v", ids)
+	}
+	if p, ok := s.Get("peb-aaaa"); !ok || p.Title != "Ours" {
+		t.Errorf("Get(peb-aaaa) = %v, %v, want Ours", p, ok)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "peb-aaaa--theirs.md")); !os.IsNotExist(err) {
+		t.Errorf("expected moved file to be removed, got %v", err)
+	}
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if p, ok := s.Get("peb-cccc"); !ok || p.Title != "Theirs" {
+		t.Errorf("Get(peb-cccc) = %v, %v, want Theirs", p, ok)
+	}
+}
@@ -0,0 +1,90 @@
+package term
+
+import "unicode/utf8"
+
+type Key string
+
+// Named keys are longer than one character, so they cannot be confused with
+// typed characters.
+const (
+	KeyUp        Key = "up"
+	KeyDown      Key = "down"
+	KeyLeft      Key = "left"
+	KeyRight     Key = "right"
+	KeyHome      Key = "home"
+	KeyEnd       Key = "end"
+	KeyPageUp    Key = "pgup"
+	KeyPageDown  Key = "pgdown"
+	KeyDelete    Key = "delete"
+	KeyEnter     Key = "enter"
+	KeyTab       Key = "tab"
+	KeyBackspace Key = "backspace"
+	KeyEscape    Key = "esc"
+	KeyCtrlC     Key = "ctrl-c"
+	KeyCtrlU     Key = "ctrl-u"
+)
+
+var escapeKeys = map[string]Key{
+	"[A": KeyUp, "[B": KeyDown, "[C": KeyRight, "[D": KeyLeft,
+	"OA": KeyUp, "OB": KeyDown, "OC": KeyRight, "OD": KeyLeft,
+	"[H": KeyHome, "[F": KeyEnd, "OH": KeyHome, "OF": KeyEnd,
+	"[1~": KeyHome, "[7~": KeyHome, "[4~": KeyEnd, "[8~": KeyEnd,
+	"[3~": KeyDelete, "[5~": KeyPageUp, "[6~": KeyPageDown,
+}
+
+// ParseKeys drops unknown escape sequences.
+func ParseKeys(data []byte) []Key {
+	var keys []Key
+	for len(data) > 0 {
+		switch b := data[0]; {
+		case b == '\x1b':
+			n := sequenceLen(data)
+			if n == 1 {
+				keys = append(keys, KeyEscape)
+			} else if key, ok := escapeKeys[string(data[1:n])]; ok {
+				keys = append(keys, key)
+			}
+			data = data[n:]
+			continue
+		case b == '\r' || b == '\n':
+			keys = append(keys, KeyEnter)
+		case b == '\t':
+			keys = append(keys, KeyTab)
+		case b == 0x7f || b == 0x08:
+			keys = append(keys, KeyBackspace)
+		case b == 0x03:
+			keys = append(keys, KeyCtrlC)
+		case b == 0x15:
+			keys = append(keys, KeyCtrlU)
+		case b < 0x20:
+
+		default:
+			r, size := utf8.DecodeRune(data)
+			if r != utf8.RuneError {
+				keys = append(keys, Key(string(r)))
+			}
+			data = data[size:]
+			continue
+		}
+		data = data[1:]
+	}
+	return keys
+}
+
+func sequenceLen(data []byte) int {
+	if len(data) < 2 {
+		return 1
+	}
+	switch data[1] {
+	case 'O':
+		return min(3, len(data))
+	case '[':
+		for i := 2; i < len(data); i++ {
+			if data[i] >= 0x40 && data[i] <= 0x7e {
+				return i + 1
+			}
+		}
+		return len(data)
+	}
+	return 1
+}
@@ -0,0 +1,31 @@
+package term
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []Key
+	}{
+		{"j", []Key{"j"}},
+		{"ab", []Key{"a", "b"}},
+		{"ü", []Key{"ü"}},
+		{"\x1b[A\x1b[B", []Key{KeyUp, KeyDown}},
+		{"\x1bOC", []Key{KeyRight}},
+		{"\x1b[5~\x1b[6~", []Key{KeyPageUp, KeyPageDown}},
+		{"\x1b", []Key{KeyEscape}},
+		{"\x1b\x1b[H", []Key{KeyEscape, KeyHome}},
+		{"\x1bq", []Key{KeyEscape, "q"}},
+		{"\x1b[1;5A", nil},
+		{"\r\x7f\x03\x15\t", []Key{KeyEnter, KeyBackspace, KeyCtrlC, KeyCtrlU, KeyTab}},
+		{"\x01", nil},
+	}
+	for _, tt := range tests {
+		if got := ParseKeys([]byte(tt.input)); !slices.Equal(got, tt.want) {
+			t.Errorf("ParseKeys(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package term
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	headingPattern  = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	listPattern     = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	taskPattern     = regexp.MustCompile(`^\[([ xX])\]\s+(.*)$`)
+	orderedPattern  = regexp.MustCompile(`^(\s*)(\d+[.)])\s+(.*)$`)
+	rulePattern     = regexp.MustCompile(`^\s*(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	linkPattern     = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	autolinkPattern = regexp.MustCompile(`<(https?://[^>\s]+)>`)
+	boldPattern     = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	italicPattern   = regexp.MustCompile(`(^|[^\w*])\*([^*\s](?:[^*]*[^*\s])?)\*|(^|[^\w_])_([^_\s](?:[^_]*[^_\s])?)_($|\W)`)
+)
+
+// Markdown styles markup instead of showing it. Without colors, the markup
+// is removed all the same, so the text reads the same in a pipe.
+func Markdown(text string, s Styles) string {
+	var out strings.Builder
+	var fence string
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+				continue
+			}
+			out.WriteString("    " + s.Fg(Cyan, line) + "\n")
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			continue
+		}
+
+		switch {
+		case headingPattern.MatchString(line):
+			m := headingPattern.FindStringSubmatch(line)
+			heading := s.Bold(inline(m[2], s))
+			if len(m[1]) <= 2 {
+				heading = s.Underline(heading)
+			}
+			out.WriteString(heading + "\n")
+		case rulePattern.MatchString(line):
+			out.WriteString(s.Dim(strings.Repeat("─", 40)) + "\n")
+		case listPattern.MatchString(line):
+			m := listPattern.FindStringSubmatch(line)
+			bullet, item := "•", m[2]
+			if task := taskPattern.FindStringSubmatch(item); task != nil {
+				bullet, item = "☐", task[2]
+				if task[1] != " " {
+					bullet = "☑"
+				}
+			}
+			out.WriteString(m[1] + bullet + " " + inline(item, s) + "\n")
+		case orderedPattern.MatchString(line):
+			m := orderedPattern.FindStringSubmatch(line)
+			out.WriteString(m[1] + m[2] + " " + inline(m[3], s) + "\n")
+		case strings.HasPrefix(trimmed, ">"):
+			quote := strings.TrimSpace(strings.TrimPrefix(trimmed, ">"))
+			out.WriteString(s.Dim("│ ") + s.Italic(inline(quote, s)) + "\n")
+		default:
+			out.WriteString(inline(line, s) + "\n")
+		}
+	}
+	return out.String()
+}
+
+func inline(line string, s Styles) string {
+	var out strings.Builder
+	for i, part := range strings.Split(line, "`") {
+		// Odd parts are inside backticks, unless the last backtick is
+		// unmatched.
+		if i%2 == 1 && strings.Count(line, "`")%2 == 0 {
+			out.WriteString(s.Fg(Cyan, part))
+			continue
+		}
+		if i%2 == 1 {
+			part = "`" + part
+		}
+		part = linkPattern.ReplaceAllStringFunc(part, func(m string) string {
+			sub := linkPattern.FindStringSubmatch(m)
+			if sub[1] == sub[2] {
+				return s.Underline(s.Fg(Blue, sub[2]))
+			}
+			return s.Underline(sub[1]) + " " + s.Dim("("+sub[2]+")")
+		})
+		part = autolinkPattern.ReplaceAllStringFunc(part, func(m string) string {
+			return s.Underline(s.Fg(Blue, m[1:len(m)-1]))
+		})
+		part = boldPattern.ReplaceAllStringFunc(part, func(m string) string {
+			return s.Bold(m[2 : len(m)-2])
+		})
+		part = italicPattern.ReplaceAllStringFunc(part, func(m string) string {
+			sub := italicPattern.FindStringSubmatch(m)
+			if sub[2] != "" {
+				return sub[1] + s.Italic(sub[2])
+			}
+			return sub[3] + s.Italic(sub[4]) + sub[5]
+		})
+		out.WriteString(part)
+	}
+	return out.String()
+}
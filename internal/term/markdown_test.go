@@ -0,0 +1,53 @@
+package term
+
+import "testing"
+
+func TestMarkdownPlain(t *testing.T) {
+	input := "# Title\n" +
+		"\n" +
+		"Some **bold**, *italic*, _also italic_ and `code_with_underscores` in snake_case.\n" +
+		"See [the docs](https://example.com) or <https://example.org>.\n" +
+		"- item\n" +
+		"  * nested\n" +
+		"- [ ] todo\n" +
+		"- [x] done\n" +
+		"1. first\n" +
+		"> quoted\n" +
+		"---\n" +
+		"```go\n" +
+		"x := **y**\n" +
+		"```\n" +
+		"Unmatched ` backtick\n"
+	want := "Title\n" +
+		"\n" +
+		"Some bold, italic, also italic and code_with_underscores in snake_case.\n" +
+		"See the docs (https://example.com) or https://example.org.\n" +
+		"• item\n" +
+		"  • nested\n" +
+		"☐ todo\n" +
+		"☑ done\n" +
+		"1. first\n" +
+		"│ quoted\n" +
+		"────────────────────────────────────────\n" +
+		"    x := **y**\n" +
+		"Unmatched ` backtick\n"
+	if got := Markdown(input, Styles{}); got != want {
+		t.Errorf("Markdown() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMarkdownColor(t *testing.T) {
+	s := Styles{Color: true}
+	tests := map[string]string{
+		"## Heading":         "\x1b[4m\x1b[1mHeading\x1b[22m\x1b[24m\n",
+		"### Small":          "\x1b[1mSmall\x1b[22m\n",
+		"a **b** c":          "a \x1b[1mb\x1b[22m c\n",
+		"a `b` c":            "a \x1b[36mb\x1b[39m c\n",
+		"[x](https://x.org)": "\x1b[4mx\x1b[24m \x1b[2m(https://x.org)\x1b[22m\n",
+	}
+	for input, want := range tests {
+		if got := Markdown(input, s); got != want {
+			t.Errorf("Markdown(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
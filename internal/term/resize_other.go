@@ -0,0 +1,8 @@
+//go:build !unix
+
+package term
+
+import "os"
+
+// NotifyResize does nothing where there is no SIGWINCH.
+func NotifyResize(c chan<- os.Signal) {}
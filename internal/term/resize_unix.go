@@ -0,0 +1,13 @@
+//go:build unix
+
+package term
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func NotifyResize(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGWINCH)
+}
@@ -0,0 +1,117 @@
+package term
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	enterScreen = "\x1b[?1049h\x1b[?25l"
+	exitScreen  = "\x1b[?25h\x1b[?1049l"
+)
+
+// Screen sets raw mode with stty(1), so it is only available on Unix.
+type Screen struct {
+	in    *os.File
+	out   *os.File
+	saved string
+	drawn []string
+}
+
+func OpenScreen(in, out *os.File) (*Screen, error) {
+	if !IsTerminal(in) || !IsTerminal(out) {
+		return nil, errors.New("not a terminal")
+	}
+	saved, err := stty(in, "-g")
+	if err != nil {
+		return nil, err
+	}
+	s := &Screen{in: in, out: out, saved: strings.TrimSpace(saved)}
+	if err := s.Resume(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Screen) Suspend() error {
+	fmt.Fprint(s.out, exitScreen)
+	_, err := stty(s.in, s.saved)
+	return err
+}
+
+func (s *Screen) Resume() error {
+	if _, err := stty(s.in, "raw", "-echo", "min", "0", "time", "1"); err != nil {
+		return err
+	}
+	fmt.Fprint(s.out, enterScreen)
+	s.drawn = nil
+	return nil
+}
+
+func (s *Screen) Close() error {
+	return s.Suspend()
+}
+
+func (s *Screen) Size() (width, height int, err error) {
+	out, err := stty(s.in, "size")
+	if err != nil {
+		return 0, 0, err
+	}
+	if _, err := fmt.Sscan(out, &height, &width); err != nil {
+		return 0, 0, fmt.Errorf("failed to parse terminal size %q: %w", out, err)
+	}
+	return width, height, nil
+}
+
+// Draw resets styles at the end of every line.
+func (s *Screen) Draw(lines []string) error {
+	if s.drawn != nil && slices.Equal(lines, s.drawn) {
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(line + "\x1b[0m\x1b[K")
+	}
+	b.WriteString("\x1b[J")
+	if _, err := io.WriteString(s.out, b.String()); err != nil {
+		return err
+	}
+	s.drawn = lines
+	return nil
+}
+
+// ReadKeys waits up to a tenth of a second for input.
+func (s *Screen) ReadKeys() ([]Key, error) {
+	// Files the runtime can poll need a deadline; for others the timeout
+	// set by Resume applies, and a read without input returns io.EOF.
+	s.in.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	buf := make([]byte, 256)
+	n, err := s.in.Read(buf)
+	if n > 0 {
+		return ParseKeys(buf[:n]), nil
+	}
+	if err == nil || err == io.EOF || errors.Is(err, os.ErrDeadlineExceeded) {
+		return nil, nil
+	}
+	return nil, err
+}
+
+func stty(f *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = f
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("stty %s failed: %w", strings.Join(args, " "), err)
+	}
+	return string(out), nil
+}
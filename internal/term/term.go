@@ -0,0 +1,65 @@
+// Package term formats output for terminals and provides the raw terminal
+// access of full-screen interfaces.
+package term
+
+import (
+	"io"
+	"os"
+	"strconv"
+)
+
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// ColorEnabled honors NO_COLOR (see https://no-color.org) and TERM=dumb.
+func ColorEnabled(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	return ok && IsTerminal(f)
+}
+
+type Color int
+
+const (
+	Red     Color = 31
+	Green   Color = 32
+	Yellow  Color = 33
+	Blue    Color = 34
+	Magenta Color = 35
+	Cyan    Color = 36
+	Gray    Color = 90
+)
+
+// Styles turns off every style by its own code rather than a full reset, so
+// styles can be nested.
+type Styles struct {
+	Color bool
+}
+
+func (s Styles) wrap(on, off, text string) string {
+	if !s.Color || text == "" {
+		return text
+	}
+	return "\x1b[" + on + "m" + text + "\x1b[" + off + "m"
+}
+
+func (s Styles) Bold(text string) string      { return s.wrap("1", "22", text) }
+func (s Styles) Dim(text string) string       { return s.wrap("2", "22", text) }
+func (s Styles) Italic(text string) string    { return s.wrap("3", "23", text) }
+func (s Styles) Underline(text string) string { return s.wrap("4", "24", text) }
+func (s Styles) Reverse(text string) string   { return s.wrap("7", "27", text) }
+
+func (s Styles) Fg(c Color, text string) string {
+	return s.wrap(strconv.Itoa(int(c)), "39", text)
+}
+
+func (s Styles) Badge(c Color, text string) string {
+	if !s.Color {
+		return "[" + text + "]"
+	}
+	return s.wrap("30;"+strconv.Itoa(int(c)+10), "39;49", " "+text+" ")
+}
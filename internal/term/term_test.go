@@ -0,0 +1,45 @@
+package term
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBadge(t *testing.T) {
+	if got := (Styles{}).Badge(Green, "fixed"); got != "[fixed]" {
+		t.Errorf("plain Badge() = %q", got)
+	}
+	if got := (Styles{Color: true}).Badge(Green, "fixed"); got != "\x1b[30;42m fixed \x1b[39;49m" {
+		t.Errorf("colored Badge() = %q", got)
+	}
+}
+
+func TestColorEnabled(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("TERM", "xterm")
+	if ColorEnabled(&strings.Builder{}) {
+		t.Error("ColorEnabled() = true for a writer that is not a terminal")
+	}
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if ColorEnabled(f) {
+		t.Error("ColorEnabled() = true for a regular file")
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		t.Skip("no terminal:", err)
+	}
+	defer tty.Close()
+	if !ColorEnabled(tty) {
+		t.Error("ColorEnabled() = false for a terminal")
+	}
+	t.Setenv("NO_COLOR", "1")
+	if ColorEnabled(tty) {
+		t.Error("ColorEnabled() = true with NO_COLOR set")
+	}
+}
@@ -0,0 +1,116 @@
+package term
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Every rune counts as one cell below, which holds for the text pebbles
+// renders except for wide characters such as CJK and emoji.
+
+func escapeLen(s string) int {
+	if len(s) < 2 || s[0] != '\x1b' || s[1] != '[' {
+		return 0
+	}
+	for i := 2; i < len(s); i++ {
+		if s[i] >= 0x40 && s[i] <= 0x7e {
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+func Width(text string) int {
+	width := 0
+	for i := 0; i < len(text); {
+		if n := escapeLen(text[i:]); n > 0 {
+			i += n
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		width++
+	}
+	return width
+}
+
+// Truncate keeps the escape sequences of text.
+func Truncate(text string, width int) string {
+	cells := 0
+	for i := 0; i < len(text); {
+		if n := escapeLen(text[i:]); n > 0 {
+			i += n
+			continue
+		}
+		if cells == width {
+			return text[:i]
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		cells++
+	}
+	return text
+}
+
+func Pad(text string, width int) string {
+	if w := Width(text); w < width {
+		return text + strings.Repeat(" ", width-w)
+	}
+	return text
+}
+
+// Wrap keeps the indentation of the first line on continuation lines.
+func Wrap(text string, width int) []string {
+	indent := text[:len(text)-len(strings.TrimLeft(text, " "))]
+	if width <= len(indent) {
+		indent = ""
+	}
+	if width < 1 {
+		return []string{text}
+	}
+
+	var lines []string
+	var line strings.Builder
+	cells := 0
+	// breakAt is -1 if there is no space to break at.
+	breakAt, breakCells := -1, 0
+	for i := 0; i < len(text); {
+		if n := escapeLen(text[i:]); n > 0 {
+			line.WriteString(text[i : i+n])
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		if cells == width {
+			current := line.String()
+			if r == ' ' {
+				// The line ends right before a space.
+				lines = append(lines, current)
+				line.Reset()
+				line.WriteString(indent)
+				cells, breakAt = len(indent), -1
+				continue
+			}
+			if breakAt >= 0 {
+				lines = append(lines, current[:breakAt])
+				rest := indent + current[breakAt+1:]
+				line.Reset()
+				line.WriteString(rest)
+				cells = cells - breakCells - 1 + len(indent)
+			} else {
+				lines = append(lines, current)
+				line.Reset()
+				line.WriteString(indent)
+				cells = len(indent)
+			}
+			breakAt = -1
+		}
+		if r == ' ' && cells > len(indent) {
+			breakAt, breakCells = line.Len(), cells
+		}
+		line.WriteRune(r)
+		cells++
+	}
+	return append(lines, line.String())
+}
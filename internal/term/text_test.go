@@ -0,0 +1,43 @@
+package term
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestWidthAndTruncate(t *testing.T) {
+	s := Styles{Color: true}
+	text := s.Bold("peb-ab12") + " • " + s.Fg(Green, "fixed")
+	if got := Width(text); got != 16 {
+		t.Errorf("Width() = %d, want 16", got)
+	}
+	if got, want := Truncate(text, 10), s.Bold("peb-ab12")+" •"; got != want {
+		t.Errorf("Truncate() = %q, want %q", got, want)
+	}
+	if got := Truncate("short", 10); got != "short" {
+		t.Errorf("Truncate() = %q", got)
+	}
+	if got := Pad("ab", 4); got != "ab  " {
+		t.Errorf("Pad() = %q", got)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  []string
+	}{
+		{"short", 10, []string{"short"}},
+		{"the quick brown fox", 10, []string{"the quick", "brown fox"}},
+		{"the quick brown", 9, []string{"the quick", "brown"}},
+		{"abcdefghijkl", 5, []string{"abcde", "fghij", "kl"}},
+		{"  • a list item that wraps", 12, []string{"  • a list", "  item that", "  wraps"}},
+		{"\x1b[1mbold words\x1b[22m here", 6, []string{"\x1b[1mbold", "words\x1b[22m", "here"}},
+	}
+	for _, tt := range tests {
+		if got := Wrap(tt.text, tt.width); !slices.Equal(got, tt.want) {
+			t.Errorf("Wrap(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+		}
+	}
+}
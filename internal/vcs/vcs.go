@@ -0,0 +1,154 @@
+// Package vcs reads commits from the git or jj repository of a project.
+package vcs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+var ErrNoRepository = errors.New("not in a git or jj repository")
+
+type Commit struct {
+	// ID is the change ID for jj, which survives rewrites.
+	ID      string
+	Message string
+}
+
+type Repo interface {
+	Name() string
+	Resolve(rev string) (string, error)
+	// Log returns the history of the working copy if revs is empty, newest
+	// first.
+	Log(revs string) ([]Commit, error)
+	// Workspace is the user's email with the branch, or the change for jj.
+	Workspace() string
+}
+
+// Detect prefers jj when a jj repository is colocated with git.
+func Detect(dir string) (Repo, error) {
+	for d := dir; ; d = filepath.Dir(d) {
+		if info, err := os.Stat(filepath.Join(d, ".jj")); err == nil && info.IsDir() {
+			return &jj{dir: dir}, nil
+		}
+		// .git is a file in worktrees and submodules.
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			return &git{dir: dir}, nil
+		}
+		if filepath.Dir(d) == d {
+			return nil, ErrNoRepository
+		}
+	}
+}
+
+func ShortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+func run(dir, name string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s %s: %s", name, args[0], msg)
+		}
+		return "", fmt.Errorf("%s %s: %w", name, args[0], err)
+	}
+	return stdout.String(), nil
+}
+
+type git struct {
+	dir string
+}
+
+func (g *git) Name() string {
+	return "git"
+}
+
+func (g *git) Resolve(rev string) (string, error) {
+	out, err := run(g.dir, "git", "rev-parse", "--verify", "--end-of-options", rev+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("unknown revision %q: %w", rev, err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (g *git) Log(revs string) ([]Commit, error) {
+	if revs == "" {
+		revs = "HEAD"
+	}
+	out, err := run(g.dir, "git", "log", "-z", "--format=%H%n%B", "--end-of-options", revs, "--")
+	if err != nil {
+		return nil, err
+	}
+	return parseLog(out), nil
+}
+
+func (g *git) Workspace() string {
+	// The ident is "name <email> timestamp".
+	ident, _ := run(g.dir, "git", "var", "GIT_AUTHOR_IDENT")
+	_, email, _ := strings.Cut(ident, "<")
+	email, _, _ = strings.Cut(email, ">")
+	branch, _ := run(g.dir, "git", "symbolic-ref", "--short", "-q", "HEAD")
+	return email + " " + strings.TrimSpace(branch)
+}
+
+type jj struct {
+	dir string
+}
+
+func (j *jj) Name() string {
+	return "jj"
+}
+
+func (j *jj) Resolve(rev string) (string, error) {
+	out, err := run(j.dir, "jj", "log", "--no-graph", "--ignore-working-copy", "-r", rev, "-T", `change_id ++ "\n"`)
+	if err != nil {
+		return "", fmt.Errorf("unknown revision %q: %w", rev, err)
+	}
+	ids := strings.Fields(out)
+	if len(ids) != 1 {
+		return "", fmt.Errorf("revision %q resolves to %d commits", rev, len(ids))
+	}
+	return ids[0], nil
+}
+
+func (j *jj) Log(revs string) ([]Commit, error) {
+	if revs == "" {
+		revs = "::@"
+	}
+	out, err := run(j.dir, "jj", "log", "--no-graph", "--ignore-working-copy", "-r", revs, "-T", `change_id ++ "\n" ++ description ++ "\0"`)
+	if err != nil {
+		return nil, err
+	}
+	return parseLog(out), nil
+}
+
+func (j *jj) Workspace() string {
+	email, _ := run(j.dir, "jj", "config", "get", "user.email")
+	change, _ := j.Resolve("@")
+	return strings.TrimSpace(email) + " " + change
+}
+
+func parseLog(out string) []Commit {
+	var commits []Commit
+	for _, entry := range strings.Split(out, "\x00") {
+		entry = strings.TrimLeft(entry, "\n")
+		if entry == "" {
+			continue
+		}
+		id, message, _ := strings.Cut(entry, "\n")
+		commits = append(commits, Commit{ID: id, Message: message})
+	}
+	return commits
+}
@@ -0,0 +1,87 @@
+package vcs
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseLog(t *testing.T) {
+	out := "aaaa\nFirst line\n\nBody\n\x00\nbbbb\nSecond\n\x00"
+	want := []Commit{
+		{ID: "aaaa", Message: "First line\n\nBody\n"},
+		{ID: "bbbb", Message: "Second\n"},
+	}
+	if got := parseLog(out); !slices.Equal(got, want) {
+		t.Errorf("parseLog() = %q, want %q", got, want)
+	}
+}
+
+func TestGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	if _, err := Detect(dir); err != ErrNoRepository {
+		t.Fatalf("expected ErrNoRepository, got %v", err)
+	}
+
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"commit", "-q", "--allow-empty", "-m", "First\n\nFixes peb-aaaa"},
+		{"commit", "-q", "--allow-empty", "-m", "Second"},
+	} {
+		if out, err := run(dir, "git", args...); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := Detect(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.Name() != "git" {
+		t.Fatalf("expected git, got %s", repo.Name())
+	}
+
+	head, err := repo.Resolve("HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(head) != 40 {
+		t.Errorf("expected a full commit hash, got %q", head)
+	}
+	if ws := repo.Workspace(); !strings.HasPrefix(ws, "test@example.com ") {
+		t.Errorf("expected the author in the workspace, got %q", ws)
+	}
+	if _, err := repo.Resolve("no-such-rev"); err == nil {
+		t.Error("expected an error for an unknown revision")
+	}
+
+	commits, err := repo.Log("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 2 || commits[0].ID != head || commits[1].Message != "First\n\nFixes peb-aaaa\n" {
+		t.Errorf("unexpected log %q", commits)
+	}
+
+	commits, err = repo.Log("HEAD~1..HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 1 || commits[0].ID != head {
+		t.Errorf("unexpected log %q", commits)
+	}
+}
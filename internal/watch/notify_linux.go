@@ -0,0 +1,59 @@
+//go:build linux
+
+package watch
+
+import (
+	"os"
+	"syscall"
+)
+
+type inotifyNotifier struct {
+	file *os.File
+	c    chan struct{}
+}
+
+func newFSNotifier(dir string) (notifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+
+	const mask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_CLOSE_WRITE |
+		syscall.IN_MODIFY | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO |
+		syscall.IN_ATTRIB | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF
+	if _, err := syscall.InotifyAddWatch(fd, dir, mask); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	// The descriptor is non-blocking, so the runtime poller handles reads and
+	// closing the file unblocks the reader goroutine.
+	n := &inotifyNotifier{
+		file: os.NewFile(uintptr(fd), "inotify"),
+		c:    make(chan struct{}, 1),
+	}
+	go n.read()
+	return n, nil
+}
+
+func (n *inotifyNotifier) read() {
+	buf := make([]byte, 64*1024)
+	for {
+		if _, err := n.file.Read(buf); err != nil {
+			return
+		}
+		// The watcher rescans the whole directory anyway.
+		select {
+		case n.c <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (n *inotifyNotifier) C() <-chan struct{} {
+	return n.c
+}
+
+func (n *inotifyNotifier) Close() error {
+	return n.file.Close()
+}
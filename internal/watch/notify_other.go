@@ -0,0 +1,10 @@
+//go:build !linux
+
+package watch
+
+import "errors"
+
+// newFSNotifier is only implemented on Linux; elsewhere the watcher polls.
+func newFSNotifier(dir string) (notifier, error) {
+	return nil, errors.New("file system notifications are not supported on this platform")
+}
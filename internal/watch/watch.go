@@ -0,0 +1,296 @@
+// Package watch reports changes to the pebs in a .pebbles directory as a
+// stream of events.
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"go.yozora.eu/pebbles/internal/peb"
+)
+
+const (
+	EventCreated = "created"
+	EventUpdated = "updated"
+	EventDeleted = "deleted"
+	EventRenamed = "renamed"
+)
+
+const DefaultPollInterval = time.Second
+
+// debounce lets e.g. the rename and write of a title update be seen together.
+const debounce = 50 * time.Millisecond
+
+type FieldChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// Event describes a change to a single peb. Old is nil for created pebs, New
+// for deleted ones.
+type Event struct {
+	Type        string                 `json:"event"`
+	ID          string                 `json:"id"`
+	Filename    string                 `json:"filename,omitempty"`
+	OldFilename string                 `json:"old-filename,omitempty"`
+	Changes     map[string]FieldChange `json:"changes,omitempty"`
+	Peb         *peb.PebJSON           `json:"peb,omitempty"`
+	Old         *peb.Peb               `json:"-"`
+	New         *peb.Peb               `json:"-"`
+}
+
+type entry struct {
+	peb      *peb.Peb
+	filename string
+}
+
+type Snapshot map[string]entry
+
+type Options struct {
+	Poll         bool
+	PollInterval time.Duration
+}
+
+type Watcher struct {
+	dir      string
+	prefix   string
+	notifier notifier
+	prev     Snapshot
+}
+
+type notifier interface {
+	C() <-chan struct{}
+	Close() error
+}
+
+// New takes an initial snapshot of dir; Run reports the changes after it.
+func New(dir, prefix string, opts Options) (*Watcher, error) {
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	var n notifier
+	if !opts.Poll {
+		n, _ = newFSNotifier(dir)
+	}
+	if n == nil {
+		n = newPollNotifier(interval)
+	}
+
+	prev, err := Scan(dir, prefix, nil)
+	if err != nil {
+		n.Close()
+		return nil, err
+	}
+
+	return &Watcher{dir: dir, prefix: prefix, notifier: n, prev: prev}, nil
+}
+
+func (w *Watcher) Run(ctx context.Context, emit func(Event) error) error {
+	defer w.notifier.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-w.notifier.C():
+		}
+
+		timer := time.NewTimer(debounce)
+	settle:
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil
+			case <-w.notifier.C():
+			case <-timer.C:
+				break settle
+			}
+		}
+
+		cur, err := Scan(w.dir, w.prefix, w.prev)
+		if err != nil {
+			return err
+		}
+		for _, e := range Diff(w.prev, cur) {
+			if err := emit(e); err != nil {
+				return err
+			}
+		}
+		w.prev = cur
+	}
+}
+
+// Scan reads every peb in dir. A file that cannot be parsed, e.g. because it
+// is being written, keeps its state from prev.
+func Scan(dir, prefix string, prev Snapshot) (Snapshot, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := make(Snapshot)
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".md") {
+			continue
+		}
+		id, err := peb.ParseID(name, prefix)
+		if err != nil {
+			continue
+		}
+		p, err := peb.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			if old, ok := prev[id]; ok {
+				snapshot[id] = old
+			}
+			continue
+		}
+		snapshot[id] = entry{peb: p, filename: name}
+	}
+	return snapshot, nil
+}
+
+// Diff returns the events that turn old into cur, sorted by peb ID. A renamed
+// file is a single update.
+func Diff(old, cur Snapshot) []Event {
+	var events []Event
+
+	for id, c := range cur {
+		o, ok := old[id]
+		if !ok {
+			events = append(events, Event{Type: EventCreated, ID: id, Filename: c.filename, New: c.peb})
+			continue
+		}
+
+		changes := diffFields(o.peb, c.peb)
+		event := Event{ID: id, Filename: c.filename, Old: o.peb, New: c.peb, Changes: changes}
+		if o.filename != c.filename {
+			event.OldFilename = o.filename
+		}
+
+		switch {
+		case len(changes) > 0:
+			event.Type = EventUpdated
+		case o.filename != c.filename:
+			event.Type = EventRenamed
+		default:
+			continue
+		}
+		events = append(events, event)
+	}
+
+	for id, o := range old {
+		if _, ok := cur[id]; !ok {
+			events = append(events, Event{Type: EventDeleted, ID: id, Filename: o.filename, Old: o.peb})
+		}
+	}
+
+	for i := range events {
+		latest := events[i].New
+		if latest == nil {
+			latest = events[i].Old
+		}
+		events[i].Peb = &peb.PebJSON{
+			ID:        latest.ID,
+			Type:      latest.Type,
+			Status:    latest.Status,
+			Title:     latest.Title,
+			BlockedBy: latest.BlockedBy,
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].ID < events[j].ID
+	})
+	return events
+}
+
+func diffFields(old, cur *peb.Peb) map[string]FieldChange {
+	changes := make(map[string]FieldChange)
+	if old.Title != cur.Title {
+		changes["title"] = FieldChange{Old: old.Title, New: cur.Title}
+	}
+	if old.Type != cur.Type {
+		changes["type"] = FieldChange{Old: old.Type, New: cur.Type}
+	}
+	if old.Status != cur.Status {
+		changes["status"] = FieldChange{Old: old.Status, New: cur.Status}
+	}
+	if old.Created != cur.Created {
+		changes["created"] = FieldChange{Old: old.Created, New: cur.Created}
+	}
+	if old.Changed != cur.Changed {
+		changes["changed"] = FieldChange{Old: old.Changed, New: cur.Changed}
+	}
+	if !slices.Equal(old.BlockedBy, cur.BlockedBy) {
+		changes["blocked-by"] = FieldChange{Old: old.BlockedBy, New: cur.BlockedBy}
+	}
+	if old.Source != cur.Source {
+		changes["source"] = FieldChange{Old: old.Source, New: cur.Source}
+	}
+	if !slices.Equal(old.Commits, cur.Commits) {
+		changes["commits"] = FieldChange{Old: old.Commits, New: cur.Commits}
+	}
+	if oldExtra, curExtra := extraJSON(old), extraJSON(cur); oldExtra != curExtra {
+		changes["extra"] = FieldChange{Old: old.Extra, New: cur.Extra}
+	}
+	if old.Content != cur.Content {
+		changes["content"] = FieldChange{Old: old.Content, New: cur.Content}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+func extraJSON(p *peb.Peb) string {
+	data, _ := json.Marshal(p.Extra)
+	return string(data)
+}
+
+type pollNotifier struct {
+	ticker *time.Ticker
+	c      chan struct{}
+	done   chan struct{}
+}
+
+func newPollNotifier(interval time.Duration) *pollNotifier {
+	n := &pollNotifier{
+		ticker: time.NewTicker(interval),
+		c:      make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	go func() {
+		for {
+			select {
+			case <-n.done:
+				return
+			case <-n.ticker.C:
+				select {
+				case n.c <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return n
+}
+
+func (n *pollNotifier) C() <-chan struct{} {
+	return n.c
+}
+
+func (n *pollNotifier) Close() error {
+	n.ticker.Stop()
+	close(n.done)
+	return nil
+}
@@ -0,0 +1,171 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.yozora.eu/pebbles/internal/peb"
+)
+
+func TestDiff(t *testing.T) {
+	created := peb.New("peb-aaaa", "Created", peb.TypeTask, peb.StatusNew, "Content")
+	deleted := peb.New("peb-bbbb", "Deleted", peb.TypeTask, peb.StatusNew, "Content")
+	before := peb.New("peb-cccc", "Old title", peb.TypeTask, peb.StatusNew, "Content")
+	after := *before
+	after.Title = "New title"
+	after.Status = peb.StatusFixed
+	moved := peb.New("peb-dddd", "Moved", peb.TypeTask, peb.StatusNew, "Content")
+	same := peb.New("peb-eeee", "Same", peb.TypeTask, peb.StatusNew, "Content")
+
+	old := Snapshot{
+		"peb-bbbb": {peb: deleted, filename: "peb-bbbb--deleted.md"},
+		"peb-cccc": {peb: before, filename: "peb-cccc--old-title.md"},
+		"peb-dddd": {peb: moved, filename: "peb-dddd--moved.md"},
+		"peb-eeee": {peb: same, filename: "peb-eeee--same.md"},
+	}
+	cur := Snapshot{
+		"peb-aaaa": {peb: created, filename: "peb-aaaa--created.md"},
+		"peb-cccc": {peb: &after, filename: "peb-cccc--new-title.md"},
+		"peb-dddd": {peb: moved, filename: "peb-dddd--elsewhere.md"},
+		"peb-eeee": {peb: same, filename: "peb-eeee--same.md"},
+	}
+
+	events := Diff(old, cur)
+	if len(events) != 4 {
+		t.Fatalf("expected 4 events, got %d: %+v", len(events), events)
+	}
+
+	want := []struct {
+		id  string
+		typ string
+	}{
+		{"peb-aaaa", EventCreated},
+		{"peb-bbbb", EventDeleted},
+		{"peb-cccc", EventUpdated},
+		{"peb-dddd", EventRenamed},
+	}
+	for i, w := range want {
+		if events[i].ID != w.id || events[i].Type != w.typ {
+			t.Errorf("event %d: expected %s %s, got %s %s", i, w.typ, w.id, events[i].Type, events[i].ID)
+		}
+		if events[i].Peb == nil || events[i].Peb.ID != w.id {
+			t.Errorf("event %d: expected peb summary for %s, got %+v", i, w.id, events[i].Peb)
+		}
+	}
+
+	update := events[2]
+	if len(update.Changes) != 2 {
+		t.Errorf("expected title and status changes, got %v", update.Changes)
+	}
+	if c := update.Changes["title"]; c.Old != "Old title" || c.New != "New title" {
+		t.Errorf("unexpected title change %+v", c)
+	}
+	if c := update.Changes["status"]; c.Old != peb.StatusNew || c.New != peb.StatusFixed {
+		t.Errorf("unexpected status change %+v", c)
+	}
+	if update.OldFilename != "peb-cccc--old-title.md" || update.Filename != "peb-cccc--new-title.md" {
+		t.Errorf("unexpected filenames %s -> %s", update.OldFilename, update.Filename)
+	}
+}
+
+func TestScanKeepsUnreadablePebs(t *testing.T) {
+	dir := t.TempDir()
+
+	p := peb.New("peb-aaaa", "Peb", peb.TypeTask, peb.StatusNew, "Content")
+	if err := peb.WriteFile(dir, p); err != nil {
+		t.Fatal(err)
+	}
+
+	prev, err := Scan(dir, "peb", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a half-written file.
+	if err := os.WriteFile(filepath.Join(dir, peb.Filename(p)), []byte("---\nid: peb-aa"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cur, err := Scan(dir, "peb", prev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events := Diff(prev, cur); len(events) != 0 {
+		t.Errorf("expected no events for an unreadable file, got %+v", events)
+	}
+}
+
+func testWatcher(t *testing.T, opts Options) {
+	dir := t.TempDir()
+
+	p := peb.New("peb-aaaa", "Old title", peb.TypeTask, peb.StatusNew, "Content")
+	if err := peb.WriteFile(dir, p); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := New(dir, "peb", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan Event, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(ctx, func(e Event) error {
+			events <- e
+			return nil
+		})
+	}()
+
+	// Rename the file and rewrite it, like "peb update" does for title changes.
+	oldPath := filepath.Join(dir, peb.Filename(p))
+	p.Title = "New title"
+	if err := os.Rename(oldPath, filepath.Join(dir, peb.Filename(p))); err != nil {
+		t.Fatal(err)
+	}
+	if err := peb.WriteFile(dir, p); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case e := <-events:
+		if e.Type != EventUpdated || e.ID != "peb-aaaa" {
+			t.Errorf("expected a single update of peb-aaaa, got %s %s", e.Type, e.ID)
+		}
+		if e.Changes["title"].New != "New title" {
+			t.Errorf("expected title change, got %v", e.Changes)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+
+	if err := os.Remove(filepath.Join(dir, peb.Filename(p))); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case e := <-events:
+		if e.Type != EventDeleted || e.ID != "peb-aaaa" {
+			t.Errorf("expected delete of peb-aaaa, got %s %s", e.Type, e.ID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run() failed: %v", err)
+	}
+}
+
+func TestWatcherNotify(t *testing.T) {
+	testWatcher(t, Options{})
+}
+
+func TestWatcherPoll(t *testing.T) {
+	testWatcher(t, Options{Poll: true, PollInterval: 20 * time.Millisecond})
+}